@@ -0,0 +1,130 @@
+package main
+
+import (
+  "context"
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "io"
+  "os"
+  "scientific-research/internal/domain"
+  "time"
+)
+
+const (
+  exportFormatCSV  = "csv"
+  exportFormatJSON = "json"
+)
+
+var exportCSVHeader = []string{
+  "stock_id", "ticker_id", "open", "close", "high", "low", "volume", "stocked_at",
+}
+
+// runExport write stored stocks to file or stdout as csv or json lines
+func runExport(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("export")
+  tickerId := flags.String("ticker", "", "ticker id, all tickers if empty")
+  fromFlag := flags.String("from", "", "range start date, 2006-01-02")
+  toFlag := flags.String("to", "", "range end date inclusive, 2006-01-02")
+  format := flags.String("format", exportFormatCSV, "output format: csv or json")
+  out := flags.String("out", "-", "output file, stdout if '-'")
+  parseFlags(flags, args)
+
+  from, err := parseDate(*fromFlag)
+  if err != nil {
+    return err
+  }
+  to, err := parseDate(*toFlag)
+  if err != nil {
+    return err
+  }
+  if !to.IsZero() {
+    to = to.AddDate(0, 0, 1)
+  }
+  if *format != exportFormatCSV && *format != exportFormatJSON {
+    return fmt.Errorf("unknown format '%s'. possible: %s, %s", *format, exportFormatCSV, exportFormatJSON)
+  }
+  _, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+
+  output := io.Writer(os.Stdout)
+  if *out != "-" {
+    file, err := os.Create(*out)
+    if err != nil {
+      return fmt.Errorf("cannot create output file: %v", err)
+    }
+    defer file.Close()
+    output = file
+  }
+  writer, err := newStockWriter(*format, output)
+  if err != nil {
+    return err
+  }
+
+  var count int
+  err = fetcherStorage.GetStocks(*tickerId, from, to, func(stock *domain.Stock) error {
+    count++
+    return writer.Write(stock)
+  })
+  if err != nil {
+    return err
+  }
+  if err = writer.Flush(); err != nil {
+    return fmt.Errorf("cannot write output: %v", err)
+  }
+  log.Infof("exported %d stocks", count)
+  return nil
+}
+
+type stockWriter interface {
+  Write(stock *domain.Stock) error
+  Flush() error
+}
+
+func newStockWriter(format string, w io.Writer) (stockWriter, error) {
+  if format == exportFormatJSON {
+    return &jsonStockWriter{encoder: json.NewEncoder(w)}, nil
+  }
+  writer := csv.NewWriter(w)
+  if err := writer.Write(exportCSVHeader); err != nil {
+    return nil, fmt.Errorf("cannot write csv header: %v", err)
+  }
+  return &csvStockWriter{writer: writer}, nil
+}
+
+type csvStockWriter struct {
+  writer *csv.Writer
+}
+
+func (w *csvStockWriter) Write(stock *domain.Stock) error {
+  return w.writer.Write([]string{
+    stock.StockId,
+    stock.TickerId,
+    stock.OpenPrice.String(),
+    stock.ClosePrice.String(),
+    stock.HighestPrice.String(),
+    stock.LowestPrice.String(),
+    stock.TradingVolume.String(),
+    stock.StockedAt.UTC().Format(time.RFC3339),
+  })
+}
+
+func (w *csvStockWriter) Flush() error {
+  w.writer.Flush()
+  return w.writer.Error()
+}
+
+// jsonStockWriter write stock per line
+type jsonStockWriter struct {
+  encoder *json.Encoder
+}
+
+func (w *jsonStockWriter) Write(stock *domain.Stock) error {
+  return w.encoder.Encode(stock)
+}
+
+func (w *jsonStockWriter) Flush() error {
+  return nil
+}
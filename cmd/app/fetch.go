@@ -0,0 +1,93 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/fetcher"
+  "scientific-research/internal/fetcher/fetchers/polygon"
+  "time"
+)
+
+func runFetch(ctx context.Context, args []string) error {
+  return runSubcommand(ctx, "fetch", args, map[string]command{
+    "once":   runFetchOnce,
+    "ticker": runFetchTicker,
+  })
+}
+
+// runFetchOnce run single tickers fetching cycle. cycle shares checkpoint with service
+// running without sharding, so service must be stopped
+func runFetchOnce(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("fetch once")
+  parseFlags(flags, args)
+
+  cfg, f, err := newOneOffFetcher(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  stopTracing, err := startTracing(ctx, cfg)
+  if err != nil {
+    return err
+  }
+  defer stopTracing()
+
+  if err = f.FetchOnce(); err != nil {
+    return err
+  }
+  log.Infof("tickers fetching cycle finished")
+  return nil
+}
+
+// runFetchTicker fetch stocks of ticker for date range. range defaults to the total mode window
+func runFetchTicker(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("fetch ticker")
+  fromFlag := flags.String("from", "", "range start date, 2006-01-02. total mode window start by default")
+  toFlag := flags.String("to", "", "range end date inclusive, 2006-01-02. today by default")
+  positional := parseFlags(flags, args)
+
+  if len(positional) != 1 {
+    return fmt.Errorf("single ticker is required, e.g. 'fetch ticker AAPL -from 2024-01-01'")
+  }
+  from, err := parseDate(*fromFlag)
+  if err != nil {
+    return err
+  }
+  to, err := parseDate(*toFlag)
+  if err != nil {
+    return err
+  }
+  cfg, f, err := newOneOffFetcher(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  stopTracing, err := startTracing(ctx, cfg)
+  if err != nil {
+    return err
+  }
+  defer stopTracing()
+
+  if to.IsZero() {
+    to = time.Now().UTC()
+  }
+  if from.IsZero() {
+    from = to.Add(-time.Duration(cfg.ModeTotalHours) * time.Hour)
+  }
+  if err = f.FetchTickerStocks(positional[0], from, to); err != nil {
+    return err
+  }
+  log.Infof("stocks of ticker '%s' fetched from %s to %s",
+    positional[0], from.Format(dateLayout), to.Format(dateLayout))
+  return nil
+}
+
+func newOneOffFetcher(ctx context.Context, configPath string) (*polygon.Config, fetcher.Fetcher, error) {
+  cfg, fetcherStorage, err := loadStorage(ctx, configPath)
+  if err != nil {
+    return nil, nil, err
+  }
+  f, err := polygon.NewFetcher(ctx, cfg, polygon.WithStorage(fetcherStorage))
+  if err != nil {
+    return nil, nil, fmt.Errorf("cannot create new fetcher: %v", err)
+  }
+  return cfg, f, nil
+}
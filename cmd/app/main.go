@@ -3,48 +3,151 @@ package main
 import (
   "context"
   "flag"
-  "net/http"
+  "fmt"
   "os"
-  "os/signal"
   "scientific-research/internal/fetcher/fetchers/polygon"
-  "scientific-research/pkg/utils/httputils"
-  "syscall"
+  "scientific-research/internal/storage"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
+  "strings"
+  "time"
+)
+
+var log = logging.Logger("app")
 
-  log "github.com/sirupsen/logrus"
+const (
+  dateLayout              = "2006-01-02"
+  tracingShutdownDuration = 5 * time.Second
 )
 
+const usage = `usage: app <command> [flags]
+
+commands:
+  serve                               run fetcher service (default command)
+  fetch once                          run single tickers fetching cycle
+  fetch ticker <ticker> [-from -to]   fetch stocks of ticker for date range
+  state show                          print fetcher checkpoint, shards progress and jobs
+  state reset                         reset tickers cursor, the next cycle starts from the first page
+  tickers list                        list stored tickers
+  migrate                             apply database migrations
+  export                              export stored stocks as csv or json lines
+
+every command takes '-path' flag with service config. run 'app <command> -h' for command flags`
+
+type command func(ctx context.Context, args []string) error
+
 func main() {
   ctx := context.Background()
 
-  servePort := flag.String("port", "8080", "serving port")
-  configPath := flag.String("path", "", "path to service config file")
-  tickerId := flag.String("ticker", "", "specified ticker id for fetching")
-  flag.Parse()
+  args := os.Args[1:]
+  // service was run with flags only before subcommands were added
+  if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+    args = append([]string{"serve"}, args...)
+  }
+  commands := map[string]command{
+    "serve":   runServe,
+    "fetch":   runFetch,
+    "state":   runState,
+    "tickers": runTickers,
+    "migrate": runMigrate,
+    "export":  runExport,
+  }
+  if args[0] == "help" {
+    fmt.Println(usage)
+    return
+  }
+  run, ok := commands[args[0]]
+  if !ok {
+    fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n%s\n", args[0], usage)
+    os.Exit(2)
+  }
+  if err := run(ctx, args[1:]); err != nil {
+    log.Fatalf("%s failed: %v", args[0], err)
+  }
+}
 
-  cfg := polygon.NewConfig()
-  if err := cfg.Parse(*configPath); err != nil {
-    log.Fatalf("cannot parse fetcher config: %v", err)
+// runSubcommand run subcommand of command group, e.g. `once` of `fetch`
+func runSubcommand(ctx context.Context, group string, args []string, subcommands map[string]command) error {
+  if len(args) == 0 {
+    fmt.Fprintln(os.Stderr, usage)
+    return fmt.Errorf("subcommand of '%s' is required", group)
   }
+  run, ok := subcommands[args[0]]
+  if !ok {
+    fmt.Fprintln(os.Stderr, usage)
+    return fmt.Errorf("unknown subcommand '%s %s'", group, args[0])
+  }
+  return run(ctx, args[1:])
+}
 
-  fetcher, err := polygon.NewFetcher(ctx, cfg)
-  if err != nil {
-    log.Fatalf("cannot create new fetcher: %v", err)
+// newFlagSet create flag set of command with `-path` flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+  flags := flag.NewFlagSet(name, flag.ExitOnError)
+  configPath := flags.String("path", "", "path to service config file")
+  return flags, configPath
+}
+
+// parseFlags parse flags placed before and after positional args. return positional args
+func parseFlags(flags *flag.FlagSet, args []string) []string {
+  var positional []string
+  for {
+    // flag set exits on parsing errors
+    _ = flags.Parse(args)
+    if flags.NArg() == 0 {
+      return positional
+    }
+    positional = append(positional, flags.Arg(0))
+    args = flags.Args()[1:]
+  }
+}
+
+func loadConfig(configPath string) (*polygon.Config, error) {
+  cfg := polygon.NewConfig()
+  if err := cfg.Parse(configPath); err != nil {
+    return nil, fmt.Errorf("cannot parse fetcher config: %v", err)
   }
-  if *tickerId != "" {
-    fetcher.SetTickerId(*tickerId)
+  if err := logging.Setup(cfg.Logging); err != nil {
+    return nil, fmt.Errorf("cannot set up logging: %v", err)
   }
+  return cfg, nil
+}
 
-  http.Handle("/health", httputils.HandleHealth())
-  go httputils.ContinuouslyServe(*servePort)
+// startTracing set up tracing of command. returned func flushes exported spans
+func startTracing(ctx context.Context, cfg *polygon.Config) (func(), error) {
+  shutdown, err := tracing.Setup(ctx, cfg.Tracing, polygon.FetcherName)
+  if err != nil {
+    return nil, fmt.Errorf("cannot set up tracing: %v", err)
+  }
+  return func() {
+    ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownDuration)
+    defer cancel()
 
-  go fetcher.ContinuouslyFetch()
-  defer fetcher.SaveFetcherState()
+    if err := shutdown(ctx); err != nil {
+      log.Errorf("cannot flush traces: %v", err)
+    }
+  }, nil
+}
 
-  serviceShutdown()
+func loadStorage(ctx context.Context, configPath string) (*polygon.Config, storage.Storage, error) {
+  cfg, err := loadConfig(configPath)
+  if err != nil {
+    return nil, nil, err
+  }
+  fetcherStorage, err := storage.NewStorage(ctx, cfg.StorageConfig)
+  if err != nil {
+    return nil, nil, fmt.Errorf("cannot create new storage: %v", err)
+  }
+  return cfg, fetcherStorage, nil
 }
 
-func serviceShutdown() {
-  exitSignal := make(chan os.Signal)
-  signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
-  <-exitSignal
+// parseDate parse `2006-01-02` date, zero time is returned for empty value
+func parseDate(value string) (time.Time, error) {
+  if value == "" {
+    return time.Time{}, nil
+  }
+  date, err := time.Parse(dateLayout, value)
+  if err != nil {
+    return time.Time{}, fmt.Errorf("malformed date '%s', expected format %s", value, dateLayout)
+  }
+  return date, nil
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+  "context"
+)
+
+// runMigrate apply database migrations which are not applied yet
+func runMigrate(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("migrate")
+  parseFlags(flags, args)
+
+  _, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  applied, err := fetcherStorage.Migrate()
+  if err != nil {
+    return err
+  }
+  if len(applied) == 0 {
+    log.Infof("database is up to date")
+    return nil
+  }
+  log.Infof("applied %d migrations: %v", len(applied), applied)
+  return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "os"
+  "os/signal"
+  "scientific-research/internal/admin"
+  "scientific-research/internal/fetcher"
+  "scientific-research/internal/fetcher/fetchers/polygon"
+  "scientific-research/internal/leader"
+  "scientific-research/internal/shard"
+  "scientific-research/pkg/utils/config"
+  "scientific-research/pkg/utils/httputils"
+  "scientific-research/pkg/utils/logging"
+  "syscall"
+  "time"
+)
+
+const configWatchInterval = 10 * time.Second
+
+// runServe run fetcher service until exit signal
+func runServe(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("serve")
+  servePort := flags.String("port", "8080", "serving port")
+  watchlist := flags.String("ticker", "",
+    "watchlist: comma separated ticker ids or patterns of tickers request params, e.g. 'AAPL,MSFT,exchange=XNAS'")
+  parseFlags(flags, args)
+
+  cfg, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  stopTracing, err := startTracing(ctx, cfg)
+  if err != nil {
+    return err
+  }
+  defer stopTracing()
+
+  fetcherOptions := []polygon.Option{polygon.WithStorage(fetcherStorage)}
+
+  ctx, cancel := context.WithCancel(ctx)
+  defer cancel()
+
+  if cfg.ShardingEnabled() {
+    coordinator, err := shard.NewCoordinator(fetcherStorage, cfg.Sharding, polygon.FetcherName)
+    if err != nil {
+      return fmt.Errorf("cannot create shard coordinator: %v", err)
+    }
+    if err = coordinator.Join(ctx); err != nil {
+      return fmt.Errorf("cannot join fetcher instances: %v", err)
+    }
+    coordinatorDone := make(chan struct{})
+    go func() {
+      defer close(coordinatorDone)
+      coordinator.Run(ctx)
+    }()
+    // instance leaves sharding after its state is saved
+    defer func() {
+      cancel()
+      <-coordinatorDone
+    }()
+    fetcherOptions = append(fetcherOptions, polygon.WithShardCoordinator(coordinator))
+  }
+
+  // fetching is fenced by cancel of its context when leadership is lost
+  fetchCtx, fence := context.WithCancel(ctx)
+  defer fence()
+
+  fetcher, err := polygon.NewFetcher(fetchCtx, cfg, fetcherOptions...)
+  if err != nil {
+    return fmt.Errorf("cannot create new fetcher: %v", err)
+  }
+  if *watchlist != "" {
+    if err = fetcher.SetWatchlist(polygon.SplitWatchlistEntries(*watchlist)...); err != nil {
+      return fmt.Errorf("cannot set watchlist: %v", err)
+    }
+  }
+
+  http.Handle("/health", httputils.HandleHealth())
+  if cfg.Admin.Enabled() {
+    if err = admin.RegisterHandlers(http.DefaultServeMux, fetcher, cfg.Admin.Token); err != nil {
+      return fmt.Errorf("cannot register admin handlers: %v", err)
+    }
+  }
+  go httputils.ContinuouslyServe(*servePort)
+
+  go watchConfigReload(ctx, *configPath, fetcher)
+
+  if cfg.LeaderElectionEnabled() {
+    return runWithLeaderElection(ctx, cfg, fetcherStorage, fetcher, fence)
+  }
+  go fetcher.ContinuouslyFetch()
+  serviceShutdown()
+  fetcher.SaveFetcherState()
+
+  return nil
+}
+
+// runWithLeaderElection run fetching only while instance holds the fetcher lease.
+// fetching cannot be restarted after its context is cancelled, so when leadership is lost
+// fetching is fenced before the lease expires and instance exits to restart as standby
+//   fence cancel of fetcher context
+func runWithLeaderElection(ctx context.Context, cfg *polygon.Config, leaseStorage leader.LeaseStorage,
+  f fetcher.Fetcher, fence context.CancelFunc) error {
+  elector, err := leader.NewElector(leaseStorage, cfg.LeaderElection, polygon.FetcherName)
+  if err != nil {
+    return fmt.Errorf("cannot create leader elector: %v", err)
+  }
+  ctx, cancel := context.WithCancel(ctx)
+  electionDone := make(chan struct{})
+  leadershipLost := make(chan struct{})
+
+  go func() {
+    defer close(electionDone)
+    elector.Run(ctx,
+      func() {
+        go f.ContinuouslyFetch()
+      },
+      func() {
+        fence()
+        close(leadershipLost)
+        // instance exits, lease is not acquired again
+        cancel()
+      },
+    )
+  }()
+
+  exitSignal := make(chan struct{})
+  go func() {
+    serviceShutdown()
+    close(exitSignal)
+  }()
+
+  select {
+  case <-exitSignal:
+  case <-leadershipLost:
+    <-electionDone
+    return fmt.Errorf("leadership of fetcher is lost. exit to restart as standby")
+  }
+
+  // standby instance must not overwrite state of the leader
+  if elector.IsLeader() {
+    f.SaveFetcherState()
+  }
+  cancel()
+  <-electionDone
+  return nil
+}
+
+// watchConfigReload reload fetcher config on SIGHUP and on config file change
+func watchConfigReload(ctx context.Context, configPath string, f fetcher.Fetcher) {
+  reloadSignal := make(chan os.Signal, 1)
+  signal.Notify(reloadSignal, syscall.SIGHUP)
+
+  fileChanged := make(chan struct{}, 1)
+  go config.Watch(ctx, configPath, configWatchInterval, func() {
+    select {
+    case fileChanged <- struct{}{}:
+    default:
+    }
+  })
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-reloadSignal:
+      log.Infof("got reload signal. reload config '%s'", configPath)
+    case <-fileChanged:
+      log.Infof("config file '%s' changed. reload config", configPath)
+    }
+    reloadConfig(configPath, f)
+  }
+}
+
+func reloadConfig(configPath string, f fetcher.Fetcher) {
+  cfg := polygon.NewConfig()
+  if err := cfg.Parse(configPath); err != nil {
+    log.Errorf("cannot parse reloaded config. keep running config: %v", err)
+    return
+  }
+  // log levels are applied on reload, e.g. to debug running fetcher
+  if err := logging.Setup(cfg.Logging); err != nil {
+    log.Errorf("cannot apply reloaded logging config: %v", err)
+  }
+  if err := f.ApplyConfig(cfg); err != nil {
+    log.Errorf("reloaded config partially applied: %v", err)
+  }
+}
+
+func serviceShutdown() {
+  exitSignal := make(chan os.Signal, 1)
+  signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
+  <-exitSignal
+}
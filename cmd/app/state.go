@@ -0,0 +1,76 @@
+package main
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "os"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/fetcher/fetchers/polygon"
+  "scientific-research/pkg/utils/timeutils"
+)
+
+// stateView state of fetcher printed by `state show`
+type stateView struct {
+  FetcherState *domain.FetcherState `json:"fetcher_state"`
+  Shards       []*domain.ShardState `json:"shards"`
+  Jobs         []*domain.JobState   `json:"jobs"`
+}
+
+func runState(ctx context.Context, args []string) error {
+  return runSubcommand(ctx, "state", args, map[string]command{
+    "show":  runStateShow,
+    "reset": runStateReset,
+  })
+}
+
+// runStateShow print the latest checkpoint, shards progress and scheduled jobs as JSON
+func runStateShow(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("state show")
+  memberId := flags.String("member", "", "sharding member id of checkpoint, empty without sharding")
+  parseFlags(flags, args)
+
+  _, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  view := &stateView{}
+
+  fetcherState, found, err := fetcherStorage.GetFetcherState(*memberId)
+  if err != nil {
+    return err
+  }
+  if found {
+    view.FetcherState = fetcherState
+  }
+  if view.Shards, err = fetcherStorage.GetShardStates(polygon.FetcherName); err != nil {
+    return err
+  }
+  if view.Jobs, err = fetcherStorage.GetJobStates(); err != nil {
+    return err
+  }
+  encoder := json.NewEncoder(os.Stdout)
+  encoder.SetIndent("", "  ")
+  return encoder.Encode(view)
+}
+
+// runStateReset put empty checkpoint, so the next tickers cycle starts from the first page.
+// running fetcher overwrites checkpoint, so it must be stopped
+func runStateReset(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("state reset")
+  memberId := flags.String("member", "", "sharding member id of checkpoint, empty without sharding")
+  parseFlags(flags, args)
+
+  _, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  if err = fetcherStorage.PutFetcherState(&domain.FetcherState{
+    MemberId:  *memberId,
+    CreatedAt: timeutils.NotTimeUTC(),
+  }); err != nil {
+    return fmt.Errorf("cannot reset fetcher state: %v", err)
+  }
+  log.Infof("fetcher state of member '%s' reset", *memberId)
+  return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "os"
+  "scientific-research/internal/fetcher/fetchers/polygon"
+  "text/tabwriter"
+)
+
+const defaultTickersLimit = 100
+
+func runTickers(ctx context.Context, args []string) error {
+  return runSubcommand(ctx, "tickers", args, map[string]command{
+    "list": runTickersList,
+  })
+}
+
+// runTickersList print page of stored tickers with their watermarks
+func runTickersList(ctx context.Context, args []string) error {
+  flags, configPath := newFlagSet("tickers list")
+  after := flags.String("after", "", "list tickers after this ticker id")
+  limit := flags.Int("limit", defaultTickersLimit, "count of listed tickers")
+  parseFlags(flags, args)
+
+  if *limit <= 0 {
+    return fmt.Errorf("limit must be positive")
+  }
+  _, fetcherStorage, err := loadStorage(ctx, *configPath)
+  if err != nil {
+    return err
+  }
+  tickers, err := fetcherStorage.GetTickers(*after, *limit)
+  if err != nil {
+    return err
+  }
+  writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+  fmt.Fprintln(writer, "TICKER\tNAME\tACTIVE\tCURRENCY\tLAST BAR")
+
+  for _, ticker := range tickers {
+    lastBar := "-"
+    syncState, found, err := fetcherStorage.GetTickerSyncState(ticker.TickerId, polygon.StocksInterval)
+    if err != nil {
+      return err
+    }
+    if found {
+      lastBar = syncState.LastBarAt.Format(dateLayout)
+    }
+    fmt.Fprintf(writer, "%s\t%s\t%t\t%s\t%s\n",
+      ticker.TickerId, ticker.CompanyName, ticker.Active, ticker.CurrencyName, lastBar)
+  }
+  return writer.Flush()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+  "context"
+  "flag"
+  "net/http"
+  "os"
+  "os/signal"
+  "scientific-research/internal/media"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/httputils"
+  "scientific-research/pkg/utils/logging"
+  "syscall"
+  "time"
+)
+
+var log = logging.Logger("media")
+
+const (
+  serviceName             = "media_service"
+  tracingShutdownDuration = 5 * time.Second
+)
+
+func main() {
+  ctx := context.Background()
+
+  servePort := flag.String("port", "8081", "serving port")
+  configPath := flag.String("path", "", "path to media service config file")
+  flag.Parse()
+
+  cfg := media.NewConfig()
+  if err := cfg.Parse(*configPath); err != nil {
+    log.Fatalf("cannot parse media service config: %v", err)
+  }
+  if err := logging.Setup(cfg.Logging); err != nil {
+    log.Fatalf("cannot set up logging: %v", err)
+  }
+  shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, serviceName)
+  if err != nil {
+    log.Fatalf("cannot set up tracing: %v", err)
+  }
+  defer func() {
+    ctx, cancel := context.WithTimeout(ctx, tracingShutdownDuration)
+    defer cancel()
+
+    if err := shutdownTracing(ctx); err != nil {
+      log.Errorf("cannot flush traces: %v", err)
+    }
+  }()
+
+  service, err := media.NewService(ctx, cfg)
+  if err != nil {
+    log.Fatalf("cannot create new media service: %v", err)
+  }
+  defer func() {
+    if err := service.Close(); err != nil {
+      log.Errorf("cannot close media service: %v", err)
+    }
+  }()
+
+  http.Handle("/health", httputils.HandleHealth())
+  go httputils.ContinuouslyServe(*servePort)
+
+  go service.ContinuouslyConsume()
+
+  serviceShutdown()
+}
+
+func serviceShutdown() {
+  exitSignal := make(chan os.Signal, 1)
+  signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
+  <-exitSignal
+}
@@ -0,0 +1,12 @@
+package admin
+
+// Config admin HTTP API of running fetcher. API is disabled if token is empty,
+// requests must have `Authorization: Bearer <token>` header
+type Config struct {
+  Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+// Enabled return true if admin API must be served
+func (c *Config) Enabled() bool {
+  return c != nil && c.Token != ""
+}
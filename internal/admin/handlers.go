@@ -0,0 +1,253 @@
+package admin
+
+import (
+  "crypto/subtle"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "scientific-research/internal/fetcher"
+  "scientific-research/internal/scheduler"
+  "scientific-research/pkg/utils/logging"
+  "strings"
+  "time"
+)
+
+var log = logging.Logger("admin")
+
+const (
+  pathPrefix = "/admin/"
+  dateLayout = "2006-01-02"
+
+  bearerPrefix = "Bearer "
+  // maxBodySize limit of request body, requests have short json bodies only
+  maxBodySize = 1 << 20
+)
+
+type handlers struct {
+  controller fetcher.Controller
+  token      string
+}
+
+type watchlistRequest struct {
+  Entries []string `json:"entries"`
+}
+
+type backfillRequest struct {
+  Ticker string `json:"ticker"`
+  From   string `json:"from"`
+  To     string `json:"to"`
+}
+
+type errorResponse struct {
+  Error string `json:"error"`
+}
+
+type okResponse struct {
+  Status string `json:"status"`
+}
+
+// RegisterHandlers register admin API handlers of controller in mux
+//   token bearer token required by all handlers
+func RegisterHandlers(mux *http.ServeMux, controller fetcher.Controller, token string) error {
+  if controller == nil {
+    return fmt.Errorf("controller is a nil")
+  }
+  if token == "" {
+    return fmt.Errorf("admin token is empty")
+  }
+  h := &handlers{controller: controller, token: token}
+
+  routes := []struct {
+    path    string
+    method  string
+    handler func(r *http.Request) (any, error)
+  }{
+    {"status", http.MethodGet, h.status},
+    {"pause", http.MethodPost, h.pause},
+    {"resume", http.MethodPost, h.resume},
+    {"trigger", http.MethodPost, h.trigger},
+    {"watchlist/add", http.MethodPost, h.addWatchlist},
+    {"watchlist/remove", http.MethodPost, h.removeWatchlist},
+    {"cursor/reset", http.MethodPost, h.resetCursor},
+    {"backfill", http.MethodPost, h.backfill},
+  }
+  for _, route := range routes {
+    mux.Handle(pathPrefix+route.path, h.handle(route.method, route.handler))
+  }
+  return nil
+}
+
+// handle check method and token, write result of handler as json
+func (h *handlers) handle(method string, handler func(r *http.Request) (any, error)) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if !h.authorized(r) {
+      writeJSON(w, http.StatusUnauthorized, &errorResponse{Error: "unauthorized"})
+      return
+    }
+    if r.Method != method {
+      w.Header().Set("Allow", method)
+      writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Error: "method not allowed"})
+      return
+    }
+    r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+    result, err := handler(r)
+    if err != nil {
+      status := errorStatus(err)
+      if status == http.StatusInternalServerError {
+        log.Errorf("admin request %s failed: %v", r.URL.Path, err)
+      }
+      writeJSON(w, status, &errorResponse{Error: err.Error()})
+      return
+    }
+    log.Infof("admin request %s %s done", r.Method, r.URL.RequestURI())
+    writeJSON(w, http.StatusOK, result)
+  }
+}
+
+func (h *handlers) authorized(r *http.Request) bool {
+  header := r.Header.Get("Authorization")
+  if !strings.HasPrefix(header, bearerPrefix) {
+    return false
+  }
+  token := strings.TrimPrefix(header, bearerPrefix)
+  return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *handlers) status(_ *http.Request) (any, error) {
+  return h.controller.Status(), nil
+}
+
+func (h *handlers) pause(_ *http.Request) (any, error) {
+  h.controller.Pause()
+  return h.controller.Status(), nil
+}
+
+func (h *handlers) resume(_ *http.Request) (any, error) {
+  h.controller.Resume()
+  return h.controller.Status(), nil
+}
+
+// trigger run job of `job` query param, all jobs if it is empty
+func (h *handlers) trigger(r *http.Request) (any, error) {
+  if err := h.controller.Trigger(r.URL.Query().Get("job")); err != nil {
+    return nil, err
+  }
+  return &okResponse{Status: "triggered"}, nil
+}
+
+func (h *handlers) addWatchlist(r *http.Request) (any, error) {
+  req := &watchlistRequest{}
+  if err := decodeWatchlistRequest(r, req); err != nil {
+    return nil, err
+  }
+  if err := h.controller.AddWatchlist(req.Entries...); err != nil {
+    return nil, &badRequestError{err: err}
+  }
+  return h.controller.Status(), nil
+}
+
+func (h *handlers) removeWatchlist(r *http.Request) (any, error) {
+  req := &watchlistRequest{}
+  if err := decodeWatchlistRequest(r, req); err != nil {
+    return nil, err
+  }
+  if err := h.controller.RemoveWatchlist(req.Entries...); err != nil {
+    return nil, &badRequestError{err: err}
+  }
+  return h.controller.Status(), nil
+}
+
+func (h *handlers) resetCursor(_ *http.Request) (any, error) {
+  if err := h.controller.ResetCursor(); err != nil {
+    return nil, err
+  }
+  return &okResponse{Status: "reset"}, nil
+}
+
+// backfill queue fetching of ticker stocks. dates are `2006-01-02` days of exchange calendar
+func (h *handlers) backfill(r *http.Request) (any, error) {
+  req := &backfillRequest{}
+  if err := decodeBody(r, req); err != nil {
+    return nil, err
+  }
+  from, err := parseDate("from", req.From)
+  if err != nil {
+    return nil, err
+  }
+  to, err := parseDate("to", req.To)
+  if err != nil {
+    return nil, err
+  }
+  if err = h.controller.Backfill(req.Ticker, from, to); err != nil {
+    return nil, &badRequestError{err: err}
+  }
+  return &okResponse{Status: "queued"}, nil
+}
+
+func decodeWatchlistRequest(r *http.Request, req *watchlistRequest) error {
+  if err := decodeBody(r, req); err != nil {
+    return err
+  }
+  if len(req.Entries) == 0 {
+    return &badRequestError{err: fmt.Errorf("entries are empty")}
+  }
+  return nil
+}
+
+func decodeBody(r *http.Request, v any) error {
+  decoder := json.NewDecoder(r.Body)
+  decoder.DisallowUnknownFields()
+  if err := decoder.Decode(v); err != nil {
+    return &badRequestError{err: fmt.Errorf("cannot decode request body: %v", err)}
+  }
+  return nil
+}
+
+func parseDate(name, value string) (time.Time, error) {
+  date, err := time.Parse(dateLayout, value)
+  if err != nil {
+    return time.Time{}, &badRequestError{err: fmt.Errorf("invalid '%s' date '%s', expected %s", name, value, dateLayout)}
+  }
+  return date, nil
+}
+
+// badRequestError error caused by request content. wrapped errors of controller keep
+// their status, e.g. fetcher is not running
+type badRequestError struct {
+  err error
+}
+
+func (e *badRequestError) Error() string {
+  return e.err.Error()
+}
+
+func (e *badRequestError) Unwrap() error {
+  return e.err
+}
+
+// errorStatus return http status of controller error
+func errorStatus(err error) int {
+  var badRequest *badRequestError
+
+  switch {
+  case errors.Is(err, fetcher.ErrNotRunning), errors.Is(err, fetcher.ErrBackfillQueueFull):
+    return http.StatusServiceUnavailable
+  case errors.Is(err, fetcher.ErrJobRunning):
+    return http.StatusConflict
+  case errors.Is(err, scheduler.ErrUnknownJob):
+    return http.StatusNotFound
+  case errors.As(err, &badRequest):
+    return http.StatusBadRequest
+  }
+  return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  if err := json.NewEncoder(w).Encode(v); err != nil {
+    log.Errorf("cannot write admin response: %v", err)
+  }
+}
@@ -0,0 +1,238 @@
+package admin
+
+import (
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "scientific-research/internal/fetcher"
+  "strings"
+  "testing"
+  "time"
+)
+
+const testToken = "secret"
+
+// memController controller which records watchlist and cursor resets. resetErr is returned
+// by cursor reset
+type memController struct {
+  watchlist []string
+  resets    int
+  resetErr  error
+}
+
+func (c *memController) Pause()                       {}
+func (c *memController) Resume()                      {}
+func (c *memController) Trigger(jobName string) error { return nil }
+
+func (c *memController) AddWatchlist(entries ...string) error {
+  for _, entry := range entries {
+    if strings.TrimSpace(entry) == "" {
+      return fmt.Errorf("watchlist entry is empty")
+    }
+  }
+  c.watchlist = append(c.watchlist, entries...)
+  return nil
+}
+
+func (c *memController) RemoveWatchlist(entries ...string) error {
+  removed := map[string]bool{}
+  for _, entry := range entries {
+    removed[entry] = true
+  }
+  var watchlist []string
+  for _, entry := range c.watchlist {
+    if !removed[entry] {
+      watchlist = append(watchlist, entry)
+    }
+  }
+  c.watchlist = watchlist
+  return nil
+}
+
+func (c *memController) ResetCursor() error {
+  if c.resetErr != nil {
+    return c.resetErr
+  }
+  c.resets++
+  return nil
+}
+
+func (c *memController) Backfill(tickerId string, from, to time.Time) error {
+  return nil
+}
+
+func (c *memController) Status() *fetcher.Status {
+  return &fetcher.Status{Watchlist: c.watchlist}
+}
+
+func newTestServer(t *testing.T, controller fetcher.Controller) *httptest.Server {
+  t.Helper()
+
+  mux := http.NewServeMux()
+  if err := RegisterHandlers(mux, controller, testToken); err != nil {
+    t.Fatalf("cannot register handlers: %v", err)
+  }
+  srv := httptest.NewServer(mux)
+  t.Cleanup(srv.Close)
+  return srv
+}
+
+// doRequest do admin request and decode json response into v if it is not nil
+func doRequest(t *testing.T, method, reqURL, authorization, body string, v any) int {
+  t.Helper()
+
+  req, err := http.NewRequest(method, reqURL, strings.NewReader(body))
+  if err != nil {
+    t.Fatalf("cannot create request: %v", err)
+  }
+  if authorization != "" {
+    req.Header.Set("Authorization", authorization)
+  }
+  resp, err := http.DefaultClient.Do(req)
+  if err != nil {
+    t.Fatalf("cannot do request: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.Header.Get("Content-Type") != "application/json" {
+    t.Errorf("%s %s: response content type is '%s'", method, reqURL, resp.Header.Get("Content-Type"))
+  }
+  if v != nil {
+    if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+      t.Fatalf("cannot decode response: %v", err)
+    }
+  }
+  return resp.StatusCode
+}
+
+func TestRegisterHandlersRequiresToken(t *testing.T) {
+  if err := RegisterHandlers(http.NewServeMux(), &memController{}, ""); err == nil {
+    t.Errorf("handlers are registered without token")
+  }
+  if err := RegisterHandlers(http.NewServeMux(), nil, testToken); err == nil {
+    t.Errorf("handlers are registered without controller")
+  }
+}
+
+func TestHandlersCheckTokenAndMethod(t *testing.T) {
+  controller := &memController{}
+  srv := newTestServer(t, controller)
+
+  tests := []struct {
+    name          string
+    method        string
+    path          string
+    authorization string
+    wantStatus    int
+  }{
+    {"missing token", http.MethodPost, "cursor/reset", "", http.StatusUnauthorized},
+    {"wrong token", http.MethodPost, "cursor/reset", bearerPrefix + "other", http.StatusUnauthorized},
+    {"token without bearer prefix", http.MethodPost, "cursor/reset", testToken, http.StatusUnauthorized},
+    {"wrong token before method check", http.MethodGet, "cursor/reset", bearerPrefix + "other", http.StatusUnauthorized},
+    {"wrong method", http.MethodGet, "cursor/reset", bearerPrefix + testToken, http.StatusMethodNotAllowed},
+    {"wrong method of status", http.MethodPost, "status", bearerPrefix + testToken, http.StatusMethodNotAllowed},
+    {"status", http.MethodGet, "status", bearerPrefix + testToken, http.StatusOK},
+  }
+  for _, test := range tests {
+    resp := &errorResponse{}
+    status := doRequest(t, test.method, srv.URL+pathPrefix+test.path, test.authorization, "", resp)
+    if status != test.wantStatus {
+      t.Errorf("%s: got status %d, want %d", test.name, status, test.wantStatus)
+    }
+    if test.wantStatus != http.StatusOK && resp.Error == "" {
+      t.Errorf("%s: error response has no error", test.name)
+    }
+  }
+  if controller.resets != 0 {
+    t.Errorf("cursor is reset by rejected requests")
+  }
+}
+
+func TestResetCursorHandler(t *testing.T) {
+  controller := &memController{}
+  srv := newTestServer(t, controller)
+  reqURL := srv.URL + pathPrefix + "cursor/reset"
+
+  resp := &okResponse{}
+  if status := doRequest(t, http.MethodPost, reqURL, bearerPrefix+testToken, "", resp); status != http.StatusOK {
+    t.Fatalf("got status %d, want ok", status)
+  }
+  if resp.Status != "reset" || controller.resets != 1 {
+    t.Errorf("got response status '%s' and %d resets, want cursor reset once", resp.Status, controller.resets)
+  }
+
+  tests := []struct {
+    err        error
+    wantStatus int
+  }{
+    {fmt.Errorf("%w: cannot reset cursor", fetcher.ErrJobRunning), http.StatusConflict},
+    {fetcher.ErrNotRunning, http.StatusServiceUnavailable},
+    {fmt.Errorf("cannot save fetcher state: connection refused"), http.StatusInternalServerError},
+  }
+  for _, test := range tests {
+    controller.resetErr = test.err
+    errResp := &errorResponse{}
+    if status := doRequest(t, http.MethodPost, reqURL, bearerPrefix+testToken, "", errResp); status != test.wantStatus {
+      t.Errorf("%v: got status %d, want %d", test.err, status, test.wantStatus)
+    }
+    if errResp.Error != test.err.Error() {
+      t.Errorf("got error '%s', want '%v'", errResp.Error, test.err)
+    }
+  }
+}
+
+func TestWatchlistHandlers(t *testing.T) {
+  controller := &memController{}
+  srv := newTestServer(t, controller)
+  addURL := srv.URL + pathPrefix + "watchlist/add"
+  removeURL := srv.URL + pathPrefix + "watchlist/remove"
+
+  status := &fetcher.Status{}
+  if code := doRequest(t, http.MethodPost, addURL, bearerPrefix+testToken, `{"entries":["AAPL","MSFT","exchange=XNAS"]}`, status); code != http.StatusOK {
+    t.Fatalf("add: got status %d, want ok", code)
+  }
+  if !equalStrings(status.Watchlist, []string{"AAPL", "MSFT", "exchange=XNAS"}) {
+    t.Errorf("add: got watchlist %v", status.Watchlist)
+  }
+
+  status = &fetcher.Status{}
+  if code := doRequest(t, http.MethodPost, removeURL, bearerPrefix+testToken, `{"entries":["MSFT"]}`, status); code != http.StatusOK {
+    t.Fatalf("remove: got status %d, want ok", code)
+  }
+  if !equalStrings(status.Watchlist, []string{"AAPL", "exchange=XNAS"}) {
+    t.Errorf("remove: got watchlist %v", status.Watchlist)
+  }
+
+  tests := []struct {
+    name   string
+    reqURL string
+    body   string
+  }{
+    {"empty entries", addURL, `{"entries":[]}`},
+    {"unknown field", addURL, `{"entries":["AAPL"],"ticker":"MSFT"}`},
+    {"invalid json", removeURL, `{"entries":`},
+    {"entry rejected by controller", addURL, `{"entries":[" "]}`},
+  }
+  for _, test := range tests {
+    errResp := &errorResponse{}
+    if code := doRequest(t, http.MethodPost, test.reqURL, bearerPrefix+testToken, test.body, errResp); code != http.StatusBadRequest {
+      t.Errorf("%s: got status %d, want bad request", test.name, code)
+    }
+  }
+  if !equalStrings(controller.watchlist, []string{"AAPL", "exchange=XNAS"}) {
+    t.Errorf("watchlist is changed by bad requests: %v", controller.watchlist)
+  }
+}
+
+func equalStrings(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for idx := range a {
+    if a[idx] != b[idx] {
+      return false
+    }
+  }
+  return true
+}
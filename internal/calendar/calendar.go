@@ -0,0 +1,150 @@
+package calendar
+
+import (
+  "fmt"
+  "sync"
+  "time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Session regular trading session of exchange day
+type Session struct {
+  // Date exchange day at midnight in exchange timezone
+  Date       time.Time
+  Open       time.Time
+  Close      time.Time
+  EarlyClose bool
+}
+
+// Calendar trading days and sessions of exchange. weekends are never trading days.
+// holidays and early closes may be added at runtime, so calendar is guarded by mu
+type Calendar struct {
+  code     string
+  location *time.Location
+  // open and close of regular session, offset from midnight
+  open  time.Duration
+  close time.Duration
+
+  mu          sync.RWMutex
+  holidays    map[string]bool
+  earlyCloses map[string]time.Duration
+}
+
+func newCalendar(code string, location *time.Location, open, close time.Duration) *Calendar {
+  return &Calendar{
+    code:        code,
+    location:    location,
+    open:        open,
+    close:       close,
+    holidays:    map[string]bool{},
+    earlyCloses: map[string]time.Duration{},
+  }
+}
+
+// Code exchange MIC code, e.g. `XNYS`
+func (c *Calendar) Code() string {
+  return c.code
+}
+
+// Location exchange timezone
+func (c *Calendar) Location() *time.Location {
+  return c.location
+}
+
+// Date return exchange day of t at midnight in exchange timezone
+func (c *Calendar) Date(t time.Time) time.Time {
+  local := t.In(c.location)
+  return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+}
+
+// IsTradingDay return true if exchange has trading session at day of t in exchange timezone
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+  date := c.Date(t)
+  if weekday := date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+    return false
+  }
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+
+  return !c.holidays[date.Format(dateLayout)]
+}
+
+// Session return trading session at day of t. false is returned if day has no session
+func (c *Calendar) Session(t time.Time) (*Session, bool) {
+  if !c.IsTradingDay(t) {
+    return nil, false
+  }
+  date := c.Date(t)
+
+  c.mu.RLock()
+  earlyClose, isEarlyClose := c.earlyCloses[date.Format(dateLayout)]
+  c.mu.RUnlock()
+
+  closeOffset := c.close
+  if isEarlyClose {
+    closeOffset = earlyClose
+  }
+  return &Session{
+    Date:       date,
+    Open:       atOffset(date, c.open),
+    Close:      atOffset(date, closeOffset),
+    EarlyClose: isEarlyClose,
+  }, true
+}
+
+// Sessions return trading sessions of days from day of `from` to day of `to` inclusive
+func (c *Calendar) Sessions(from, to time.Time) []*Session {
+  var sessions []*Session
+  last := c.Date(to)
+  for date := c.Date(from); !date.After(last); date = date.AddDate(0, 0, 1) {
+    if session, ok := c.Session(date); ok {
+      sessions = append(sessions, session)
+    }
+  }
+  return sessions
+}
+
+// AddHoliday mark day of t as holiday without trading session
+func (c *Calendar) AddHoliday(t time.Time) {
+  date := c.Date(t).Format(dateLayout)
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.holidays[date] = true
+  delete(c.earlyCloses, date)
+}
+
+// AddEarlyClose set close time of shortened session at day of closeAt
+func (c *Calendar) AddEarlyClose(closeAt time.Time) {
+  date := c.Date(closeAt)
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.earlyCloses[date.Format(dateLayout)] = clockOf(closeAt.In(c.location))
+  delete(c.holidays, date.Format(dateLayout))
+}
+
+// atOffset return wall clock time of day. offset is applied to wall clock, so session
+// time is kept on days of DST change
+func atOffset(date time.Time, offset time.Duration) time.Time {
+  hours := int(offset / time.Hour)
+  minutes := int(offset % time.Hour / time.Minute)
+  return time.Date(date.Year(), date.Month(), date.Day(), hours, minutes, 0, 0, date.Location())
+}
+
+// clockOf return wall clock time of t as offset from midnight
+func clockOf(t time.Time) time.Duration {
+  return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// parseClock parse `15:04` wall clock time to offset from midnight
+func parseClock(value string) (time.Duration, error) {
+  clock, err := time.Parse("15:04", value)
+  if err != nil {
+    return 0, fmt.Errorf("malformed time '%s': %v", value, err)
+  }
+  return clockOf(clock), nil
+}
@@ -0,0 +1,147 @@
+package calendar
+
+import (
+  "testing"
+  "time"
+)
+
+func mustGetCalendar(t *testing.T, name string) *Calendar {
+  t.Helper()
+
+  registry, err := Default()
+  if err != nil {
+    t.Fatalf("cannot load embedded calendars: %v", err)
+  }
+  calendar, err := registry.Get(name)
+  if err != nil {
+    t.Fatalf("cannot get calendar: %v", err)
+  }
+  return calendar
+}
+
+func TestRegistryGetByAlias(t *testing.T) {
+  calendar := mustGetCalendar(t, " nyse ")
+  if calendar.Code() != "XNYS" {
+    t.Errorf("calendar code is '%s', want XNYS", calendar.Code())
+  }
+  registry, err := Default()
+  if err != nil {
+    t.Fatalf("cannot load embedded calendars: %v", err)
+  }
+  if _, err = registry.Get("XXXX"); err == nil {
+    t.Errorf("unknown calendar returned, error expected")
+  }
+}
+
+func TestIsTradingDay(t *testing.T) {
+  calendar := mustGetCalendar(t, "XNYS")
+  newYork := calendar.Location()
+
+  tests := []struct {
+    name string
+    at   time.Time
+    want bool
+  }{
+    {"weekday", time.Date(2026, 3, 2, 12, 0, 0, 0, newYork), true},
+    {"saturday", time.Date(2026, 3, 7, 12, 0, 0, 0, newYork), false},
+    {"sunday", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), false},
+    {"holiday", time.Date(2026, 11, 26, 12, 0, 0, 0, newYork), false},
+    // monday 02:00 UTC is sunday evening in New York
+    {"day in exchange timezone", time.Date(2026, 3, 9, 2, 0, 0, 0, time.UTC), false},
+  }
+  for _, test := range tests {
+    if got := calendar.IsTradingDay(test.at); got != test.want {
+      t.Errorf("%s: trading day of %s is %t, want %t", test.name, test.at, got, test.want)
+    }
+  }
+}
+
+func TestSessionKeepsWallClockOnDstChange(t *testing.T) {
+  calendar := mustGetCalendar(t, "XNYS")
+  newYork := calendar.Location()
+
+  // the first trading day of daylight saving time
+  session, ok := calendar.Session(time.Date(2026, 3, 9, 12, 0, 0, 0, newYork))
+  if !ok {
+    t.Fatalf("no session on trading day")
+  }
+  if want := time.Date(2026, 3, 9, 13, 30, 0, 0, time.UTC); !session.Open.Equal(want) {
+    t.Errorf("session opens at %s, want %s", session.Open.UTC(), want)
+  }
+  if want := time.Date(2026, 3, 9, 20, 0, 0, 0, time.UTC); !session.Close.Equal(want) {
+    t.Errorf("session closes at %s, want %s", session.Close.UTC(), want)
+  }
+  if session.EarlyClose {
+    t.Errorf("regular session marked as early close")
+  }
+}
+
+func TestSessionEarlyClose(t *testing.T) {
+  calendar := mustGetCalendar(t, "XNYS")
+  newYork := calendar.Location()
+
+  session, ok := calendar.Session(time.Date(2026, 11, 27, 9, 0, 0, 0, newYork))
+  if !ok {
+    t.Fatalf("no session on early close day")
+  }
+  if want := time.Date(2026, 11, 27, 13, 0, 0, 0, newYork); !session.Close.Equal(want) || !session.EarlyClose {
+    t.Errorf("session closes at %s (early: %t), want early close at %s", session.Close, session.EarlyClose, want)
+  }
+}
+
+func TestSessions(t *testing.T) {
+  calendar := mustGetCalendar(t, "XNYS")
+  newYork := calendar.Location()
+
+  // thanksgiving week: holiday on thursday, early close on friday
+  sessions := calendar.Sessions(
+    time.Date(2026, 11, 23, 0, 0, 0, 0, newYork),
+    time.Date(2026, 11, 29, 0, 0, 0, 0, newYork),
+  )
+  want := []string{"2026-11-23", "2026-11-24", "2026-11-25", "2026-11-27"}
+  if len(sessions) != len(want) {
+    t.Fatalf("got %d sessions, want %d", len(sessions), len(want))
+  }
+  for idx, session := range sessions {
+    if date := session.Date.Format(dateLayout); date != want[idx] {
+      t.Errorf("session %d is at %s, want %s", idx, date, want[idx])
+    }
+  }
+}
+
+func TestApplyMarketDays(t *testing.T) {
+  registry, err := Default()
+  if err != nil {
+    t.Fatalf("cannot load embedded calendars: %v", err)
+  }
+  nyse, err := registry.Get("XNYS")
+  if err != nil {
+    t.Fatalf("cannot get calendar: %v", err)
+  }
+  newYork := nyse.Location()
+
+  applied := registry.ApplyMarketDays([]*MarketDay{
+    {Exchange: "NYSE", Date: "2026-03-03", Status: "closed"},
+    {Exchange: "NASDAQ", Date: "2026-03-04", Status: "early-close", Close: time.Date(2026, 3, 4, 18, 0, 0, 0, time.UTC)},
+    {Exchange: "OTC", Date: "2026-03-05", Status: "closed"},
+    {Exchange: "NYSE", Date: "2026-03-05", Status: "open"},
+    nil,
+  })
+  if applied != 2 {
+    t.Errorf("applied %d market days, want 2", applied)
+  }
+  if nyse.IsTradingDay(time.Date(2026, 3, 3, 12, 0, 0, 0, newYork)) {
+    t.Errorf("upcoming holiday is a trading day")
+  }
+  nasdaq, err := registry.Get("XNAS")
+  if err != nil {
+    t.Fatalf("cannot get calendar: %v", err)
+  }
+  session, ok := nasdaq.Session(time.Date(2026, 3, 4, 12, 0, 0, 0, newYork))
+  if !ok {
+    t.Fatalf("no session on early close day")
+  }
+  if want := time.Date(2026, 3, 4, 13, 0, 0, 0, newYork); !session.Close.Equal(want) {
+    t.Errorf("session closes at %s, want %s", session.Close, want)
+  }
+}
@@ -0,0 +1,168 @@
+package calendar
+
+import (
+  _ "embed"
+  "fmt"
+  "strings"
+  "time"
+
+  "gopkg.in/yaml.v3"
+)
+
+const (
+  upcomingStatusClosed     = "closed"
+  upcomingStatusEarlyClose = "early-close"
+)
+
+//go:embed calendars.yaml
+var embeddedData []byte
+
+type calendarsData struct {
+  Calendars []*calendarData `yaml:"calendars"`
+}
+
+type calendarData struct {
+  Code         string            `yaml:"code"`
+  Aliases      []string          `yaml:"aliases"`
+  Timezone     string            `yaml:"timezone"`
+  Open         string            `yaml:"open"`
+  Close        string            `yaml:"close"`
+  Holidays     []string          `yaml:"holidays"`
+  HolidaysFrom string            `yaml:"holidays_from"`
+  EarlyCloses  map[string]string `yaml:"early_closes"`
+}
+
+// MarketDay upcoming holiday or early close of exchange, e.g. from Polygon market status
+type MarketDay struct {
+  // Exchange exchange code or alias
+  Exchange string
+  // Date exchange day in `2006-01-02` format
+  Date string
+  // Status `closed` or `early-close`
+  Status string
+  // Close close time of early close
+  Close time.Time
+}
+
+// Registry exchange calendars by MIC code and aliases
+type Registry struct {
+  calendars map[string]*Calendar
+}
+
+// Default return registry loaded from embedded calendars data
+func Default() (*Registry, error) {
+  return NewRegistry(embeddedData)
+}
+
+// NewRegistry load registry from YAML calendars data
+//   data calendars data in format of embedded `calendars.yaml`
+func NewRegistry(data []byte) (*Registry, error) {
+  parsed := &calendarsData{}
+  if err := yaml.Unmarshal(data, parsed); err != nil {
+    return nil, fmt.Errorf("cannot parse calendars data: %v", err)
+  }
+  r := &Registry{calendars: map[string]*Calendar{}}
+
+  for _, data := range parsed.Calendars {
+    calendar, err := newCalendarFromData(data)
+    if err != nil {
+      return nil, fmt.Errorf("cannot load calendar '%s': %v", data.Code, err)
+    }
+    if data.HolidaysFrom != "" {
+      source, err := r.Get(data.HolidaysFrom)
+      if err != nil {
+        return nil, fmt.Errorf("cannot load holidays of calendar '%s': %v", data.Code, err)
+      }
+      for date := range source.holidays {
+        calendar.holidays[date] = true
+      }
+      for date, closeOffset := range source.earlyCloses {
+        calendar.earlyCloses[date] = closeOffset
+      }
+    }
+    for _, name := range append([]string{data.Code}, data.Aliases...) {
+      r.calendars[strings.ToUpper(name)] = calendar
+    }
+  }
+  return r, nil
+}
+
+func newCalendarFromData(data *calendarData) (*Calendar, error) {
+  if data.Code == "" {
+    return nil, fmt.Errorf("calendar code is empty")
+  }
+  location, err := time.LoadLocation(data.Timezone)
+  if err != nil {
+    return nil, fmt.Errorf("cannot load timezone: %v", err)
+  }
+  open, err := parseClock(data.Open)
+  if err != nil {
+    return nil, fmt.Errorf("cannot parse session open: %v", err)
+  }
+  close, err := parseClock(data.Close)
+  if err != nil {
+    return nil, fmt.Errorf("cannot parse session close: %v", err)
+  }
+  calendar := newCalendar(data.Code, location, open, close)
+
+  for _, holiday := range data.Holidays {
+    date, err := time.ParseInLocation(dateLayout, holiday, location)
+    if err != nil {
+      return nil, fmt.Errorf("malformed holiday '%s': %v", holiday, err)
+    }
+    calendar.holidays[date.Format(dateLayout)] = true
+  }
+  for earlyCloseDate, earlyCloseTime := range data.EarlyCloses {
+    date, err := time.ParseInLocation(dateLayout, earlyCloseDate, location)
+    if err != nil {
+      return nil, fmt.Errorf("malformed early close date '%s': %v", earlyCloseDate, err)
+    }
+    closeOffset, err := parseClock(earlyCloseTime)
+    if err != nil {
+      return nil, fmt.Errorf("cannot parse early close of %s: %v", earlyCloseDate, err)
+    }
+    calendar.earlyCloses[date.Format(dateLayout)] = closeOffset
+  }
+  return calendar, nil
+}
+
+// Get return calendar by MIC code or alias, e.g. `XNYS` or `NYSE`
+func (r *Registry) Get(name string) (*Calendar, error) {
+  calendar, ok := r.calendars[strings.ToUpper(strings.TrimSpace(name))]
+  if !ok {
+    return nil, fmt.Errorf("unknown exchange calendar '%s'", name)
+  }
+  return calendar, nil
+}
+
+// ApplyMarketDays add upcoming holidays and early closes to calendars.
+// days of unknown exchanges are skipped. return count of applied days
+func (r *Registry) ApplyMarketDays(days []*MarketDay) int {
+  var applied int
+  for _, day := range days {
+    if day == nil {
+      continue
+    }
+    calendar, err := r.Get(day.Exchange)
+    if err != nil {
+      continue
+    }
+    date, err := time.ParseInLocation(dateLayout, day.Date, calendar.location)
+    if err != nil {
+      continue
+    }
+    switch day.Status {
+    case upcomingStatusClosed:
+      calendar.AddHoliday(date)
+    case upcomingStatusEarlyClose:
+      if day.Close.IsZero() {
+        continue
+      }
+      calendar.AddEarlyClose(day.Close)
+    default:
+      continue
+    }
+    applied++
+  }
+  return applied
+}
@@ -0,0 +1,216 @@
+package envelope
+
+import (
+  _ "embed"
+  "fmt"
+  "scientific-research/internal/domain"
+  "strconv"
+)
+
+// SchemaVersion current version of put message contract
+const SchemaVersion = 1
+
+// legacySchemaVersion messages published before contract versioning (json round trip headers)
+const legacySchemaVersion = 0
+
+const (
+  HeaderMessageId       = "message_id"
+  HeaderSchemaVersion   = "schema_version"
+  HeaderName            = "name"
+  HeaderSection         = "section"
+  HeaderContentType     = "content_type"
+  HeaderContentLength   = "content_length"
+  HeaderContentRef      = "content_ref"
+  HeaderContentChecksum = "content_checksum"
+  HeaderOverwrite       = "overwrite"
+  HeaderFrom            = "from"
+  HeaderTimestamp       = "timestamp"
+  HeaderTraceId         = "trace_id"
+  HeaderCorrelationId   = "correlation_id"
+)
+
+// JSONSchema published JSON Schema of put message headers
+//
+//go:embed put_message.v1.schema.json
+var JSONSchema []byte
+
+// EncodeHeaders encode message meta info to typed headers: strings, int64 and bool values.
+// message id and schema version are set in headers if empty, meta info is not modified
+//   metaInfo message meta info
+func EncodeHeaders(metaInfo *domain.PutMessageMetaInfo) (map[string]any, error) {
+  if metaInfo == nil {
+    return nil, fmt.Errorf("message meta info is a nil")
+  }
+  copied := *metaInfo
+  metaInfo = &copied
+
+  if metaInfo.MessageId == "" {
+    metaInfo.MessageId = domain.NewMessageId()
+  }
+  if metaInfo.SchemaVersion == legacySchemaVersion {
+    metaInfo.SchemaVersion = SchemaVersion
+  }
+  if metaInfo.SchemaVersion != SchemaVersion {
+    return nil, fmt.Errorf("unsupported schema version: %d", metaInfo.SchemaVersion)
+  }
+  headers := map[string]any{
+    HeaderMessageId:     metaInfo.MessageId,
+    HeaderSchemaVersion: int64(metaInfo.SchemaVersion),
+    HeaderName:          metaInfo.Name,
+    HeaderSection:       metaInfo.Section,
+    HeaderContentType:   metaInfo.ContentType,
+    HeaderContentLength: metaInfo.ContentLength,
+    HeaderOverwrite:     metaInfo.Overwrite,
+    HeaderFrom:          metaInfo.From,
+    HeaderTimestamp:     metaInfo.Timestamp,
+  }
+  optional := map[string]string{
+    HeaderContentRef:      metaInfo.ContentRef,
+    HeaderContentChecksum: metaInfo.ContentChecksum,
+    HeaderTraceId:         metaInfo.TraceId,
+    HeaderCorrelationId:   metaInfo.CorrelationId,
+  }
+  for key, value := range optional {
+    if value != "" {
+      headers[key] = value
+    }
+  }
+  return headers, nil
+}
+
+// Decode decode message from headers and body. legacy messages without schema version
+// are accepted. checksum is verified if message carries content
+//   headers message headers
+//   body message body
+func Decode(headers map[string]any, body []byte) (*domain.PutMessage, error) {
+  metaInfo, err := DecodeHeaders(headers)
+  if err != nil {
+    return nil, err
+  }
+  if metaInfo.ContentRef == "" && metaInfo.ContentChecksum != "" {
+    if checksum := domain.ContentChecksum(body); checksum != metaInfo.ContentChecksum {
+      return nil, fmt.Errorf("content checksum mismatch: expected '%s', got '%s'",
+        metaInfo.ContentChecksum, checksum)
+    }
+  }
+  return &domain.PutMessage{
+    MetaInfo: metaInfo,
+    Content:  body,
+  }, nil
+}
+
+// DecodeHeaders decode message meta info from headers
+//   headers message headers
+func DecodeHeaders(headers map[string]any) (*domain.PutMessageMetaInfo, error) {
+  d := &decoder{headers: headers}
+
+  schemaVersion := d.int64(HeaderSchemaVersion)
+  if schemaVersion > SchemaVersion {
+    return nil, fmt.Errorf("unsupported schema version: %d", schemaVersion)
+  }
+  metaInfo := &domain.PutMessageMetaInfo{
+    MessageId:       d.string(HeaderMessageId),
+    SchemaVersion:   int(schemaVersion),
+    Name:            d.string(HeaderName),
+    Section:         d.string(HeaderSection),
+    ContentType:     d.string(HeaderContentType),
+    ContentLength:   d.int64(HeaderContentLength),
+    ContentRef:      d.string(HeaderContentRef),
+    ContentChecksum: d.string(HeaderContentChecksum),
+    Overwrite:       d.bool(HeaderOverwrite),
+    From:            d.string(HeaderFrom),
+    Timestamp:       d.int64(HeaderTimestamp),
+    TraceId:         d.string(HeaderTraceId),
+    CorrelationId:   d.string(HeaderCorrelationId),
+  }
+  if d.err != nil {
+    return nil, d.err
+  }
+  if metaInfo.Name == "" || metaInfo.Section == "" {
+    return nil, fmt.Errorf("required headers '%s' and '%s' must be set", HeaderName, HeaderSection)
+  }
+  if schemaVersion != legacySchemaVersion && metaInfo.MessageId == "" {
+    return nil, fmt.Errorf("required header '%s' must be set", HeaderMessageId)
+  }
+  return metaInfo, nil
+}
+
+// decoder read typed header values. first error is kept, missing headers decoded as zero values
+type decoder struct {
+  headers map[string]any
+  err     error
+}
+
+func (d *decoder) fail(key string, value any) {
+  if d.err == nil {
+    d.err = fmt.Errorf("header '%s' has unexpected value '%v' of type %T", key, value, value)
+  }
+}
+
+func (d *decoder) string(key string) string {
+  value, ok := d.headers[key]
+  if !ok || value == nil {
+    return ""
+  }
+  switch v := value.(type) {
+  case string:
+    return v
+  case []byte:
+    return string(v)
+  }
+  d.fail(key, value)
+  return ""
+}
+
+func (d *decoder) int64(key string) int64 {
+  value, ok := d.headers[key]
+  if !ok || value == nil {
+    return 0
+  }
+  switch v := value.(type) {
+  case int:
+    return int64(v)
+  case int8:
+    return int64(v)
+  case int16:
+    return int64(v)
+  case int32:
+    return int64(v)
+  case int64:
+    return v
+  case uint8:
+    return int64(v)
+  case uint16:
+    return int64(v)
+  case uint32:
+    return int64(v)
+  case float32:
+    return int64(v)
+  case float64:
+    // legacy messages: numbers after json round trip
+    return int64(v)
+  case string:
+    // legacy messages: content length from http header
+    if v == "" {
+      return 0
+    }
+    parsed, err := strconv.ParseInt(v, 10, 64)
+    if err == nil {
+      return parsed
+    }
+  }
+  d.fail(key, value)
+  return 0
+}
+
+func (d *decoder) bool(key string) bool {
+  value, ok := d.headers[key]
+  if !ok || value == nil {
+    return false
+  }
+  if v, ok := value.(bool); ok {
+    return v
+  }
+  d.fail(key, value)
+  return false
+}
@@ -0,0 +1,282 @@
+package envelope
+
+import (
+  "encoding/json"
+  "net/url"
+  "reflect"
+  "regexp"
+  "scientific-research/internal/domain"
+  "testing"
+)
+
+func testMetaInfo() *domain.PutMessageMetaInfo {
+  return &domain.PutMessageMetaInfo{
+    MessageId:       "8f14e45fceea167a5a36dedd4bea2543",
+    SchemaVersion:   SchemaVersion,
+    Name:            "AAPL-icon",
+    Section:         "polygon_references",
+    ContentType:     "image/png",
+    ContentLength:   5,
+    ContentChecksum: domain.ContentChecksum([]byte("image")),
+    Overwrite:       true,
+    From:            "polygon",
+    Timestamp:       1772409600,
+    TraceId:         "4bf92f3577b34da6a3ce929d0e0e4736",
+    CorrelationId:   "a3ce929d0e0e4736",
+  }
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+  tests := []struct {
+    name   string
+    modify func(metaInfo *domain.PutMessageMetaInfo)
+  }{
+    {"inline content", func(metaInfo *domain.PutMessageMetaInfo) {}},
+    {"content reference", func(metaInfo *domain.PutMessageMetaInfo) {
+      metaInfo.ContentRef = "file:///data/objects/polygon_references/AAPL.png"
+    }},
+    {"without optional headers", func(metaInfo *domain.PutMessageMetaInfo) {
+      metaInfo.ContentChecksum = ""
+      metaInfo.TraceId = ""
+      metaInfo.CorrelationId = ""
+      metaInfo.Overwrite = false
+    }},
+  }
+  for _, test := range tests {
+    metaInfo := testMetaInfo()
+    test.modify(metaInfo)
+
+    headers, err := EncodeHeaders(metaInfo)
+    if err != nil {
+      t.Fatalf("%s: cannot encode headers: %v", test.name, err)
+    }
+    message, err := Decode(headers, []byte("image"))
+    if err != nil {
+      t.Fatalf("%s: cannot decode message: %v", test.name, err)
+    }
+    if !reflect.DeepEqual(message.MetaInfo, metaInfo) {
+      t.Errorf("%s: decoded meta info %+v, want %+v", test.name, message.MetaInfo, metaInfo)
+    }
+    if string(message.Content) != "image" {
+      t.Errorf("%s: decoded content '%s'", test.name, message.Content)
+    }
+  }
+}
+
+func TestEncodeHeadersDoesNotModifyMetaInfo(t *testing.T) {
+  metaInfo := testMetaInfo()
+  metaInfo.MessageId = ""
+  metaInfo.SchemaVersion = 0
+
+  headers, err := EncodeHeaders(metaInfo)
+  if err != nil {
+    t.Fatalf("cannot encode headers: %v", err)
+  }
+  if metaInfo.MessageId != "" || metaInfo.SchemaVersion != 0 {
+    t.Errorf("meta info is modified: message id '%s', schema version %d", metaInfo.MessageId, metaInfo.SchemaVersion)
+  }
+  if messageId, _ := headers[HeaderMessageId].(string); messageId == "" {
+    t.Errorf("message id is not generated")
+  }
+  if headers[HeaderSchemaVersion] != int64(SchemaVersion) {
+    t.Errorf("schema version header is %v, want %d", headers[HeaderSchemaVersion], SchemaVersion)
+  }
+}
+
+func TestEncodeHeadersRejectsUnsupportedVersion(t *testing.T) {
+  metaInfo := testMetaInfo()
+  metaInfo.SchemaVersion = SchemaVersion + 1
+
+  if _, err := EncodeHeaders(metaInfo); err == nil {
+    t.Errorf("unsupported schema version is encoded")
+  }
+}
+
+func TestDecode(t *testing.T) {
+  valid := func() map[string]any {
+    headers, err := EncodeHeaders(testMetaInfo())
+    if err != nil {
+      t.Fatalf("cannot encode headers: %v", err)
+    }
+    return headers
+  }
+  tests := []struct {
+    name    string
+    headers func() map[string]any
+    body    string
+    wantErr bool
+  }{
+    {"valid", valid, "image", false},
+    {"checksum mismatch", valid, "other", true},
+    {"newer schema version", func() map[string]any {
+      headers := valid()
+      headers[HeaderSchemaVersion] = int64(SchemaVersion + 1)
+      return headers
+    }, "image", true},
+    {"missing message id", func() map[string]any {
+      headers := valid()
+      delete(headers, HeaderMessageId)
+      return headers
+    }, "image", true},
+    {"missing name", func() map[string]any {
+      headers := valid()
+      delete(headers, HeaderName)
+      return headers
+    }, "image", true},
+    {"wrong header type", func() map[string]any {
+      headers := valid()
+      headers[HeaderOverwrite] = "true"
+      return headers
+    }, "image", true},
+    // headers of messages published before versioning are json round trip of meta info
+    {"legacy message", func() map[string]any {
+      return map[string]any{
+        HeaderName:          "AAPL-icon",
+        HeaderSection:       "polygon_references",
+        HeaderContentLength: "5",
+        HeaderTimestamp:     float64(1772409600),
+      }
+    }, "image", false},
+  }
+  for _, test := range tests {
+    _, err := Decode(test.headers(), []byte(test.body))
+    if gotErr := err != nil; gotErr != test.wantErr {
+      t.Errorf("%s: got error %v, want error: %t", test.name, err, test.wantErr)
+    }
+  }
+}
+
+// schemaProperty keywords of JSON schema used by put message schema
+type schemaProperty struct {
+  Type      string   `json:"type"`
+  Const     *float64 `json:"const"`
+  MinLength *int     `json:"minLength"`
+  Minimum   *float64 `json:"minimum"`
+  Pattern   string   `json:"pattern"`
+  Format    string   `json:"format"`
+}
+
+type schema struct {
+  Type       string                     `json:"type"`
+  Properties map[string]*schemaProperty `json:"properties"`
+  Required   []string                   `json:"required"`
+}
+
+func loadSchema(t *testing.T) *schema {
+  t.Helper()
+
+  s := &schema{}
+  if err := json.Unmarshal(JSONSchema, s); err != nil {
+    t.Fatalf("cannot parse JSON schema: %v", err)
+  }
+  return s
+}
+
+// validateHeaders return violations of JSON schema by encoded headers
+func validateHeaders(s *schema, headers map[string]any) []string {
+  var violations []string
+  for _, key := range s.Required {
+    if _, ok := headers[key]; !ok {
+      violations = append(violations, "missing required "+key)
+    }
+  }
+  for key, value := range headers {
+    property, ok := s.Properties[key]
+    if !ok {
+      violations = append(violations, "unknown header "+key)
+      continue
+    }
+    switch property.Type {
+    case "string":
+      str, ok := value.(string)
+      if !ok {
+        violations = append(violations, key+" is not a string")
+        continue
+      }
+      if property.MinLength != nil && len(str) < *property.MinLength {
+        violations = append(violations, key+" is too short")
+      }
+      if property.Pattern != "" && !regexp.MustCompile(property.Pattern).MatchString(str) {
+        violations = append(violations, key+" does not match pattern")
+      }
+      if property.Format == "uri" {
+        if parsed, err := url.Parse(str); err != nil || parsed.Scheme == "" {
+          violations = append(violations, key+" is not an uri")
+        }
+      }
+    case "integer":
+      number, ok := value.(int64)
+      if !ok {
+        violations = append(violations, key+" is not an integer")
+        continue
+      }
+      if property.Const != nil && float64(number) != *property.Const {
+        violations = append(violations, key+" is not a const")
+      }
+      if property.Minimum != nil && float64(number) < *property.Minimum {
+        violations = append(violations, key+" is below minimum")
+      }
+    case "boolean":
+      if _, ok := value.(bool); !ok {
+        violations = append(violations, key+" is not a boolean")
+      }
+    default:
+      violations = append(violations, key+" has unknown schema type "+property.Type)
+    }
+  }
+  return violations
+}
+
+func TestSchemaDescribesAllHeaders(t *testing.T) {
+  s := loadSchema(t)
+  if s.Type != "object" {
+    t.Errorf("schema type is '%s', want object", s.Type)
+  }
+  headers := []string{
+    HeaderMessageId, HeaderSchemaVersion, HeaderName, HeaderSection, HeaderContentType,
+    HeaderContentLength, HeaderContentRef, HeaderContentChecksum, HeaderOverwrite,
+    HeaderFrom, HeaderTimestamp, HeaderTraceId, HeaderCorrelationId,
+  }
+  for _, header := range headers {
+    if s.Properties[header] == nil {
+      t.Errorf("header '%s' is not described by schema", header)
+    }
+  }
+  if len(s.Properties) != len(headers) {
+    t.Errorf("schema has %d properties, want %d headers", len(s.Properties), len(headers))
+  }
+  if schemaVersion := s.Properties[HeaderSchemaVersion].Const; schemaVersion == nil || *schemaVersion != SchemaVersion {
+    t.Errorf("schema version const does not match SchemaVersion %d", SchemaVersion)
+  }
+}
+
+func TestEncodedHeadersMatchSchema(t *testing.T) {
+  s := loadSchema(t)
+
+  withRef := testMetaInfo()
+  withRef.ContentRef = "https://api.polygon.io/v1/reference/company-branding/AAPL/icon.png"
+  minimal := &domain.PutMessageMetaInfo{Name: "AAPL-icon", Section: "polygon_references"}
+
+  for name, metaInfo := range map[string]*domain.PutMessageMetaInfo{
+    "full":      testMetaInfo(),
+    "reference": withRef,
+    "minimal":   minimal,
+  } {
+    headers, err := EncodeHeaders(metaInfo)
+    if err != nil {
+      t.Fatalf("%s: cannot encode headers: %v", name, err)
+    }
+    if violations := validateHeaders(s, headers); len(violations) != 0 {
+      t.Errorf("%s: headers do not match schema: %v", name, violations)
+    }
+  }
+
+  // validator itself rejects broken headers
+  headers, _ := EncodeHeaders(testMetaInfo())
+  headers[HeaderContentChecksum] = "md5:abc"
+  headers[HeaderSchemaVersion] = int64(2)
+  delete(headers, HeaderFrom)
+  if violations := validateHeaders(s, headers); len(violations) != 3 {
+    t.Errorf("got violations %v of broken headers, want 3", violations)
+  }
+}
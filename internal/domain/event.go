@@ -0,0 +1,86 @@
+package domain
+
+import (
+  "encoding/json"
+  "time"
+)
+
+const (
+  EventTypeStockIngested = "stock.ingested"
+  EventTypeTickerUpdated = "ticker.updated"
+)
+
+// Event notification for downstream consumers about ingested data
+type Event struct {
+  EventId   string    `json:"event_id"`
+  Type      string    `json:"type"`
+  TickerId  string    `json:"ticker_id"`
+  Interval  string    `json:"interval"`
+  Timestamp time.Time `json:"timestamp"`
+  Payload   any       `json:"payload"`
+}
+
+// TickerUpdate summary of bars ingested for ticker in single fetch
+type TickerUpdate struct {
+  TickerId      string    `json:"ticker_id"`
+  Interval      string    `json:"interval"`
+  IngestedCount int       `json:"ingested_count"`
+  FirstBarAt    time.Time `json:"first_bar_at"`
+  LastBarAt     time.Time `json:"last_bar_at"`
+}
+
+// StockPayload payload of `stock.ingested` event. prices and volume are json numbers
+// with exact decimal digits, consumers parsing them as floats are not broken by strings
+type StockPayload struct {
+  StockId       string      `json:"stock_id"`
+  TickerId      string      `json:"ticker_id"`
+  OpenPrice     json.Number `json:"open_price"`
+  ClosePrice    json.Number `json:"close_price"`
+  HighestPrice  json.Number `json:"highest_price"`
+  LowestPrice   json.Number `json:"lowest_price"`
+  TradingVolume json.Number `json:"trading_volume"`
+  StockedAt     time.Time   `json:"stocked_time"`
+  CreatedAt     time.Time   `json:"created_at"`
+}
+
+// NewStockPayload create event payload of stock bar
+func NewStockPayload(stock *Stock) *StockPayload {
+  return &StockPayload{
+    StockId:       stock.StockId,
+    TickerId:      stock.TickerId,
+    OpenPrice:     json.Number(stock.OpenPrice.String()),
+    ClosePrice:    json.Number(stock.ClosePrice.String()),
+    HighestPrice:  json.Number(stock.HighestPrice.String()),
+    LowestPrice:   json.Number(stock.LowestPrice.String()),
+    TradingVolume: json.Number(stock.TradingVolume.String()),
+    StockedAt:     stock.StockedAt,
+    CreatedAt:     stock.CreatedAt,
+  }
+}
+
+// NewStockIngestedEvent event about new stock bar stored
+//   interval bar interval, e.g. `1day`
+//   stock stored stock bar
+func NewStockIngestedEvent(interval string, stock *Stock) *Event {
+  return &Event{
+    EventId:   NewMessageId(),
+    Type:      EventTypeStockIngested,
+    TickerId:  stock.TickerId,
+    Interval:  interval,
+    Timestamp: time.Now().UTC(),
+    Payload:   NewStockPayload(stock),
+  }
+}
+
+// NewTickerUpdatedEvent event about new bars stored for ticker
+//   update summary of ingested bars
+func NewTickerUpdatedEvent(update *TickerUpdate) *Event {
+  return &Event{
+    EventId:   NewMessageId(),
+    Type:      EventTypeTickerUpdated,
+    TickerId:  update.TickerId,
+    Interval:  update.Interval,
+    Timestamp: time.Now().UTC(),
+    Payload:   update,
+  }
+}
@@ -1,7 +1,11 @@
 package domain
 
 import (
-  "encoding/json"
+  "crypto/rand"
+  "crypto/sha256"
+  "encoding/hex"
+  "fmt"
+  "time"
 )
 
 type PutMessage struct {
@@ -9,24 +13,48 @@ type PutMessage struct {
   Content  []byte              `json:"content"`
 }
 
+// OutboxMessage message stored in outbox table in the same transaction as related data
+type OutboxMessage struct {
+  MessageId int64       `json:"message_id"`
+  Message   *PutMessage `json:"message"`
+  CreatedAt time.Time   `json:"created_at"`
+  SentAt    *time.Time  `json:"sent_at"`
+  Attempts  int         `json:"attempts"`
+}
+
+// PutMessageMetaInfo message meta info. if ContentRef is set, message has no content
+// and consumer must pull it by reference and verify with ContentChecksum (if set).
+// encoded to typed AMQP headers by `envelope` package
 type PutMessageMetaInfo struct {
-  Name          string `json:"name"`
-  Section       string `json:"section"`
-  ContentType   string `json:"content_type"`
-  ContentLength string `json:"content_length,omitempty"`
-  Overwrite     bool   `json:"overwrite,omitempty"`
-  From          string `json:"from"`
-  Timestamp     int64  `json:"timestamp"`
+  MessageId       string `json:"message_id"`
+  SchemaVersion   int    `json:"schema_version"`
+  Name            string `json:"name"`
+  Section         string `json:"section"`
+  ContentType     string `json:"content_type"`
+  ContentLength   int64  `json:"content_length,omitempty"`
+  ContentRef      string `json:"content_ref,omitempty"`
+  ContentChecksum string `json:"content_checksum,omitempty"`
+  Overwrite       bool   `json:"overwrite,omitempty"`
+  From            string `json:"from"`
+  Timestamp       int64  `json:"timestamp"`
+  TraceId         string `json:"trace_id,omitempty"`
+  CorrelationId   string `json:"correlation_id,omitempty"`
 }
 
-func (m *PutMessageMetaInfo) MarshalMap() (map[string]any, error) {
-  b, err := json.Marshal(m)
-  if err != nil {
-    return nil, err
-  }
-  mMap := map[string]any{}
-  if err := json.Unmarshal(b, &mMap); err != nil {
-    return nil, err
+const checksumPrefix = "sha256:"
+
+// ContentChecksum return checksum of content in `sha256:<hex>` format
+func ContentChecksum(content []byte) string {
+  sum := sha256.Sum256(content)
+  return fmt.Sprint(checksumPrefix, hex.EncodeToString(sum[:]))
+}
+
+// NewMessageId return random 128-bit message id in hex
+func NewMessageId() string {
+  id := make([]byte, 16)
+  if _, err := rand.Read(id); err != nil {
+    // crypto/rand never fails on supported platforms
+    panic(fmt.Sprintf("cannot read random bytes: %v", err))
   }
-  return mMap, nil
+  return hex.EncodeToString(id)
 }
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+  "time"
+
+  "github.com/shopspring/decimal"
+)
 
 type Ticker struct {
   TickerId          string    `json:"ticker_id"`
@@ -26,23 +30,85 @@ type TickerDetails struct {
   CreatedAt          time.Time `json:"created_at"`
 }
 
+// Stock bar of ticker. prices and volume are exact decimals, in json they are strings
 type Stock struct {
-  StockId       string    `json:"stock_id"`
-  TickerId      string    `json:"ticker_id"`
-  OpenPrice     float64   `json:"open_price"`
-  ClosePrice    float64   `json:"close_price"`
-  HighestPrice  float64   `json:"highest_price"`
-  LowestPrice   float64   `json:"lowest_price"`
-  TradingVolume float64   `json:"trading_volume"`
-  StockedAt     time.Time `json:"stocked_time"`
-  CreatedAt     time.Time `json:"created_at"`
+  StockId       string          `json:"stock_id"`
+  TickerId      string          `json:"ticker_id"`
+  OpenPrice     decimal.Decimal `json:"open_price"`
+  ClosePrice    decimal.Decimal `json:"close_price"`
+  HighestPrice  decimal.Decimal `json:"highest_price"`
+  LowestPrice   decimal.Decimal `json:"lowest_price"`
+  TradingVolume decimal.Decimal `json:"trading_volume"`
+  StockedAt     time.Time       `json:"stocked_time"`
+  CreatedAt     time.Time       `json:"created_at"`
+}
+
+// StockAnomaly bar which failed data quality validation. it is quarantined instead of
+// stored with stocks. reason lists all failed checks
+type StockAnomaly struct {
+  Stock      *Stock    `json:"stock"`
+  Reason     string    `json:"reason"`
+  DetectedAt time.Time `json:"detected_at"`
+}
+
+// BrandingHash last published content hash of ticker branding image
+type BrandingHash struct {
+  TickerId     string    `json:"ticker_id"`
+  BrandingType string    `json:"branding_type"`
+  ContentHash  string    `json:"content_hash"`
+  UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TickerSyncState watermark of ticker bars: timestamp of the last stored bar of interval
+type TickerSyncState struct {
+  TickerId  string    `json:"ticker_id"`
+  Interval  string    `json:"interval"`
+  LastBarAt time.Time `json:"last_bar_at"`
+  UpdatedAt time.Time `json:"updated_at"`
 }
 
+// FetcherState checkpoint of tickers fetching: cursor of tickers page and the last fully
+// processed ticker of this page. TickerReqUrl keeps page url without credentials.
+// MemberId is set if tickers are sharded, each instance keeps its own checkpoint
 type FetcherState struct {
   StateId             int       `json:"state_id"`
   TickerReqUrl        string    `json:"ticker_req_url"`
   TickerDetailsReqUrl string    `json:"ticker_details_req_url"`
   StockReqUrl         string    `json:"stock_req_url"`
+  Cursor              string    `json:"cursor"`
+  LastTickerId        string    `json:"last_ticker_id"`
+  MemberId            string    `json:"member_id"`
   CreatedAt           time.Time `json:"created_at"`
   Finished            bool      `json:"finished"`
 }
+
+// ShardState progress of tickers shard in current fetching cycle
+type ShardState struct {
+  FetcherId      string    `json:"fetcher_id"`
+  Shard          int       `json:"shard"`
+  OwnerId        string    `json:"owner_id"`
+  LastTickerId   string    `json:"last_ticker_id"`
+  ProcessedCount int       `json:"processed_count"`
+  CycleStartedAt time.Time `json:"cycle_started_at"`
+  UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// FetcherLease lease of fetcher held by leader instance. times are by database clock
+type FetcherLease struct {
+  FetcherId string    `json:"fetcher_id"`
+  HolderId  string    `json:"holder_id"`
+  ExpiresAt time.Time `json:"expires_at"`
+  UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobState persisted runs of scheduled job
+type JobState struct {
+  JobName       string     `json:"job_name"`
+  OwnerId       string     `json:"owner_id"`
+  LastRunAt     *time.Time `json:"last_run_at"`
+  LastSuccessAt *time.Time `json:"last_success_at"`
+  NextRunAt     time.Time  `json:"next_run_at"`
+  LastStatus    string     `json:"last_status"`
+  LastError     string     `json:"last_error"`
+  UpdatedAt     time.Time  `json:"updated_at"`
+}
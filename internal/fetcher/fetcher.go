@@ -1,7 +1,55 @@
 package fetcher
 
+import (
+  "errors"
+  "scientific-research/pkg/utils/config"
+  "time"
+)
+
+var (
+  // ErrNotRunning fetching is not running, e.g. instance is standby of leader election
+  ErrNotRunning = errors.New("fetching is not running")
+  // ErrJobRunning operation conflicts with running job
+  ErrJobRunning = errors.New("conflicting job is running")
+  // ErrBackfillQueueFull too many backfills are pending
+  ErrBackfillQueueFull = errors.New("backfill queue is full")
+)
+
 type Fetcher interface {
   ContinuouslyFetch()
   SaveFetcherState()
-  SetTickerId(tickerId string)
+  ApplyConfig(config config.Config) error
+  // SetWatchlist fetch only watchlist tickers, entries are ticker ids or patterns
+  SetWatchlist(entries ...string) error
+  // FetchOnce run single tickers fetching cycle. interrupted cycle is resumed
+  FetchOnce() error
+  // FetchTickerStocks fetch stocks of ticker for date range regardless of its watermark
+  FetchTickerStocks(tickerId string, from, to time.Time) error
+  Controller
+}
+
+// Controller control of running fetcher
+type Controller interface {
+  // Pause stop fetching before the next ticker until resumed
+  Pause()
+  Resume()
+  // Trigger run scheduled job immediately, all jobs if name is empty
+  Trigger(jobName string) error
+  // AddWatchlist add entries to watchlist of running fetcher
+  AddWatchlist(entries ...string) error
+  // RemoveWatchlist remove entries from watchlist of running fetcher
+  RemoveWatchlist(entries ...string) error
+  // ResetCursor start the next tickers cycle from the first page
+  ResetCursor() error
+  // Backfill queue fetching of ticker stocks for date range
+  Backfill(tickerId string, from, to time.Time) error
+  Status() *Status
+}
+
+// Status state of running fetcher
+type Status struct {
+  Paused           bool     `json:"paused"`
+  RunningJob       string   `json:"running_job"`
+  Watchlist        []string `json:"watchlist"`
+  PendingBackfills int      `json:"pending_backfills"`
 }
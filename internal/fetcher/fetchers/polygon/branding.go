@@ -1,21 +1,46 @@
 package polygon
 
 import (
+  "context"
   "fmt"
+  "mime"
+  "path"
   "scientific-research/internal/domain"
+  "scientific-research/internal/domain/envelope"
+  "scientific-research/internal/httpclient"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
   "scientific-research/pkg/utils/timeutils"
   "strings"
 )
 
-func (f *Fetcher) formMsgForBrandingImage(tickerId, imageURL, brandingType string) (*domain.PutMessage, error) {
-  const (
-    headerContentType   = "Content-Type"
-    headerContentLength = "Content-Length"
-    blobContentType     = "application/octet-stream"
-    sectionName         = "polygon_references"
-    nameDashSep         = "-"
-  )
-  imageResp, err := f.client.GetFullResp(imageURL)
+const (
+  headerContentType   = "Content-Type"
+  headerContentLength = "Content-Length"
+  blobContentType     = "application/octet-stream"
+  sectionName         = "polygon_references"
+  nameDashSep         = "-"
+)
+
+func (f *Fetcher) formMsgForBrandingImage(ctx context.Context, tickerId, imageURL, brandingType string) (*domain.PutMessage, error) {
+  metaInfo := &domain.PutMessageMetaInfo{
+    MessageId:     domain.NewMessageId(),
+    SchemaVersion: envelope.SchemaVersion,
+    Name:          fmt.Sprint(tickerId, nameDashSep, brandingType),
+    Section:       sectionName,
+    Overwrite:     false,
+    From:          FetcherName,
+    Timestamp:     timeutils.NowTimestampUTC(),
+    TraceId:       tracing.TraceId(ctx),
+    CorrelationId: logging.CorrelationId(ctx),
+  }
+  messageMode, maxMessageSize := f.getConfig().brandingSettings()
+
+  if messageMode == brandingModeReference {
+    return formReferenceMsgForBrandingImage(metaInfo, imageURL), nil
+  }
+
+  imageResp, err := f.client.GetFullRespContext(ctx, imageURL)
   if err != nil {
     return nil, fmt.Errorf("cannot get image response for ticker '%s': %v", tickerId, err)
   }
@@ -23,48 +48,126 @@ func (f *Fetcher) formMsgForBrandingImage(tickerId, imageURL, brandingType strin
   if !ok {
     contentType = blobContentType
   }
-  imageName := fmt.Sprint(tickerId, nameDashSep, brandingType)
-  contentLength := imageResp.Headers.GetOrDefault(headerContentLength)
+  content := imageResp.Content
+  checksum := domain.ContentChecksum(content)
+
+  metaInfo.ContentType = contentType
+  metaInfo.ContentLength = int64(len(content))
+  metaInfo.ContentChecksum = checksum
+
+  if len(content) <= maxMessageSize {
+    return &domain.PutMessage{
+      MetaInfo: metaInfo,
+      Content:  content,
+    }, nil
+  }
+  // large payload goes to object store, message carries reference and checksum
+  if f.objectStore == nil {
+    return nil, fmt.Errorf("image of ticker '%s' size %d exceeds message size limit %d and object store is not configured",
+      tickerId, len(content), maxMessageSize)
+  }
+  objectKey := path.Join(sectionName, strings.TrimPrefix(checksum, "sha256:"))
+
+  contentRef, err := f.objectStore.Put(objectKey, contentType, content)
+  if err != nil {
+    return nil, fmt.Errorf("cannot put image of ticker '%s' to object store: %v", tickerId, err)
+  }
+  metaInfo.ContentRef = contentRef
 
   return &domain.PutMessage{
-    MetaInfo: &domain.PutMessageMetaInfo{
-      Name:          imageName,
-      Section:       sectionName,
-      ContentType:   contentType,
-      ContentLength: contentLength,
-      Overwrite:     false,
-      From:          fetcherName,
-      Timestamp:     timeutils.NowTimestampUTC(),
-    },
-    Content: imageResp.Content,
+    MetaInfo: metaInfo,
   }, nil
 }
 
-func (f *Fetcher) sendMessagesToPutTickerBranding(tickerId string, branding *tickerDetailsBranding) error {
+// formReferenceMsgForBrandingImage form message with source image url without downloading image.
+// credentials are stripped from url, media service pulls image with its own Polygon credentials.
+// message has no checksum, content is not known to fetcher
+func formReferenceMsgForBrandingImage(metaInfo *domain.PutMessageMetaInfo, imageURL string) *domain.PutMessage {
+  contentType := mime.TypeByExtension(path.Ext(imageURL))
+  if contentType == "" {
+    contentType = blobContentType
+  }
+  metaInfo.ContentType = contentType
+  metaInfo.ContentRef = httpclient.StripCredentials(imageURL)
+
+  return &domain.PutMessage{
+    MetaInfo: metaInfo,
+  }
+}
+
+// formMsgsToPutTickerBranding form messages to put ticker branding images which changed
+// since the last publishing and their new hashes. messages are stored to outbox with
+// ticker details and published by outbox relay
+func (f *Fetcher) formMsgsToPutTickerBranding(
+  ctx context.Context,
+  tickerId string,
+  branding *tickerDetailsBranding,
+) ([]*domain.PutMessage, []*domain.BrandingHash, error) {
   const (
     brandingTypeIcon = "icon"
     brandingTypeLogo = "logo"
   )
   if tickerId == "" || branding == nil {
-    return nil
+    return nil, nil, nil
   }
-  iconURL := strings.TrimSpace(branding.IconUrl)
-
-  iconPutMsg, err := f.formMsgForBrandingImage(tickerId, iconURL, brandingTypeIcon)
-  if err != nil {
-    return err
+  brandingURLs := []struct {
+    brandingType string
+    imageURL     string
+  }{
+    {brandingTypeIcon, strings.TrimSpace(branding.IconUrl)},
+    {brandingTypeLogo, strings.TrimSpace(branding.LogoUrl)},
   }
-  if err = f.msQueue.SendMessage(iconPutMsg); err != nil {
-    return err
+  var (
+    messages []*domain.PutMessage
+    hashes   []*domain.BrandingHash
+  )
+  for _, brandingURL := range brandingURLs {
+    if brandingURL.imageURL == "" {
+      continue
+    }
+    putMsg, err := f.formMsgForBrandingImage(ctx, tickerId, brandingURL.imageURL, brandingURL.brandingType)
+    if err != nil {
+      return nil, nil, err
+    }
+    hash, changed, err := f.checkBrandingChanged(ctx, tickerId, brandingURL.brandingType, putMsg)
+    if err != nil {
+      return nil, nil, err
+    }
+    if !changed {
+      f.logger(ctx).Infof("%s of ticker '%s' not changed. message not sent", brandingURL.brandingType, tickerId)
+      continue
+    }
+    messages = append(messages, putMsg)
+    hashes = append(hashes, hash)
   }
-  logoURL := strings.TrimSpace(branding.LogoUrl)
+  return messages, hashes, nil
+}
 
-  logoPutMsg, err := f.formMsgForBrandingImage(tickerId, logoURL, brandingTypeLogo)
+// checkBrandingChanged compare hash of message content with the last published one.
+// in reference mode content is unknown, so hash of content reference is compared.
+// changed image replaces the stored one, so message of changed image has overwrite set
+func (f *Fetcher) checkBrandingChanged(
+  ctx context.Context,
+  tickerId string,
+  brandingType string,
+  putMsg *domain.PutMessage,
+) (*domain.BrandingHash, bool, error) {
+  contentHash := putMsg.MetaInfo.ContentChecksum
+  if contentHash == "" {
+    contentHash = domain.ContentChecksum([]byte(putMsg.MetaInfo.ContentRef))
+  }
+  lastHash, found, err := f.storage.WithContext(ctx).GetBrandingHash(tickerId, brandingType)
   if err != nil {
-    return err
+    return nil, false, fmt.Errorf("cannot get branding hash from storage: %v", err)
   }
-  if err = f.msQueue.SendMessage(logoPutMsg); err != nil {
-    return err
+  if found && lastHash.ContentHash == contentHash {
+    return nil, false, nil
   }
-  return nil
+  putMsg.MetaInfo.Overwrite = found
+  return &domain.BrandingHash{
+    TickerId:     tickerId,
+    BrandingType: brandingType,
+    ContentHash:  contentHash,
+    UpdatedAt:    timeutils.NotTimeUTC(),
+  }, true, nil
 }
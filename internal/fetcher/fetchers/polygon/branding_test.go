@@ -0,0 +1,43 @@
+package polygon
+
+import (
+  "context"
+  "scientific-research/pkg/utils/logging"
+  "testing"
+
+  "go.opentelemetry.io/otel/trace"
+)
+
+func TestBrandingMessageCarriesTraceAndCorrelationIds(t *testing.T) {
+  f := newTestFetcher(t, "http://localhost", newMemStorage())
+  f.config.BrandingConfig = &BrandingConfig{MessageMode: brandingModeReference}
+
+  traceId := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+  ctx := trace.ContextWithSpanContext(logging.WithCorrelationId(context.Background()),
+    trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: trace.SpanID{1}}))
+
+  message, err := f.formMsgForBrandingImage(ctx, "AAPL", "https://api.polygon.io/icon.png?apiKey=secret", "icon")
+  if err != nil {
+    t.Fatalf("cannot form branding message: %v", err)
+  }
+  metaInfo := message.MetaInfo
+  if metaInfo.TraceId != traceId.String() {
+    t.Errorf("trace id is '%s', want '%s'", metaInfo.TraceId, traceId)
+  }
+  if correlationId := logging.CorrelationId(ctx); correlationId == "" || metaInfo.CorrelationId != correlationId {
+    t.Errorf("correlation id is '%s', want '%s'", metaInfo.CorrelationId, correlationId)
+  }
+  if metaInfo.ContentRef != "https://api.polygon.io/icon.png" {
+    t.Errorf("content reference is '%s', want url without credentials", metaInfo.ContentRef)
+  }
+
+  // messages formed out of fetching cycle have no ids
+  message, err = f.formMsgForBrandingImage(context.Background(), "AAPL", "https://api.polygon.io/icon.png", "icon")
+  if err != nil {
+    t.Fatalf("cannot form branding message: %v", err)
+  }
+  if message.MetaInfo.TraceId != "" || message.MetaInfo.CorrelationId != "" {
+    t.Errorf("message out of cycle has trace id '%s' and correlation id '%s'",
+      message.MetaInfo.TraceId, message.MetaInfo.CorrelationId)
+  }
+}
@@ -0,0 +1,81 @@
+package polygon
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/calendar"
+  "scientific-research/internal/scheduler"
+  "strings"
+  "time"
+)
+
+// getCalendar return exchange calendar by name for scheduler. `weekdays` calendar
+// has sessions from monday to friday without holidays
+func (f *Fetcher) getCalendar(name string) (scheduler.Calendar, error) {
+  if strings.ToLower(name) == calendarWeekdays {
+    return scheduler.Weekdays, nil
+  }
+  return f.calendars.Get(name)
+}
+
+// stocksCalendar return calendar of exchange stocks are traded on
+func (f *Fetcher) stocksCalendar() *calendar.Calendar {
+  stocksCalendar, err := f.calendars.Get(f.getConfig().calendarExchange())
+  if err != nil {
+    // exchange validated on config parsing
+    log.Errorf("cannot get stocks calendar: %v. use %s", err, defaultCalendarExchange)
+    stocksCalendar, _ = f.calendars.Get(defaultCalendarExchange)
+  }
+  return stocksCalendar
+}
+
+// refreshCalendars add upcoming holidays and early closes from Polygon market status to
+// embedded calendars. refreshed at most once per refresh interval, errors are logged only.
+// concurrent callers wait for running refresh instead of requesting market status again
+func (f *Fetcher) refreshCalendars(ctx context.Context) {
+  if !f.getConfig().calendarRefreshEnabled() {
+    return
+  }
+  f.calendarsMu.Lock()
+  defer f.calendarsMu.Unlock()
+
+  if time.Since(f.calendarsRefreshedAt) < calendarRefreshInterval {
+    return
+  }
+  days, err := f.getUpcomingMarketDays(ctx)
+  if err != nil {
+    f.logger(ctx).Errorf("cannot get upcoming market days. use embedded calendars: %v", err)
+    return
+  }
+  f.calendarsRefreshedAt = time.Now()
+
+  applied := f.calendars.ApplyMarketDays(days)
+  f.logger(ctx).Infof("applied %d of %d upcoming market days to exchange calendars", applied, len(days))
+}
+
+func (f *Fetcher) getUpcomingMarketDays(ctx context.Context) ([]*calendar.MarketDay, error) {
+  reqURL := fmt.Sprint(f.apiBaseURL, marketStatusUpcomingApi)
+
+  resp, err := f.client.GetContext(ctx, reqURL)
+  if err != nil {
+    return nil, fmt.Errorf("cannot get response: %v", err)
+  }
+  var results []*marketDayResult
+  if err = f.client.ParseResponse(resp, &results); err != nil {
+    return nil, fmt.Errorf("cannot parse response: %v", err)
+  }
+
+  days := make([]*calendar.MarketDay, 0, len(results))
+  for _, result := range results {
+    if result == nil {
+      continue
+    }
+    days = append(days, &calendar.MarketDay{
+      Exchange: result.Exchange,
+      Date:     result.Date,
+      Status:   result.Status,
+      Close:    result.Close,
+    })
+  }
+  return days, nil
+}
@@ -2,20 +2,95 @@ package polygon
 
 import (
   "fmt"
+  "reflect"
+  "scientific-research/internal/admin"
+  "scientific-research/internal/calendar"
+  "scientific-research/internal/leader"
+  "scientific-research/internal/objectstore"
   "scientific-research/internal/queue/rabbitmq"
+  "scientific-research/internal/scheduler"
+  "scientific-research/internal/shard"
   "scientific-research/internal/storage/postgres"
+  "scientific-research/internal/tracing"
   "scientific-research/pkg/utils/config"
+  "scientific-research/pkg/utils/logging"
+  "scientific-research/pkg/utils/retries"
   "scientific-research/pkg/utils/validation"
+  "time"
 )
 
 type Config struct {
-  ModeTotalHours   int              `yaml:"total_mode_hours" required:"true"`
-  ModeCurrentHours int              `yaml:"current_mode_hours" required:"true"`
-  ApiToken         string           `yaml:"api_token" required:"true"`
-  StorageConfig    *postgres.Config `yaml:"storage_config" required:"true"`
-  QueueConfig      *rabbitmq.Config `yaml:"queue_config" required:"true"`
+  ModeTotalHours   int                `yaml:"total_mode_hours" env:"POLYGON_TOTAL_MODE_HOURS" required:"true" validate:"min=1"`
+  ModeCurrentHours int                `yaml:"current_mode_hours" env:"POLYGON_CURRENT_MODE_HOURS" required:"true" validate:"min=1"`
+  ApiToken         string             `yaml:"api_token" env:"POLYGON_API_TOKEN" required:"true"`
+  ApiBaseUrl       string             `yaml:"api_base_url" env:"POLYGON_API_BASE_URL" validate:"url"`
+  ApiAuthMode      string             `yaml:"api_auth_mode" env:"POLYGON_API_AUTH_MODE" validate:"oneof=query header"`
+  LimitsConfig     *LimitsConfig      `yaml:"limits_config"`
+  RetriesConfig    *RetriesConfig     `yaml:"retries_config"`
+  TickersFilter    TickersFilter      `yaml:"tickers_filter"`
+  BrandingConfig   *BrandingConfig    `yaml:"branding_config"`
+  StorageConfig    *postgres.Config   `yaml:"storage_config" required:"true"`
+  QueueConfig      *rabbitmq.Config   `yaml:"queue_config" required:"true"`
+  LeaderElection   *leader.Config     `yaml:"leader_election"`
+  Sharding         *shard.Config      `yaml:"sharding"`
+  Schedule         *scheduler.Config  `yaml:"schedule"`
+  CalendarConfig   *CalendarConfig    `yaml:"calendar_config"`
+  Watchlist        *WatchlistConfig   `yaml:"watchlist"`
+  DataQuality      *DataQualityConfig `yaml:"data_quality"`
+  Admin            *admin.Config      `yaml:"admin"`
+  Logging          *logging.Config    `yaml:"logging"`
+  Tracing          *tracing.Config    `yaml:"tracing"`
 }
 
+// LimitsConfig Polygon API requests limit. unset fields take default values
+type LimitsConfig struct {
+  RequestsCount int           `yaml:"requests_count" env:"POLYGON_REQUESTS_COUNT" validate:"min=1"`
+  RequestsPer   time.Duration `yaml:"requests_per" env:"POLYGON_REQUESTS_PER"`
+  Wait          time.Duration `yaml:"wait" env:"POLYGON_REQUESTS_WAIT"`
+  Deadline      time.Duration `yaml:"deadline" env:"POLYGON_REQUESTS_DEADLINE"`
+}
+
+// RetriesConfig retry policy for Polygon API requests. unset fields take default values
+type RetriesConfig struct {
+  RetryCount   int           `yaml:"retry_count" env:"POLYGON_RETRY_COUNT" validate:"min=1"`
+  WaitInterval time.Duration `yaml:"wait_interval" env:"POLYGON_RETRY_WAIT_INTERVAL"`
+}
+
+// BrandingConfig how branding images are sent to media service.
+// `content` mode (default) sends image bytes, payloads larger than max message size
+// are put to object store and sent as reference. `reference` mode sends source image url
+// without downloading, media service pulls image itself
+type BrandingConfig struct {
+  MessageMode    string              `yaml:"message_mode" env:"POLYGON_BRANDING_MESSAGE_MODE" validate:"oneof=content reference"`
+  MaxMessageSize int                 `yaml:"max_message_size" env:"POLYGON_BRANDING_MAX_MESSAGE_SIZE" validate:"min=1"`
+  ObjectStore    *objectstore.Config `yaml:"object_store"`
+}
+
+// CalendarConfig exchange calendar of fetched stocks. embedded calendars are refreshed
+// with upcoming holidays from Polygon market status unless refresh is disabled
+type CalendarConfig struct {
+  Exchange       string `yaml:"exchange" env:"POLYGON_CALENDAR_EXCHANGE"`
+  DisableRefresh bool   `yaml:"disable_refresh" env:"POLYGON_CALENDAR_DISABLE_REFRESH"`
+}
+
+// WatchlistConfig tickers fetched instead of all tickers. entries are ticker ids or
+// patterns of tickers request params, e.g. `AAPL` or `exchange=XNYS&type=ETF`.
+// file has entries separated by commas, spaces or new lines and is read on every run
+type WatchlistConfig struct {
+  Tickers []string `yaml:"tickers"`
+  File    string   `yaml:"file" env:"POLYGON_WATCHLIST_FILE"`
+}
+
+// DataQualityConfig validation of fetched bars. max price jump is max ratio of close change
+// between consecutive bars, e.g. 0.5 quarantines bar with close 50% above or below
+// previous close
+type DataQualityConfig struct {
+  MaxPriceJump float64 `yaml:"max_price_jump" env:"POLYGON_MAX_PRICE_JUMP" validate:"min=0"`
+}
+
+// TickersFilter additional query params for tickers request, e.g. `exchange: XNAS`
+type TickersFilter map[string]string
+
 func NewConfig() *Config {
   return &Config{}
 }
@@ -27,5 +102,157 @@ func (c *Config) Parse(configPath string) error {
   if err := config.ParseYamlConfig(configPath, c); err != nil {
     return fmt.Errorf("cannot parse yaml config: %v", err)
   }
-  return validation.CheckRequiredFields(c)
+  if err := validation.ValidateStruct(c); err != nil {
+    return err
+  }
+  if c.LeaderElectionEnabled() && c.ShardingEnabled() {
+    return fmt.Errorf("leader election and sharding cannot be enabled together")
+  }
+  if err := c.Schedule.Validate(); err != nil {
+    return fmt.Errorf("invalid schedule: %v", err)
+  }
+  calendars, err := calendar.Default()
+  if err != nil {
+    return fmt.Errorf("cannot load exchange calendars: %v", err)
+  }
+  if _, err = calendars.Get(c.calendarExchange()); err != nil {
+    return fmt.Errorf("invalid calendar config: %v", err)
+  }
+  if c.Watchlist != nil {
+    if _, err = parseWatchlistEntries(c.Watchlist.Tickers); err != nil {
+      return fmt.Errorf("invalid watchlist: %v", err)
+    }
+  }
+  return nil
+}
+
+func (c *Config) requestsLimit() (int, time.Duration, time.Duration, time.Duration) {
+  limits := &LimitsConfig{}
+  if c.LimitsConfig != nil {
+    limits = c.LimitsConfig
+  }
+  return valueOrDefault(limits.RequestsCount, polygonReqsLimit),
+    valueOrDefault(limits.RequestsPer, polygonReqPerDur),
+    valueOrDefault(limits.Wait, polygonWaitDur),
+    valueOrDefault(limits.Deadline, polygonDeadlineDur)
+}
+
+func (c *Config) brandingSettings() (string, int) {
+  branding := &BrandingConfig{}
+  if c.BrandingConfig != nil {
+    branding = c.BrandingConfig
+  }
+  return valueOrDefault(branding.MessageMode, brandingModeContent),
+    valueOrDefault(branding.MaxMessageSize, defaultMaxMessageSize)
+}
+
+func (c *Config) calendarExchange() string {
+  if c.CalendarConfig == nil {
+    return defaultCalendarExchange
+  }
+  return valueOrDefault(c.CalendarConfig.Exchange, defaultCalendarExchange)
+}
+
+func (c *Config) calendarRefreshEnabled() bool {
+  return c.CalendarConfig == nil || !c.CalendarConfig.DisableRefresh
+}
+
+func (c *Config) maxPriceJump() float64 {
+  if c.DataQuality == nil {
+    return defaultMaxPriceJump
+  }
+  return valueOrDefault(c.DataQuality.MaxPriceJump, defaultMaxPriceJump)
+}
+
+// watchlistEnabled return true if config has watchlist tickers or file
+func (c *Config) watchlistEnabled() bool {
+  return c.Watchlist != nil && (len(c.Watchlist.Tickers) != 0 || c.Watchlist.File != "")
+}
+
+// LeaderElectionEnabled return true if only lease holder must run fetching
+func (c *Config) LeaderElectionEnabled() bool {
+  return c.LeaderElection != nil && c.LeaderElection.Enabled
+}
+
+// ShardingEnabled return true if tickers are split between fetcher instances
+func (c *Config) ShardingEnabled() bool {
+  return c.Sharding != nil && c.Sharding.Enabled
+}
+
+func (c *Config) objectStoreConfig() *objectstore.Config {
+  if c.BrandingConfig == nil {
+    return nil
+  }
+  return c.BrandingConfig.ObjectStore
+}
+
+func (c *Config) retriesOption() *retries.Option {
+  if c.RetriesConfig == nil {
+    return nil
+  }
+  option := retries.NewDefaultOption()
+  option.RetryCount = valueOrDefault(c.RetriesConfig.RetryCount, option.RetryCount)
+  option.WaitInterval = valueOrDefault(c.RetriesConfig.WaitInterval, option.WaitInterval)
+  return option
+}
+
+// restartRequiredChanges return names of changed fields which cannot be applied to running fetcher
+func restartRequiredChanges(current, updated *Config) []string {
+  var changed []string
+
+  fields := []struct {
+    name             string
+    current, updated any
+  }{
+    {"api_token", current.ApiToken, updated.ApiToken},
+    {"api_base_url", current.ApiBaseUrl, updated.ApiBaseUrl},
+    {"api_auth_mode", current.ApiAuthMode, updated.ApiAuthMode},
+    {"storage_config", current.StorageConfig, updated.StorageConfig},
+    {"queue_config", current.QueueConfig, updated.QueueConfig},
+    {"leader_election", current.LeaderElection, updated.LeaderElection},
+    {"sharding", current.Sharding, updated.Sharding},
+    {"schedule", current.Schedule, updated.Schedule},
+    {"admin", current.Admin, updated.Admin},
+    {"tracing", current.Tracing, updated.Tracing},
+    {"branding_config.object_store", current.objectStoreConfig(), updated.objectStoreConfig()},
+  }
+  for _, field := range fields {
+    if !reflect.DeepEqual(field.current, field.updated) {
+      changed = append(changed, field.name)
+    }
+  }
+  return changed
+}
+
+// withRestartRequiredFrom return copy of config with fields which require restart taken from running config
+func (c *Config) withRestartRequiredFrom(running *Config) *Config {
+  merged := *c
+  merged.ApiToken = running.ApiToken
+  merged.ApiBaseUrl = running.ApiBaseUrl
+  merged.ApiAuthMode = running.ApiAuthMode
+  merged.StorageConfig = running.StorageConfig
+  merged.QueueConfig = running.QueueConfig
+  merged.LeaderElection = running.LeaderElection
+  merged.Sharding = running.Sharding
+  merged.Schedule = running.Schedule
+  merged.Admin = running.Admin
+  merged.Tracing = running.Tracing
+
+  if running.BrandingConfig != nil || merged.BrandingConfig != nil {
+    branding := &BrandingConfig{}
+    if merged.BrandingConfig != nil {
+      *branding = *merged.BrandingConfig
+    }
+    branding.ObjectStore = running.objectStoreConfig()
+    merged.BrandingConfig = branding
+  }
+  return &merged
+}
+
+func valueOrDefault[T comparable](value, defaultValue T) T {
+  var zero T
+  if value == zero {
+    return defaultValue
+  }
+  return value
 }
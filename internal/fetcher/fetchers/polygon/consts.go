@@ -2,18 +2,22 @@ package polygon
 
 import "time"
 
-const fetcherName = "polygon_fetcher"
+// FetcherName name of fetcher in messages and default fetcher id of leader election
+const FetcherName = "polygon_fetcher"
 
 const (
   fetcherModeTotal   = 0
   fetcherModeCurrent = 1
-  fetcherRetryCount  = 10
 )
 
+// fetcherModeNames names of fetcher modes in log entries
+var fetcherModeNames = map[int]string{
+  fetcherModeTotal:   "total",
+  fetcherModeCurrent: "current",
+}
+
 const (
-  recentlyFetchedSleepInterval  = 1 * time.Hour
-  encounteredErrorSleepInterval = 10 * time.Minute
-  recentlyThresholdInterval     = 24 * time.Hour
+  stateCheckpointInterval = 30 * time.Second
 )
 
 const (
@@ -24,18 +28,50 @@ const (
 )
 
 const (
-  respCursorKey = "cursor"
-  respStatusOK  = "OK"
+  respCursorKey  = "cursor"
+  respStatusOK   = "OK"
+  tickerQueryKey = "ticker"
+  limitQueryKey  = "limit"
 )
 
+// tickersPageLimit max tickers page size, every sharded replica pages through all tickers
+const tickersPageLimit = "1000"
+
 const (
-  basePrefixApi = "https://api.polygon.io"
+  basePrefixApi = "https://api.polygon.io" // default, can be overridden by config `api_base_url`
 
   tickersApi       = "/v3/reference/tickers"
   stocksApi        = "/v2/aggs/ticker/%s/range/%d/%s/%s/%s"
   tickerDetailsApi = "/v3/reference/tickers/%s"
 
+  marketStatusUpcomingApi = "/v1/marketstatus/upcoming"
+
   apiTokenKey = "apiKey"
 )
 
+const (
+  stocksMultiplier = 1
+  stocksTimespan   = "day"
+  StocksInterval   = "1day" // interval of fetched bars: multiplier and timespan, used in event routing keys and watermarks
+)
+
+const (
+  apiAuthModeQuery  = "query"  // token in `apiKey` query param
+  apiAuthModeHeader = "header" // token in `Authorization: Bearer` header
+)
+
+const (
+  brandingModeContent   = "content"
+  brandingModeReference = "reference"
+  defaultMaxMessageSize = 512 * 1024 // bytes
+)
+
+const (
+  calendarWeekdays        = "weekdays"
+  defaultCalendarExchange = "XNYS"
+  calendarRefreshInterval = 24 * time.Hour
+)
+
 const defaultStringValue = "N/A"
+
+const defaultMaxPriceJump = 0.5
@@ -0,0 +1,250 @@
+package polygon
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/fetcher"
+  "scientific-research/internal/scheduler"
+  "scientific-research/internal/tracing"
+  "strings"
+  "sync"
+  "time"
+)
+
+const (
+  backfillQueueSize = 100
+  // backfillJobName job name of backfills in log entries
+  backfillJobName = "backfill"
+)
+
+// control runtime control of running fetcher. guarded by mu, it is changed by admin
+// requests while jobs are running
+type control struct {
+  mu sync.Mutex
+  // resumed is closed on resume, nil if fetching is not paused
+  resumed     chan struct{}
+  scheduler   *scheduler.Scheduler
+  jobKinds    map[string]string
+  runningJob  string
+  runningKind string
+  backfills   chan *backfillRequest
+}
+
+type backfillRequest struct {
+  tickerId string
+  from     time.Time
+  to       time.Time
+}
+
+func newControl() *control {
+  return &control{
+    jobKinds:  map[string]string{},
+    backfills: make(chan *backfillRequest, backfillQueueSize),
+  }
+}
+
+func (f *Fetcher) Pause() {
+  f.control.mu.Lock()
+  defer f.control.mu.Unlock()
+
+  if f.control.resumed == nil {
+    f.control.resumed = make(chan struct{})
+    log.Info("fetching paused")
+  }
+}
+
+func (f *Fetcher) Resume() {
+  f.control.mu.Lock()
+  defer f.control.mu.Unlock()
+
+  if f.control.resumed != nil {
+    close(f.control.resumed)
+    f.control.resumed = nil
+    log.Info("fetching resumed")
+  }
+}
+
+// waitIfPaused block until fetching is resumed or fetcher context is done
+func (f *Fetcher) waitIfPaused() error {
+  f.control.mu.Lock()
+  resumed := f.control.resumed
+  f.control.mu.Unlock()
+
+  if resumed == nil {
+    return f.ctx.Err()
+  }
+  select {
+  case <-resumed:
+    return nil
+  case <-f.ctx.Done():
+    return f.ctx.Err()
+  }
+}
+
+func (f *Fetcher) Trigger(jobName string) error {
+  f.control.mu.Lock()
+  s := f.control.scheduler
+  f.control.mu.Unlock()
+
+  if s == nil {
+    return fetcher.ErrNotRunning
+  }
+  return s.Trigger(jobName)
+}
+
+// setScheduler make scheduler of running fetcher available for triggers
+//   jobKinds kinds of scheduled jobs by job name
+func (f *Fetcher) setScheduler(s *scheduler.Scheduler, jobKinds map[string]string) {
+  f.control.mu.Lock()
+  defer f.control.mu.Unlock()
+
+  f.control.scheduler = s
+  f.control.jobKinds = jobKinds
+}
+
+// controlled wrap job handler to wait while fetching is paused and track running job.
+// every run has own correlation id in log entries and own trace
+func (f *Fetcher) controlled(name, kind string, run jobRun) jobRun {
+  return func(ctx context.Context, info *scheduler.RunInfo) (err error) {
+    if err = f.waitIfPaused(); err != nil {
+      return err
+    }
+    ctx, span := f.startCycle(ctx, name)
+    defer func() { tracing.End(span, err) }()
+
+    f.control.mu.Lock()
+    f.control.runningJob, f.control.runningKind = name, kind
+    f.control.mu.Unlock()
+
+    defer func() {
+      f.control.mu.Lock()
+      f.control.runningJob, f.control.runningKind = "", ""
+      f.control.mu.Unlock()
+    }()
+    return run(ctx, info)
+  }
+}
+
+// AddWatchlist add entries to watchlist. runtime watchlist is not persisted, it is lost
+// on restart
+func (f *Fetcher) AddWatchlist(entries ...string) error {
+  if err := f.checkWatchlistScheduled(); err != nil {
+    return err
+  }
+  if _, err := parseWatchlistEntries(entries); err != nil {
+    return err
+  }
+  f.watchlist.add(entries)
+  return nil
+}
+
+// RemoveWatchlist remove entries of runtime watchlist, config and watchlist file until restart
+func (f *Fetcher) RemoveWatchlist(entries ...string) error {
+  if err := f.checkWatchlistScheduled(); err != nil {
+    return err
+  }
+  f.watchlist.remove(entries)
+  return nil
+}
+
+// checkWatchlistScheduled return error if running fetcher has no watchlist job
+func (f *Fetcher) checkWatchlistScheduled() error {
+  f.control.mu.Lock()
+  defer f.control.mu.Unlock()
+
+  if f.control.scheduler == nil {
+    return fetcher.ErrNotRunning
+  }
+  for _, kind := range f.control.jobKinds {
+    if kind == jobKindWatchlist {
+      return nil
+    }
+  }
+  return fmt.Errorf("no %s job is scheduled", jobKindWatchlist)
+}
+
+// ResetCursor drop progress of interrupted tickers cycle, the next cycle starts from
+// the first page. it is rejected while tickers job is running. control is locked across
+// the check and the reset, so tickers job cannot start in between
+func (f *Fetcher) ResetCursor() error {
+  f.control.mu.Lock()
+  if f.control.runningKind == jobKindTickers {
+    f.control.mu.Unlock()
+    return fmt.Errorf("%w: cannot reset cursor", fetcher.ErrJobRunning)
+  }
+  f.state.ResetCursor()
+  f.control.mu.Unlock()
+  log.Info("tickers cursor reset")
+
+  if err := f.saveFetcherState(); err != nil {
+    return fmt.Errorf("cannot save fetcher state: %v", err)
+  }
+  return nil
+}
+
+// Backfill queue fetching of ticker stocks, backfills are run one by one concurrently with jobs
+func (f *Fetcher) Backfill(tickerId string, from, to time.Time) error {
+  tickerId = strings.ToUpper(strings.TrimSpace(tickerId))
+  if tickerId == "" {
+    return fmt.Errorf("ticker id is empty")
+  }
+  if to.Before(from) {
+    return fmt.Errorf("range end %s is before its start %s", to.Format(dateLayout), from.Format(dateLayout))
+  }
+  f.control.mu.Lock()
+  running := f.control.scheduler != nil
+  f.control.mu.Unlock()
+
+  if !running {
+    return fetcher.ErrNotRunning
+  }
+  select {
+  case f.control.backfills <- &backfillRequest{tickerId: tickerId, from: from, to: to}:
+    log.Infof("backfill of ticker %s from %s to %s queued", tickerId, from.Format(dateLayout), to.Format(dateLayout))
+    return nil
+  default:
+    return fetcher.ErrBackfillQueueFull
+  }
+}
+
+// runBackfills run queued backfills until fetcher context is done
+func (f *Fetcher) runBackfills() {
+  for {
+    select {
+    case <-f.ctx.Done():
+      return
+    case req := <-f.control.backfills:
+      if err := f.waitIfPaused(); err != nil {
+        return
+      }
+      ctx, span := f.startCycle(f.ctx, backfillJobName)
+      err := f.fetchTickerStocks(ctx, req.tickerId, req.from, req.to)
+      tracing.End(span, err)
+      if err != nil {
+        if f.ctx.Err() != nil {
+          return
+        }
+        f.logger(ctx).Errorf("cannot backfill ticker %s: %v", req.tickerId, err)
+        continue
+      }
+      f.logger(ctx).Infof("backfill of ticker %s finished", req.tickerId)
+    }
+  }
+}
+
+func (f *Fetcher) Status() *fetcher.Status {
+  f.control.mu.Lock()
+  status := &fetcher.Status{
+    Paused:           f.control.resumed != nil,
+    RunningJob:       f.control.runningJob,
+    PendingBackfills: len(f.control.backfills),
+  }
+  f.control.mu.Unlock()
+
+  watchlist, err := f.getWatchlist()
+  if err != nil {
+    log.Errorf("cannot get watchlist: %v", err)
+  }
+  status.Watchlist = watchlist
+  return status
+}
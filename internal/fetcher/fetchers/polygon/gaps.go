@@ -0,0 +1,61 @@
+package polygon
+
+import (
+  "context"
+  "scientific-research/internal/calendar"
+  "strings"
+  "time"
+)
+
+const (
+  // maxLoggedGaps count of missing days listed in gap warning
+  maxLoggedGaps = 5
+  dateLayout    = "2006-01-02"
+)
+
+// detectGaps log closed trading sessions of requested range without bars. sessions before
+// the first bar are skipped, ticker may be listed in the middle of range
+//   tickerId ticker id
+//   sessions requested trading sessions
+//   results bars of aggregates response
+func (f *Fetcher) detectGaps(ctx context.Context, tickerId string, sessions []*calendar.Session, results []*stockResult) {
+  if len(sessions) == 0 || len(results) == 0 {
+    return
+  }
+  location := sessions[0].Date.Location()
+
+  barDays := map[string]bool{}
+  var firstBarDay time.Time
+  for _, result := range results {
+    if result == nil {
+      continue
+    }
+    barAt := time.UnixMilli(result.Timestamp).In(location)
+    barDay := time.Date(barAt.Year(), barAt.Month(), barAt.Day(), 0, 0, 0, 0, location)
+    barDays[barDay.Format(dateLayout)] = true
+
+    if firstBarDay.IsZero() || barDay.Before(firstBarDay) {
+      firstBarDay = barDay
+    }
+  }
+  now := f.now()
+
+  var gaps []string
+  for _, session := range sessions {
+    if session.Date.Before(firstBarDay) || session.Close.After(now) {
+      continue
+    }
+    if day := session.Date.Format(dateLayout); !barDays[day] {
+      gaps = append(gaps, day)
+    }
+  }
+  if len(gaps) == 0 {
+    return
+  }
+  logged := gaps
+  if len(logged) > maxLoggedGaps {
+    logged = logged[:maxLoggedGaps]
+  }
+  f.logger(ctx).Warnf("ticker '%s' has no bars for %d trading sessions: %s",
+    tickerId, len(gaps), strings.Join(logged, ", "))
+}
@@ -0,0 +1,87 @@
+package polygon
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/scheduler"
+  "scientific-research/internal/tracing"
+  "time"
+)
+
+// job names of one-off fetching in log entries
+const (
+  fetchOnceJobName   = "fetch_once"
+  fetchTickerJobName = "fetch_ticker"
+)
+
+// FetchOnce run single tickers fetching cycle and relay pending outbox messages.
+// interrupted cycle is resumed from the saved state. the total mode window is fetched
+// if no cycle has finished yet
+func (f *Fetcher) FetchOnce() error {
+  if err := f.loadFetcherState(); err != nil {
+    return fmt.Errorf("cannot load fetcher state: %v", err)
+  }
+  info := &scheduler.RunInfo{}
+
+  f.state.mu.Lock()
+  if f.state.finished {
+    info.LastSuccessAt = f.state.updatedAt
+  }
+  f.state.mu.Unlock()
+
+  ctx, span := f.startCycle(f.ctx, fetchOnceJobName)
+  err := f.runTickersJob(ctx, info)
+  tracing.End(span, err)
+  if err != nil {
+    return err
+  }
+  f.relayPending()
+  return nil
+}
+
+// FetchTickerStocks fetch stocks of ticker for trading sessions from day of `from` to
+// day of `to` inclusive. only dates of range are used, they are days of exchange calendar.
+// ticker watermark is moved forward only
+func (f *Fetcher) FetchTickerStocks(tickerId string, from, to time.Time) error {
+  ctx, span := f.startCycle(f.ctx, fetchTickerJobName)
+  err := f.fetchTickerStocks(ctx, tickerId, from, to)
+  tracing.End(span, err)
+
+  return err
+}
+
+func (f *Fetcher) fetchTickerStocks(ctx context.Context, tickerId string, from, to time.Time) error {
+  if tickerId == "" {
+    return fmt.Errorf("ticker id is empty")
+  }
+  if to.Before(from) {
+    return fmt.Errorf("range end %s is before its start %s", to.Format(dateLayout), from.Format(dateLayout))
+  }
+  f.refreshCalendars(ctx)
+
+  stocksCalendar := f.stocksCalendar()
+  sessions := closedSessions(stocksCalendar.Sessions(
+    exchangeDate(from, stocksCalendar.Location()),
+    exchangeDate(to, stocksCalendar.Location()),
+  ), f.now())
+  if len(sessions) == 0 {
+    f.logger(ctx).Warnf("no closed trading sessions from %s to %s", from.Format(dateLayout), to.Format(dateLayout))
+    return nil
+  }
+  return f.fetchStocksOfSessions(ctx, tickerId, sessions)
+}
+
+// relayPending publish outbox messages of one-off run, running service relays them otherwise
+func (f *Fetcher) relayPending() {
+  sent, err := f.relay.RelayPending()
+  if err != nil {
+    log.Errorf("cannot relay outbox messages. they are relayed by running fetcher: %v", err)
+    return
+  }
+  log.Infof("relayed %d outbox messages", sent)
+}
+
+// exchangeDate return midnight of date of t in exchange location
+func exchangeDate(t time.Time, location *time.Location) time.Time {
+  return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
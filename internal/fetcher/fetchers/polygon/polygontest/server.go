@@ -0,0 +1,454 @@
+package polygontest
+
+import (
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+const (
+  tickersPath        = "/v3/reference/tickers"
+  aggsPath           = "/v2/aggs/ticker/"
+  brandingPath       = "/v1/reference/company-branding/"
+  marketUpcomingPath = "/v1/marketstatus/upcoming"
+
+  cursorKey        = "cursor"
+  limitKey         = "limit"
+  apiTokenKey      = "apiKey"
+  bearerAuthPrefix = "Bearer "
+  statusOK         = "OK"
+
+  defaultPageSize = 2
+  dateLayout      = "2006-01-02"
+)
+
+// Ticker fixture for tickers and ticker details responses
+type Ticker struct {
+  Ticker         string
+  Name           string
+  Market         string
+  Locale         string
+  Type           string
+  Exchange       string
+  Cik            string
+  CurrencyName   string
+  Description    string
+  HomepageUrl    string
+  PhoneNumber    string
+  TotalEmployees int
+  City           string
+  Address        string
+  PostalCode     string
+  State          string
+  LastUpdatedUtc time.Time
+}
+
+// Bar fixture for aggregates response
+type Bar struct {
+  Open      float64
+  Close     float64
+  High      float64
+  Low       float64
+  Volume    float64
+  Timestamp time.Time
+}
+
+// MarketDay fixture for upcoming market status response
+type MarketDay struct {
+  Exchange string
+  Name     string
+  Date     string
+  // Status `closed` or `early-close`
+  Status string
+  Open   time.Time
+  Close  time.Time
+}
+
+type failure struct {
+  count      int
+  statusCode int
+}
+
+// Server fake Polygon API serving tickers pages with `next_url` cursors,
+// ticker details, aggregates and branding images
+type Server struct {
+  *httptest.Server
+
+  mu       sync.Mutex
+  pageSize int
+  apiToken string
+  tickers  []*Ticker
+  bars     map[string][]*Bar
+  images   map[string][]byte
+  upcoming []*MarketDay
+  failures map[string]*failure
+  requests []string
+}
+
+type Option func(s *Server)
+
+// WithPageSize set max count of tickers in single tickers page. request `limit` below it is honoured
+func WithPageSize(pageSize int) Option {
+  return func(s *Server) {
+    if pageSize <= 0 {
+      return
+    }
+    s.pageSize = pageSize
+  }
+}
+
+// WithApiToken require `apiKey` query param or bearer token with specified value. unauthorized requests get 401
+func WithApiToken(apiToken string) Option {
+  return func(s *Server) {
+    s.apiToken = apiToken
+  }
+}
+
+// NewServer start fake Polygon server. caller must call Close
+func NewServer(options ...Option) *Server {
+  s := &Server{
+    pageSize: defaultPageSize,
+    bars:     map[string][]*Bar{},
+    images:   map[string][]byte{},
+    failures: map[string]*failure{},
+  }
+  for _, opt := range options {
+    opt(s)
+  }
+  s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+  return s
+}
+
+// AddTicker add ticker with branding images (icon and logo) to the server
+func (s *Server) AddTicker(ticker *Ticker) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.tickers = append(s.tickers, ticker)
+  sort.Slice(s.tickers, func(i, j int) bool {
+    return s.tickers[i].Ticker < s.tickers[j].Ticker
+  })
+  s.images[brandingImagePath(ticker.Ticker, "icon")] = []byte(fmt.Sprint(ticker.Ticker, "-icon"))
+  s.images[brandingImagePath(ticker.Ticker, "logo")] = []byte(fmt.Sprint(ticker.Ticker, "-logo"))
+}
+
+// AddBars add aggregate bars for ticker
+func (s *Server) AddBars(tickerId string, bars ...*Bar) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.bars[tickerId] = append(s.bars[tickerId], bars...)
+  sort.Slice(s.bars[tickerId], func(i, j int) bool {
+    return s.bars[tickerId][i].Timestamp.Before(s.bars[tickerId][j].Timestamp)
+  })
+}
+
+// AddMarketDays add upcoming holidays and early closes
+func (s *Server) AddMarketDays(days ...*MarketDay) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.upcoming = append(s.upcoming, days...)
+}
+
+// FailNext respond with status code to the next count requests which path starts with prefix
+func (s *Server) FailNext(pathPrefix string, count, statusCode int) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.failures[pathPrefix] = &failure{
+    count:      count,
+    statusCode: statusCode,
+  }
+}
+
+// Requests return served request URIs without api token in order of arrival
+func (s *Server) Requests() []string {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  requests := make([]string, len(s.requests))
+  copy(requests, s.requests)
+  return requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+  query := r.URL.Query()
+  token := query.Get(apiTokenKey)
+  query.Del(apiTokenKey)
+
+  if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, bearerAuthPrefix) {
+    token = strings.TrimPrefix(bearer, bearerAuthPrefix)
+  }
+
+  reqURI := r.URL.Path
+  if encoded := query.Encode(); encoded != "" {
+    reqURI = fmt.Sprint(reqURI, "?", encoded)
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.requests = append(s.requests, reqURI)
+
+  if s.apiToken != "" && token != s.apiToken {
+    writeError(w, http.StatusUnauthorized)
+    return
+  }
+  if statusCode, failed := s.takeFailure(r.URL.Path); failed {
+    writeError(w, statusCode)
+    return
+  }
+  path := r.URL.Path
+
+  switch {
+  case path == tickersPath:
+    s.handleTickers(w, r)
+  case strings.HasPrefix(path, tickersPath+"/"):
+    s.handleTickerDetails(w, strings.TrimPrefix(path, tickersPath+"/"))
+  case strings.HasPrefix(path, aggsPath):
+    s.handleAggs(w, strings.TrimPrefix(path, aggsPath))
+  case strings.HasPrefix(path, brandingPath):
+    s.handleImage(w, path)
+  case path == marketUpcomingPath:
+    s.handleMarketUpcoming(w)
+  default:
+    writeError(w, http.StatusNotFound)
+  }
+}
+
+func (s *Server) takeFailure(path string) (int, bool) {
+  for prefix, f := range s.failures {
+    if !strings.HasPrefix(path, prefix) || f.count <= 0 {
+      continue
+    }
+    f.count--
+    return f.statusCode, true
+  }
+  return 0, false
+}
+
+func (s *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
+  query := r.URL.Query()
+  offset, ok := queryInt(query, cursorKey, 0)
+  if !ok {
+    writeError(w, http.StatusBadRequest)
+    return
+  }
+  pageSize, ok := queryInt(query, limitKey, s.pageSize)
+  if !ok || pageSize == 0 {
+    writeError(w, http.StatusBadRequest)
+    return
+  }
+  if pageSize > s.pageSize {
+    pageSize = s.pageSize
+  }
+  tickers := filterTickers(s.tickers, query)
+
+  if offset > len(tickers) {
+    offset = len(tickers)
+  }
+  end := offset + pageSize
+  if end > len(tickers) {
+    end = len(tickers)
+  }
+
+  results := make([]map[string]any, 0, end-offset)
+  for _, ticker := range tickers[offset:end] {
+    results = append(results, map[string]any{
+      "ticker":           ticker.Ticker,
+      "name":             ticker.Name,
+      "market":           ticker.Market,
+      "locale":           ticker.Locale,
+      "type":             ticker.Type,
+      "primary_exchange": ticker.Exchange,
+      "cik":              ticker.Cik,
+      "active":           true,
+      "currency_name":    ticker.CurrencyName,
+      "last_updated_utc": ticker.LastUpdatedUtc,
+    })
+  }
+  resp := map[string]any{
+    "results": results,
+    "status":  statusOK,
+    "count":   len(results),
+  }
+  // next page keeps filters and limit of request, api token is not a part of next url
+  if end < len(tickers) {
+    query.Del(apiTokenKey)
+    query.Set(cursorKey, strconv.Itoa(end))
+    resp["next_url"] = fmt.Sprint(s.URL, tickersPath, "?", query.Encode())
+  }
+  writeJSON(w, resp)
+}
+
+// queryInt return non-negative int query param or defaultValue if param is absent
+func queryInt(query url.Values, key string, defaultValue int) (int, bool) {
+  value := query.Get(key)
+  if value == "" {
+    return defaultValue, true
+  }
+  parsed, err := strconv.Atoi(value)
+  if err != nil || parsed < 0 {
+    return 0, false
+  }
+  return parsed, true
+}
+
+// filterTickers return tickers matching `ticker`, `exchange`, `type` and `market` params
+func filterTickers(tickers []*Ticker, query url.Values) []*Ticker {
+  var filtered []*Ticker
+  for _, ticker := range tickers {
+    fields := map[string]string{
+      "ticker":   ticker.Ticker,
+      "exchange": ticker.Exchange,
+      "type":     ticker.Type,
+      "market":   ticker.Market,
+    }
+    matches := true
+    for key, value := range fields {
+      if expected := query.Get(key); expected != "" && expected != value {
+        matches = false
+      }
+    }
+    if matches {
+      filtered = append(filtered, ticker)
+    }
+  }
+  return filtered
+}
+
+func (s *Server) handleTickerDetails(w http.ResponseWriter, tickerId string) {
+  for _, ticker := range s.tickers {
+    if ticker.Ticker != tickerId {
+      continue
+    }
+    writeJSON(w, map[string]any{
+      "status": statusOK,
+      "results": map[string]any{
+        "active":          true,
+        "ticker":          ticker.Ticker,
+        "name":            ticker.Name,
+        "cik":             ticker.Cik,
+        "currency_name":   ticker.CurrencyName,
+        "description":     ticker.Description,
+        "homepage_url":    ticker.HomepageUrl,
+        "phone_number":    ticker.PhoneNumber,
+        "total_employees": ticker.TotalEmployees,
+        "address": map[string]any{
+          "address1":    ticker.Address,
+          "city":        ticker.City,
+          "postal_code": ticker.PostalCode,
+          "state":       ticker.State,
+        },
+        "branding": map[string]any{
+          "icon_url": fmt.Sprint(s.URL, brandingImagePath(ticker.Ticker, "icon")),
+          "logo_url": fmt.Sprint(s.URL, brandingImagePath(ticker.Ticker, "logo")),
+        },
+      },
+    })
+    return
+  }
+  writeError(w, http.StatusNotFound)
+}
+
+// handleAggs serve `{ticker}/range/{multiplier}/{timespan}/{from}/{to}`
+func (s *Server) handleAggs(w http.ResponseWriter, rangePath string) {
+  const rangeParts = 6
+
+  parts := strings.Split(rangePath, "/")
+  if len(parts) != rangeParts {
+    writeError(w, http.StatusBadRequest)
+    return
+  }
+  tickerId := parts[0]
+
+  from, err := time.Parse(dateLayout, parts[4])
+  if err != nil {
+    writeError(w, http.StatusBadRequest)
+    return
+  }
+  to, err := time.Parse(dateLayout, parts[5])
+  if err != nil {
+    writeError(w, http.StatusBadRequest)
+    return
+  }
+  to = to.Add(24 * time.Hour)
+
+  var results []map[string]any
+  for _, bar := range s.bars[tickerId] {
+    if bar.Timestamp.Before(from) || !bar.Timestamp.Before(to) {
+      continue
+    }
+    results = append(results, map[string]any{
+      "o": bar.Open,
+      "c": bar.Close,
+      "h": bar.High,
+      "l": bar.Low,
+      "v": bar.Volume,
+      "t": bar.Timestamp.UnixMilli(),
+    })
+  }
+  writeJSON(w, map[string]any{
+    "ticker":       tickerId,
+    "adjusted":     true,
+    "queryCount":   len(results),
+    "resultsCount": len(results),
+    "status":       statusOK,
+    "results":      results,
+  })
+}
+
+func (s *Server) handleMarketUpcoming(w http.ResponseWriter) {
+  results := make([]map[string]any, 0, len(s.upcoming))
+  for _, day := range s.upcoming {
+    result := map[string]any{
+      "exchange": day.Exchange,
+      "name":     day.Name,
+      "date":     day.Date,
+      "status":   day.Status,
+    }
+    if !day.Open.IsZero() {
+      result["open"] = day.Open
+      result["close"] = day.Close
+    }
+    results = append(results, result)
+  }
+  writeJSON(w, results)
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, path string) {
+  image, ok := s.images[path]
+  if !ok {
+    writeError(w, http.StatusNotFound)
+    return
+  }
+  w.Header().Set("Content-Type", "image/svg+xml")
+  w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+  _, _ = w.Write(image)
+}
+
+func brandingImagePath(tickerId, brandingType string) string {
+  return fmt.Sprint(brandingPath, tickerId, "/images/", brandingType, ".svg")
+}
+
+func writeJSON(w http.ResponseWriter, resp any) {
+  w.Header().Set("Content-Type", "application/json")
+  _ = json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w http.ResponseWriter, statusCode int) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(statusCode)
+  _ = json.NewEncoder(w).Encode(map[string]any{
+    "status": "ERROR",
+    "error":  http.StatusText(statusCode),
+  })
+}
@@ -0,0 +1,102 @@
+package polygontest
+
+import (
+  "encoding/json"
+  "net/http"
+  "net/url"
+  "testing"
+)
+
+type tickersPage struct {
+  Results []struct {
+    Ticker string `json:"ticker"`
+  } `json:"results"`
+  NextUrl string `json:"next_url"`
+}
+
+func getTickersPage(t *testing.T, reqURL string) *tickersPage {
+  t.Helper()
+
+  resp, err := http.Get(reqURL)
+  if err != nil {
+    t.Fatalf("cannot request tickers: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    t.Fatalf("tickers response status is %d", resp.StatusCode)
+  }
+  page := &tickersPage{}
+  if err = json.NewDecoder(resp.Body).Decode(page); err != nil {
+    t.Fatalf("cannot decode tickers page: %v", err)
+  }
+  return page
+}
+
+func TestTickersNextUrlKeepsFilterAndLimit(t *testing.T) {
+  srv := NewServer(WithPageSize(10))
+  defer srv.Close()
+
+  for _, ticker := range []*Ticker{
+    {Ticker: "AAA", Type: "CS"},
+    {Ticker: "BBB", Type: "ETF"},
+    {Ticker: "CCC", Type: "CS"},
+    {Ticker: "DDD", Type: "ETF"},
+    {Ticker: "EEE", Type: "CS"},
+  } {
+    srv.AddTicker(ticker)
+  }
+
+  var tickerIds []string
+  reqURL := srv.URL + tickersPath + "?type=CS&limit=2&apiKey=secret"
+  for pages := 0; reqURL != ""; pages++ {
+    if pages == 3 {
+      t.Fatalf("tickers pages are not finished")
+    }
+    page := getTickersPage(t, reqURL)
+    if len(page.Results) > 2 {
+      t.Errorf("got %d tickers in page, want limit 2", len(page.Results))
+    }
+    for _, result := range page.Results {
+      tickerIds = append(tickerIds, result.Ticker)
+    }
+    if reqURL = page.NextUrl; reqURL == "" {
+      break
+    }
+    parsed, err := url.Parse(reqURL)
+    if err != nil {
+      t.Fatalf("cannot parse next url: %v", err)
+    }
+    query := parsed.Query()
+    if query.Get("type") != "CS" || query.Get(limitKey) != "2" || query.Get(cursorKey) == "" {
+      t.Errorf("next url %s lost filter, limit or cursor", reqURL)
+    }
+    if query.Has(apiTokenKey) {
+      t.Errorf("next url %s keeps api token", reqURL)
+    }
+  }
+
+  want := []string{"AAA", "CCC", "EEE"}
+  if len(tickerIds) != len(want) {
+    t.Fatalf("got tickers %v, want %v", tickerIds, want)
+  }
+  for idx := range want {
+    if tickerIds[idx] != want[idx] {
+      t.Errorf("got tickers %v, want %v", tickerIds, want)
+      break
+    }
+  }
+}
+
+func TestTickersPageSizeIsMaxLimit(t *testing.T) {
+  srv := NewServer()
+  defer srv.Close()
+
+  for _, tickerId := range []string{"AAA", "BBB", "CCC"} {
+    srv.AddTicker(&Ticker{Ticker: tickerId})
+  }
+  page := getTickersPage(t, srv.URL+tickersPath+"?limit=1000")
+  if len(page.Results) != defaultPageSize || page.NextUrl == "" {
+    t.Errorf("got %d tickers and next url '%s', want page of %d tickers", len(page.Results), page.NextUrl, defaultPageSize)
+  }
+}
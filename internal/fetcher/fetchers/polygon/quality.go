@@ -0,0 +1,135 @@
+package polygon
+
+import (
+  "fmt"
+  "scientific-research/internal/calendar"
+  "scientific-research/internal/domain"
+  "scientific-research/pkg/utils/timeutils"
+  "strings"
+  "time"
+
+  "github.com/shopspring/decimal"
+)
+
+const anomalyReasonSeparator = "; "
+
+var percents = decimal.NewFromInt(100)
+
+// stocksValidator data quality checks of bars of single aggregates response.
+// bars are checked in response order
+type stocksValidator struct {
+  // from, to requested range, `to` is excluded
+  from         time.Time
+  to           time.Time
+  maxPriceJump decimal.Decimal
+  // lastAt timestamp of the latest checked bar, valid or not
+  lastAt time.Time
+  // prev reference of price jumps: the previous bar of range with ordered timestamp,
+  // it is the last stored bar before range for the first bar
+  prev *domain.Stock
+}
+
+// stocksValidation bars of response split by validation
+type stocksValidation struct {
+  valid     []*domain.Stock
+  anomalies []*domain.StockAnomaly
+  // lastBarAt timestamp of the latest bar of requested range, valid or quarantined
+  lastBarAt time.Time
+}
+
+// validateStocks split bars into valid bars and quarantined anomalies. range of bars is
+// from the first session day to the end of the last session day
+//   maxPriceJump max ratio of close change between consecutive bars
+//   lastStored the last stored bar before range, nil if ticker has no stored bars
+func validateStocks(
+  stocks []*domain.Stock,
+  sessions []*calendar.Session,
+  maxPriceJump float64,
+  lastStored *domain.Stock,
+) *stocksValidation {
+  validator := &stocksValidator{
+    from:         sessions[0].Date,
+    to:           sessions[len(sessions)-1].Date.AddDate(0, 0, 1),
+    maxPriceJump: decimal.NewFromFloat(maxPriceJump),
+    prev:         lastStored,
+  }
+  validation := &stocksValidation{}
+
+  for _, stock := range stocks {
+    if validator.inRange(stock) && stock.StockedAt.After(validation.lastBarAt) {
+      validation.lastBarAt = stock.StockedAt
+    }
+    reasons := validator.check(stock)
+    if len(reasons) == 0 {
+      validation.valid = append(validation.valid, stock)
+      continue
+    }
+    validation.anomalies = append(validation.anomalies, &domain.StockAnomaly{
+      Stock:      stock,
+      Reason:     strings.Join(reasons, anomalyReasonSeparator),
+      DetectedAt: timeutils.NotTimeUTC(),
+    })
+  }
+  return validation
+}
+
+// check return reasons of bar rejection, bar is valid if no reasons are returned
+func (v *stocksValidator) check(stock *domain.Stock) []string {
+  var reasons []string
+
+  if stock.OpenPrice.IsNegative() || stock.ClosePrice.IsNegative() ||
+    stock.HighestPrice.IsNegative() || stock.LowestPrice.IsNegative() {
+    reasons = append(reasons, "negative price")
+  }
+  switch {
+  case stock.TradingVolume.IsNegative():
+    reasons = append(reasons, "negative volume")
+  case stock.TradingVolume.IsZero():
+    reasons = append(reasons, "zero volume of trading session")
+  }
+  if stock.HighestPrice.LessThan(stock.LowestPrice) {
+    reasons = append(reasons, fmt.Sprintf("high %s is below low %s", stock.HighestPrice, stock.LowestPrice))
+  }
+  if stock.HighestPrice.LessThan(decimal.Max(stock.OpenPrice, stock.ClosePrice)) {
+    reasons = append(reasons, fmt.Sprintf("high %s is below open or close", stock.HighestPrice))
+  }
+  if stock.LowestPrice.GreaterThan(decimal.Min(stock.OpenPrice, stock.ClosePrice)) {
+    reasons = append(reasons, fmt.Sprintf("low %s is above open or close", stock.LowestPrice))
+  }
+  inRange := v.inRange(stock)
+  if !inRange {
+    reasons = append(reasons, fmt.Sprintf("timestamp %s is out of requested range %s - %s",
+      stock.StockedAt.Format(time.RFC3339), v.from.Format(dateLayout), v.to.AddDate(0, 0, -1).Format(dateLayout)))
+  }
+  ordered := v.lastAt.IsZero() || stock.StockedAt.After(v.lastAt)
+  if !ordered {
+    reasons = append(reasons, fmt.Sprintf("timestamp %s is not after previous bar %s",
+      stock.StockedAt.Format(time.RFC3339), v.lastAt.Format(time.RFC3339)))
+  }
+  if jump, ok := v.priceJump(stock); ok && jump.GreaterThan(v.maxPriceJump) {
+    reasons = append(reasons, fmt.Sprintf("close jumped %s%% from previous close %s",
+      jump.Mul(percents).StringFixed(1), v.prev.ClosePrice))
+  }
+
+  if stock.StockedAt.After(v.lastAt) {
+    v.lastAt = stock.StockedAt
+  }
+  // reference moves to quarantined bars too, so a real price move is flagged once
+  // and the next bars are compared with the new price level
+  if inRange && ordered {
+    v.prev = stock
+  }
+  return reasons
+}
+
+func (v *stocksValidator) inRange(stock *domain.Stock) bool {
+  return !stock.StockedAt.Before(v.from) && stock.StockedAt.Before(v.to)
+}
+
+// priceJump return ratio of close change from the reference bar
+func (v *stocksValidator) priceJump(stock *domain.Stock) (decimal.Decimal, bool) {
+  if v.prev == nil || !v.prev.ClosePrice.IsPositive() {
+    return decimal.Zero, false
+  }
+  return stock.ClosePrice.Sub(v.prev.ClosePrice).Abs().Div(v.prev.ClosePrice), true
+}
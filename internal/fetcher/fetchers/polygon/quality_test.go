@@ -0,0 +1,126 @@
+package polygon
+
+import (
+  "scientific-research/internal/calendar"
+  "scientific-research/internal/domain"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/shopspring/decimal"
+)
+
+const testMaxPriceJump = 0.5
+
+var testSessions = []*calendar.Session{
+  {Date: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+  {Date: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)},
+  {Date: time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+}
+
+// testStock bar of day of march 2026 with open, close, high, low prices and volume
+func testStock(day int, prices ...string) *domain.Stock {
+  values := make([]decimal.Decimal, len(prices))
+  for idx, price := range prices {
+    values[idx] = decimal.RequireFromString(price)
+  }
+  return &domain.Stock{
+    TickerId:      "AAPL",
+    OpenPrice:     values[0],
+    ClosePrice:    values[1],
+    HighestPrice:  values[2],
+    LowestPrice:   values[3],
+    TradingVolume: values[4],
+    StockedAt:     time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC),
+  }
+}
+
+func TestValidateStocksRejectsBrokenBars(t *testing.T) {
+  tests := []struct {
+    name   string
+    stock  *domain.Stock
+    reason string
+  }{
+    {"negative price", testStock(2, "-1", "10", "11", "9", "100"), "negative price"},
+    {"negative volume", testStock(2, "10", "10", "11", "9", "-100"), "negative volume"},
+    {"zero volume", testStock(2, "10", "10", "11", "9", "0"), "zero volume"},
+    {"high below low", testStock(2, "10", "10", "9", "11", "100"), "high 9 is below low 11"},
+    {"high below close", testStock(2, "10", "12", "11", "9", "100"), "high 11 is below open or close"},
+    {"low above open", testStock(2, "8", "10", "11", "9", "100"), "low 9 is above open or close"},
+    {"before range", testStock(1, "10", "10", "11", "9", "100"), "out of requested range"},
+    {"after range", testStock(5, "10", "10", "11", "9", "100"), "out of requested range"},
+  }
+  for _, test := range tests {
+    validation := validateStocks([]*domain.Stock{test.stock}, testSessions, testMaxPriceJump, nil)
+    if len(validation.valid) != 0 || len(validation.anomalies) != 1 {
+      t.Errorf("%s: got %d valid bars, %d anomalies, want anomaly", test.name,
+        len(validation.valid), len(validation.anomalies))
+      continue
+    }
+    if reason := validation.anomalies[0].Reason; !strings.Contains(reason, test.reason) {
+      t.Errorf("%s: anomaly reason is '%s', want '%s'", test.name, reason, test.reason)
+    }
+  }
+}
+
+func TestValidateStocksRejectsUnorderedBars(t *testing.T) {
+  stocks := []*domain.Stock{
+    testStock(3, "10", "10", "11", "9", "100"),
+    testStock(3, "10", "10", "11", "9", "100"),
+    testStock(2, "10", "10", "11", "9", "100"),
+    testStock(4, "10", "10", "11", "9", "100"),
+  }
+  validation := validateStocks(stocks, testSessions, testMaxPriceJump, nil)
+
+  if len(validation.valid) != 2 || validation.valid[0] != stocks[0] || validation.valid[1] != stocks[3] {
+    t.Errorf("got %d valid bars, want the first and the last bars", len(validation.valid))
+  }
+  if len(validation.anomalies) != 2 {
+    t.Fatalf("got %d anomalies, want duplicate and earlier bars", len(validation.anomalies))
+  }
+  for _, anomaly := range validation.anomalies {
+    if !strings.Contains(anomaly.Reason, "is not after previous bar") {
+      t.Errorf("anomaly reason is '%s', want unordered timestamp", anomaly.Reason)
+    }
+  }
+}
+
+func TestValidateStocksPriceJump(t *testing.T) {
+  lastStored := testStock(1, "10", "10", "11", "9", "100")
+  stocks := []*domain.Stock{
+    // +100% from the last stored bar
+    testStock(2, "20", "20", "21", "19", "100"),
+    // compared with the flagged bar, not with the stored one
+    testStock(3, "20", "21", "22", "19", "100"),
+    // -50% is not above max jump
+    testStock(4, "21", "10.5", "21", "10", "100"),
+  }
+  validation := validateStocks(stocks, testSessions, testMaxPriceJump, lastStored)
+
+  if len(validation.anomalies) != 1 || validation.anomalies[0].Stock != stocks[0] {
+    t.Fatalf("got %d anomalies, want the first bar only", len(validation.anomalies))
+  }
+  if reason := validation.anomalies[0].Reason; reason != "close jumped 100.0% from previous close 10" {
+    t.Errorf("anomaly reason is '%s'", reason)
+  }
+  if len(validation.valid) != 2 {
+    t.Errorf("got %d valid bars, want 2", len(validation.valid))
+  }
+}
+
+func TestValidateStocksLastBarAtIncludesAnomalies(t *testing.T) {
+  stocks := []*domain.Stock{
+    testStock(2, "10", "10", "11", "9", "100"),
+    testStock(4, "10", "10", "11", "9", "0"),
+    testStock(5, "10", "10", "11", "9", "100"),
+  }
+  validation := validateStocks(stocks, testSessions, testMaxPriceJump, nil)
+
+  // quarantined bar of range moves watermark, bar out of range does not
+  if want := stocks[1].StockedAt; !validation.lastBarAt.Equal(want) {
+    t.Errorf("last bar is at %s, want %s", validation.lastBarAt, want)
+  }
+  if len(validation.valid) != 1 || len(validation.anomalies) != 2 {
+    t.Errorf("got %d valid bars, %d anomalies, want 1 and 2", len(validation.valid), len(validation.anomalies))
+  }
+}
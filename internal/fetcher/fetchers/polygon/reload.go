@@ -0,0 +1,66 @@
+package polygon
+
+import (
+  "fmt"
+  "reflect"
+  "scientific-research/pkg/utils/config"
+  "strings"
+)
+
+// ApplyConfig apply reloaded config to running fetcher. mode hours, requests limit,
+// retry policy and tickers filter applied live. changed tickers filter drops cursor of
+// interrupted cycle. changes of fields which require reconnect (api token, storage and
+// queue configs) are rejected
+func (f *Fetcher) ApplyConfig(cfg config.Config) error {
+  updated, ok := cfg.(*Config)
+  if !ok || updated == nil {
+    return fmt.Errorf("unexpected config type: %T", cfg)
+  }
+  running := f.getConfig()
+
+  rejected := restartRequiredChanges(running, updated)
+  for _, field := range rejected {
+    log.Warnf("config field '%s' changed but requires restart. change rejected", field)
+  }
+  updated = updated.withRestartRequiredFrom(running)
+
+  f.settingsMu.Lock()
+  f.config = updated
+  f.settingsMu.Unlock()
+
+  f.client.SetRequestsLimit(updated.requestsLimit())
+  f.client.SetRetries(updated.retriesOption())
+
+  if !reflect.DeepEqual(running.TickersFilter, updated.TickersFilter) {
+    f.resetCursorOfChangedFilter()
+  }
+
+  log.Infof("fetcher config reloaded. total mode hours: %d, current mode hours: %d, tickers filter: %v",
+    updated.ModeTotalHours, updated.ModeCurrentHours, updated.TickersFilter)
+
+  if len(rejected) != 0 {
+    return fmt.Errorf("fields require restart and were not applied: %s", strings.Join(rejected, ", "))
+  }
+  return nil
+}
+
+// resetCursorOfChangedFilter drop cursor of tickers pages listed with previous filter. running
+// tickers job keeps filter of its cycle start, its cursor is dropped when the cycle is resumed.
+// reset state is saved by the next checkpoint
+func (f *Fetcher) resetCursorOfChangedFilter() {
+  f.control.mu.Lock()
+  defer f.control.mu.Unlock()
+
+  if f.control.runningKind == jobKindTickers {
+    log.Info("tickers filter changed while tickers job is running. the job keeps previous filter")
+    return
+  }
+  f.state.ResetCursor()
+  log.Info("tickers filter changed. tickers cursor reset")
+}
+
+func (f *Fetcher) getConfig() *Config {
+  f.settingsMu.RLock()
+  defer f.settingsMu.RUnlock()
+  return f.config
+}
@@ -0,0 +1,61 @@
+package polygon
+
+import (
+  "context"
+  "testing"
+)
+
+func TestApplyConfigResetsCursorOfChangedFilter(t *testing.T) {
+  tests := []struct {
+    name        string
+    filter      TickersFilter
+    runningKind string
+    wantCursor  string
+  }{
+    {"unchanged filter", nil, "", "2"},
+    {"changed filter", TickersFilter{"type": "CS"}, "", ""},
+    {"changed filter while tickers job runs", TickersFilter{"type": "CS"}, jobKindTickers, "2"},
+  }
+  for _, test := range tests {
+    f := newTestFetcher(t, "http://localhost", newMemStorage())
+    f.state.SetPage("2", "http://localhost"+tickersApi+"?cursor=2")
+    f.state.SetLastTickerId("CCC")
+    f.control.runningKind = test.runningKind
+
+    updated := *f.config
+    updated.TickersFilter = test.filter
+    if err := f.ApplyConfig(&updated); err != nil {
+      t.Fatalf("%s: cannot apply config: %v", test.name, err)
+    }
+    if cursor, _ := f.state.resumePoint(); cursor != test.wantCursor {
+      t.Errorf("%s: cursor is '%s', want '%s'", test.name, cursor, test.wantCursor)
+    }
+  }
+}
+
+func TestFetchTickersDropsCursorOfOtherFilter(t *testing.T) {
+  srv := newTestServer(t, lastClosedSessions(t, 1), testTickers...)
+  s := newMemStorage()
+  f := newTestFetcher(t, srv.URL, s)
+
+  // cycle without filter is interrupted on the second page
+  srv.FailNext(tickersApi+"/DDD", 1, 500)
+  if err := f.fetchTickers(context.Background()); err == nil {
+    t.Fatalf("failed ticker is not reported")
+  }
+  f.checkpoint(true)
+
+  // restarted fetcher has other filter
+  served := len(srv.Requests())
+  resumed := newTestFetcher(t, srv.URL, s)
+  resumed.config.TickersFilter = TickersFilter{"type": "CS"}
+  if err := resumed.loadFetcherState(); err != nil {
+    t.Fatalf("cannot load checkpoint: %v", err)
+  }
+  if err := resumed.fetchTickers(context.Background()); err != nil {
+    t.Fatalf("cannot fetch tickers: %v", err)
+  }
+  if cursors := tickersPageCursors(srv.Requests()[served:]); !equalStrings(cursors, []string{"", "2", "4"}) {
+    t.Errorf("requested tickers pages %q, want cycle from the first page", cursors)
+  }
+}
@@ -0,0 +1,177 @@
+package polygon
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/scheduler"
+  "scientific-research/pkg/utils/logging"
+  "scientific-research/pkg/utils/timeutils"
+  "time"
+)
+
+const (
+  jobKindTickers   = "tickers"
+  jobKindBars      = "bars"
+  jobKindWatchlist = "watchlist"
+  jobKindIntraday  = "intraday"
+)
+
+// jobRun handler of scheduled job
+type jobRun func(ctx context.Context, info *scheduler.RunInfo) error
+
+// defaultJobs jobs scheduled if config has no schedule: daily tickers refresh
+var defaultJobs = []*scheduler.JobConfig{
+  {
+    Name:       "tickers",
+    Kind:       jobKindTickers,
+    Cron:       "@daily",
+    RunOnStart: true,
+  },
+}
+
+// defaultWatchlistJobs jobs scheduled if config has no schedule and watchlist is set
+var defaultWatchlistJobs = []*scheduler.JobConfig{
+  {
+    Name:       "watchlist",
+    Kind:       jobKindWatchlist,
+    Cron:       "@daily",
+    RunOnStart: true,
+  },
+}
+
+// newScheduler create scheduler with jobs from config
+func (f *Fetcher) newScheduler() (*scheduler.Scheduler, error) {
+  config := f.getConfig()
+
+  scheduleConfig := &scheduler.Config{}
+  if config.Schedule != nil {
+    scheduleConfig = config.Schedule
+  }
+  location, err := scheduler.LoadLocation(scheduleConfig.Timezone)
+  if err != nil {
+    return nil, fmt.Errorf("cannot load scheduler timezone: %v", err)
+  }
+  jobConfigs := scheduleConfig.Jobs
+  if len(jobConfigs) == 0 {
+    jobConfigs = defaultJobs
+    if f.hasWatchlist() {
+      jobConfigs = defaultWatchlistJobs
+    }
+  }
+
+  s := scheduler.NewScheduler(f.storage, f.memberId())
+  jobKinds := map[string]string{}
+  for _, jobConfig := range jobConfigs {
+    run, err := f.jobHandler(jobConfig)
+    if err != nil {
+      return nil, err
+    }
+    jobKinds[jobConfig.Name] = jobConfig.Kind
+
+    job, err := scheduler.NewJob(jobConfig, location, f.getCalendar, f.controlled(jobConfig.Name, jobConfig.Kind, run))
+    if err != nil {
+      return nil, err
+    }
+    if err = s.Add(job); err != nil {
+      return nil, err
+    }
+  }
+  f.setScheduler(s, jobKinds)
+  return s, nil
+}
+
+func (f *Fetcher) jobHandler(config *scheduler.JobConfig) (jobRun, error) {
+  switch config.Kind {
+  case jobKindTickers:
+    return f.runTickersJob, nil
+  case jobKindBars:
+    return f.runBarsJob, nil
+  case jobKindWatchlist:
+    return f.runWatchlistJob, nil
+  case jobKindIntraday:
+    return f.runIntradayJob, nil
+  }
+  return nil, fmt.Errorf("unknown kind '%s' of job '%s'. possible: %s, %s, %s, %s",
+    config.Kind, config.Name, jobKindTickers, jobKindBars, jobKindWatchlist, jobKindIntraday)
+}
+
+// setModeFromRunInfo fetch the total window until job has succeeded once, then the current window.
+// return context with mode log field
+func (f *Fetcher) setModeFromRunInfo(ctx context.Context, info *scheduler.RunInfo) context.Context {
+  mode := fetcherModeCurrent
+  if info.LastSuccessAt == nil {
+    mode = fetcherModeTotal
+  }
+  f.state.SetModeCode(mode)
+  return logging.WithField(ctx, logging.FieldMode, fetcherModeNames[mode])
+}
+
+// runTickersJob fetch tickers pages with details and stocks. interrupted cycle is resumed
+// from the saved state
+func (f *Fetcher) runTickersJob(ctx context.Context, info *scheduler.RunInfo) error {
+  f.refreshCalendars(ctx)
+  ctx = f.setModeFromRunInfo(ctx, info)
+  f.state.ResetFinished()
+
+  if err := f.fetchTickers(ctx); err != nil {
+    // progress is kept in state, the next run resumes from the last processed ticker
+    f.checkpoint(true)
+    return err
+  }
+  f.state.SetUpdatedTime(timeutils.NotTimeUTC())
+  f.state.SetFinished()
+  f.checkpoint(true)
+
+  return nil
+}
+
+// runBarsJob fetch stocks of closed sessions of stored tickers owned by this instance
+func (f *Fetcher) runBarsJob(ctx context.Context, info *scheduler.RunInfo) error {
+  f.refreshCalendars(ctx)
+  return f.fetchStoredTickersStocks(ctx, info, false)
+}
+
+// runIntradayJob fetch stocks of stored tickers owned by this instance including the open
+// session. runs outside of the exchange session (holidays, after early close) are skipped
+func (f *Fetcher) runIntradayJob(ctx context.Context, info *scheduler.RunInfo) error {
+  f.refreshCalendars(ctx)
+
+  nowT := f.now().UTC()
+  session, ok := f.stocksCalendar().Session(nowT)
+  if !ok || nowT.Before(session.Open) || !nowT.Before(session.Close) {
+    f.logger(ctx).Debugf("no open trading session at %s. skip intraday run", nowT.Format(time.RFC3339))
+    return nil
+  }
+  return f.fetchStoredTickersStocks(ctx, info, true)
+}
+
+// fetchStoredTickersStocks fetch stocks of stored tickers owned by this instance. failed tickers
+// do not stop the job, they are fetched again on the next run
+func (f *Fetcher) fetchStoredTickersStocks(ctx context.Context, info *scheduler.RunInfo, withOpenSession bool) error {
+  ctx = f.setModeFromRunInfo(ctx, info)
+
+  tickerIds, err := f.storage.WithContext(ctx).GetTickerIds(true)
+  if err != nil {
+    return fmt.Errorf("cannot get tickers from storage: %v", err)
+  }
+  var failed int
+  for _, tickerId := range tickerIds {
+    if err = f.waitIfPaused(); err != nil {
+      return err
+    }
+    if ctx.Err() != nil {
+      return ctx.Err()
+    }
+    if !f.ownsTicker(tickerId) {
+      continue
+    }
+    if err = f.fetchStocks(ctx, tickerId, withOpenSession); err != nil {
+      f.logger(ctx).Errorf("cannot fetch stocks for ticker %s: %v", tickerId, err)
+      failed++
+    }
+  }
+  if failed != 0 {
+    return fmt.Errorf("cannot fetch stocks for %d of %d tickers", failed, len(tickerIds))
+  }
+  return nil
+}
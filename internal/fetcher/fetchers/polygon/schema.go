@@ -1,6 +1,10 @@
 package polygon
 
-import "time"
+import (
+  "time"
+
+  "github.com/shopspring/decimal"
+)
 
 type tickerResult struct {
   Ticker         string    `json:"ticker"`
@@ -21,13 +25,15 @@ type tickersResponse struct {
   NextUrl string          `json:"next_url"`
 }
 
+// stockResult aggregate bar. prices and volume are parsed from json numbers without
+// float rounding
 type stockResult struct {
-  Open      float64 `json:"o"`
-  Close     float64 `json:"c"`
-  Highest   float64 `json:"h"`
-  Lowest    float64 `json:"l"`
-  Timestamp int64   `json:"t"`
-  Volume    float64 `json:"v"`
+  Open      decimal.Decimal `json:"o"`
+  Close     decimal.Decimal `json:"c"`
+  Highest   decimal.Decimal `json:"h"`
+  Lowest    decimal.Decimal `json:"l"`
+  Timestamp int64           `json:"t"`
+  Volume    decimal.Decimal `json:"v"`
 }
 
 type stocksResponse struct {
@@ -77,3 +83,13 @@ type tickerDetailsResponse struct {
   Results *tickerDetailsResults `json:"results"`
   Status  string                `json:"status"`
 }
+
+// marketDayResult upcoming holiday or early close, item of `/v1/marketstatus/upcoming` response
+type marketDayResult struct {
+  Exchange string    `json:"exchange"`
+  Name     string    `json:"name"`
+  Date     string    `json:"date"`
+  Status   string    `json:"status"`
+  Open     time.Time `json:"open"`
+  Close    time.Time `json:"close"`
+}
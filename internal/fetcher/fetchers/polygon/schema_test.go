@@ -0,0 +1,60 @@
+package polygon
+
+import (
+  "encoding/json"
+  "testing"
+  "time"
+)
+
+func TestStocksResponseKeepsDecimals(t *testing.T) {
+  body := `{
+    "ticker": "AAPL",
+    "status": "OK",
+    "results": [
+      {"o": 0.30000000000000004123, "c": 187.125, "h": 1.5e2, "l": 0.0001,
+       "v": 123456789012345678901, "t": 1772409600000}
+    ]
+  }`
+
+  var response stocksResponse
+  if err := json.Unmarshal([]byte(body), &response); err != nil {
+    t.Fatalf("cannot parse response: %v", err)
+  }
+  if len(response.StockResults) != 1 {
+    t.Fatalf("got %d results, want 1", len(response.StockResults))
+  }
+  stock, err := createStock(response.Ticker, response.StockResults[0])
+  if err != nil {
+    t.Fatalf("cannot create stock: %v", err)
+  }
+
+  tests := []struct {
+    name string
+    got  string
+    want string
+  }{
+    {"open", stock.OpenPrice.String(), "0.30000000000000004123"},
+    {"close", stock.ClosePrice.String(), "187.125"},
+    {"high", stock.HighestPrice.String(), "150"},
+    {"low", stock.LowestPrice.String(), "0.0001"},
+    {"volume", stock.TradingVolume.String(), "123456789012345678901"},
+  }
+  for _, test := range tests {
+    if test.got != test.want {
+      t.Errorf("%s is %s, want %s", test.name, test.got, test.want)
+    }
+  }
+  if stock.StockId != "AAPL-1772409600000" {
+    t.Errorf("stock id is '%s'", stock.StockId)
+  }
+  if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !stock.StockedAt.Equal(want) {
+    t.Errorf("stock time is %s, want %s", stock.StockedAt, want)
+  }
+}
+
+func TestCreateStockOfEmptyResult(t *testing.T) {
+  stock, err := createStock("AAPL", nil)
+  if stock != nil || err != nil {
+    t.Errorf("got %v, %v for empty result, want nothing", stock, err)
+  }
+}
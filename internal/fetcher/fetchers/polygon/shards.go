@@ -0,0 +1,77 @@
+package polygon
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/domain"
+)
+
+// tickers paging is not partitioned between sharded instances: every instance requests all
+// pages of `/v3/reference/tickers` and skips tickers of not owned shards. the cursor of the
+// next page is opaque, so pages cannot be split by shard, and owned shards change between
+// pages on rebalance. the cost is tickers pages requests multiplied by instances count
+// within the rate limit of each instance, details and stocks requests are not duplicated
+
+// handOverShards record shards acquired on rebalance with progress of their previous owner,
+// tickers of these shards passed by tickers pages before acquiring are fetched on cycle end
+func (f *Fetcher) handOverShards(shards []int) {
+  states, err := f.storage.GetShardStates(FetcherName)
+  if err != nil {
+    log.Errorf("cannot get progress of acquired shards %v: %v. fetch all their tickers", shards, err)
+  }
+  progress := map[int]*domain.ShardState{}
+  for _, shardState := range states {
+    progress[shardState.Shard] = shardState
+  }
+  for _, shard := range shards {
+    // nil progress if shard was never fetched
+    f.state.AddHandedOverShard(shard, progress[shard])
+  }
+  log.Infof("shards %v acquired, their passed tickers are fetched on cycle end", shards)
+}
+
+// catchUpHandedOverShards fetch stocks of stored tickers of shards acquired during the cycle.
+// tickers up to the last processed ticker of the previous owner in this cycle are skipped,
+// tickers already fetched by this instance have no sessions to request. details are
+// refreshed in the next cycle
+func (f *Fetcher) catchUpHandedOverShards(ctx context.Context) error {
+  if f.shards == nil {
+    return nil
+  }
+  handedOver, cycleStartedAt := f.state.handedOverShards()
+  if len(handedOver) == 0 {
+    return nil
+  }
+  tickerIds, err := f.storage.WithContext(ctx).GetTickerIds(true)
+  if err != nil {
+    return fmt.Errorf("cannot get tickers of acquired shards from storage: %v", err)
+  }
+  var fetched, failed int
+  for _, tickerId := range tickerIds {
+    previous, ok := handedOver[f.shards.ShardOf(tickerId)]
+    if !ok || !f.ownsTicker(tickerId) {
+      continue
+    }
+    if previous != nil && !previous.UpdatedAt.Before(cycleStartedAt) && tickerId <= previous.LastTickerId {
+      continue
+    }
+    if err = f.waitIfPaused(); err != nil {
+      return err
+    }
+    if ctx.Err() != nil {
+      return ctx.Err()
+    }
+    if err = f.fetchStocks(withTicker(ctx, tickerId), tickerId, false); err != nil {
+      f.logger(ctx).Errorf("cannot fetch stocks for ticker %s of acquired shard: %v", tickerId, err)
+      failed++
+      continue
+    }
+    fetched++
+  }
+  f.logger(ctx).Infof("caught up %d tickers of %d acquired shards", fetched, len(handedOver))
+
+  if failed != 0 {
+    return fmt.Errorf("cannot fetch stocks for %d tickers of acquired shards", failed)
+  }
+  return nil
+}
@@ -2,155 +2,312 @@ package polygon
 
 import (
   "fmt"
+  "net/url"
   "scientific-research/internal/domain"
+  "scientific-research/internal/httpclient"
   "scientific-research/pkg/utils/common"
   "scientific-research/pkg/utils/timeutils"
+  "sync"
   "time"
-
-  log "github.com/sirupsen/logrus"
 )
 
+// state progress of tickers fetching. cursor is the cursor of tickers page which is
+// being processed (empty for the first page), lastTickerId is the last fully processed
+// ticker of this page. guarded by mu, fetching goroutine and state saving may run concurrently
 type state struct {
-  finished         bool
-  updatedAt        *time.Time
-  modeCode         int
-  modeTotalHours   int
-  modeCurrentHours int
-  ticker           *stateReq
-  tickerDetails    *stateReq
-  stocks           *stateReq
+  mu             sync.Mutex
+  finished       bool
+  updatedAt      *time.Time
+  modeCode       int
+  cursor         string
+  lastTickerId   string
+  pageURL        string
+  checkpointedAt time.Time
+  // progress of owned shards in current cycle, empty if tickers are not sharded
+  cycleStartedAt time.Time
+  shards         map[int]*domain.ShardState
+  shardsChanged  bool
+  // shards acquired on rebalance in current cycle with stored progress of their previous
+  // owner (nil if shard has no progress)
+  handedOver map[int]*domain.ShardState
 }
 
-type stateReq struct {
-  reqURL string
-  used   bool
+func newFetcherState() *state {
+  return &state{
+    modeCode:   fetcherModeTotal,
+    shards:     map[int]*domain.ShardState{},
+    handedOver: map[int]*domain.ShardState{},
+  }
 }
 
-func newFetcherState(modeTotalHours, modeCurrentHours int) *state {
-  return &state{
-    modeCode:         fetcherModeTotal,
-    modeTotalHours:   modeTotalHours,
-    modeCurrentHours: modeCurrentHours,
-    ticker:           &stateReq{},
-    tickerDetails:    &stateReq{},
-    stocks:           &stateReq{},
+// StartCycle reset shards progress at the beginning of tickers fetching cycle
+func (s *state) StartCycle() {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.cycleStartedAt = timeutils.NotTimeUTC()
+  s.shards = map[int]*domain.ShardState{}
+  s.shardsChanged = false
+  s.handedOver = map[int]*domain.ShardState{}
+}
+
+// AddHandedOverShard record shard acquired on rebalance with stored progress of previous owner.
+// progress of already recorded shard is kept
+func (s *state) AddHandedOverShard(shard int, previous *domain.ShardState) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if _, ok := s.handedOver[shard]; !ok {
+    s.handedOver[shard] = previous
+  }
+}
+
+// handedOverShards return copy of shards acquired in current cycle and start of cycle
+func (s *state) handedOverShards() (map[int]*domain.ShardState, time.Time) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  handedOver := make(map[int]*domain.ShardState, len(s.handedOver))
+  for shard, previous := range s.handedOver {
+    handedOver[shard] = previous
+  }
+  return handedOver, s.cycleStartedAt
+}
+
+// AddShardProgress record processed ticker of owned shard
+func (s *state) AddShardProgress(fetcherId, memberId string, shard int, tickerId string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if s.cycleStartedAt.IsZero() {
+    // cycle resumed after restart
+    s.cycleStartedAt = timeutils.NotTimeUTC()
+  }
+  shardState, ok := s.shards[shard]
+  if !ok {
+    shardState = &domain.ShardState{
+      FetcherId:      fetcherId,
+      Shard:          shard,
+      CycleStartedAt: s.cycleStartedAt,
+    }
+    s.shards[shard] = shardState
+  }
+  shardState.OwnerId = memberId
+  shardState.LastTickerId = tickerId
+  shardState.ProcessedCount++
+  shardState.UpdatedAt = timeutils.NotTimeUTC()
+  s.shardsChanged = true
+}
+
+// takeChangedShards return copy of shards progress if it changed since the last call
+func (s *state) takeChangedShards() []*domain.ShardState {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if !s.shardsChanged {
+    return nil
+  }
+  s.shardsChanged = false
+
+  shards := make([]*domain.ShardState, 0, len(s.shards))
+  for _, shardState := range s.shards {
+    shardCopy := *shardState
+    shards = append(shards, &shardCopy)
   }
+  return shards
 }
 
 func (s *state) SetFinished() {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
   s.finished = true
+  s.cursor = ""
+  s.lastTickerId = ""
+  s.pageURL = ""
+}
+
+// ResetCursor drop progress of interrupted cycle
+func (s *state) ResetCursor() {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.cursor = ""
+  s.lastTickerId = ""
+  s.pageURL = ""
+  s.cycleStartedAt = time.Time{}
 }
 
 func (s *state) ResetFinished() {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
   s.finished = false
 }
 
 func (s *state) SetUpdatedTime(t time.Time) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
   s.updatedAt = &t
 }
 
+// SetPage set tickers page which is being processed
+//   cursor page cursor, empty for the first page
+//   pageURL page request url, saved for debugging
+func (s *state) SetPage(cursor, pageURL string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if cursor != s.cursor {
+    s.lastTickerId = ""
+  }
+  s.cursor = cursor
+  s.pageURL = stripStateReqURL(pageURL)
+}
+
+// SetLastTickerId set the last fully processed ticker of current page
+func (s *state) SetLastTickerId(tickerId string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.lastTickerId = tickerId
+}
+
+// getPageURL return url of tickers page which is being processed, without credentials
+func (s *state) getPageURL() string {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.pageURL
+}
+
+// resumePoint return page cursor and the last processed ticker to resume from
+func (s *state) resumePoint() (string, string) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.cursor, s.lastTickerId
+}
+
 func (s *state) SetModeCode(mode int) {
   if mode != fetcherModeTotal && mode != fetcherModeCurrent {
     log.Warnf("invalid fetcher mode code: %d. mode code do not set. possible: %d - total, %d - current",
       mode, fetcherModeTotal, fetcherModeCurrent)
     return
   }
+  s.mu.Lock()
   s.modeCode = mode
+  s.mu.Unlock()
+
   log.Infof("current fetcher mode: %d. possible: %d - total, %d - current",
     mode, fetcherModeTotal, fetcherModeCurrent)
 }
 
-func (f *Fetcher) SetTickerId(tickerId string) {
-  if tickerId == "" {
-    log.Warnf("ticker id is empty. ticker id do not set")
-    return
-  }
-  f.specTickerId = tickerId
-}
+func (s *state) getModeCode() int {
+  s.mu.Lock()
+  defer s.mu.Unlock()
 
-func (f *Fetcher) hasRecentlyFetched() bool {
-  if !f.state.finished || f.state.updatedAt == nil {
-    return false
-  }
-  updatedAt := *f.state.updatedAt
-  thresholdTime := updatedAt.Add(recentlyThresholdInterval)
-  return thresholdTime.After(time.Now())
+  return s.modeCode
 }
 
-func (f *Fetcher) hasSpecTickerId() bool {
-  return f.specTickerId != ""
+// memberId return id of instance if tickers are sharded
+func (f *Fetcher) memberId() string {
+  if f.shards == nil {
+    return ""
+  }
+  return f.shards.MemberId()
 }
 
+// ContinuouslyFetch run scheduled jobs until fetcher context is done
 func (f *Fetcher) ContinuouslyFetch() {
   if err := f.loadFetcherState(); err != nil {
     log.Errorf("state loading from storage failed. : %v", err)
   }
-  f.state.SetModeCode(fetcherModeTotal)
+  go f.relay.ContinuouslyRelay()
+  go f.runBackfills()
 
-  tryLeft := fetcherRetryCount
-  // fetch with retries
-  for tryLeft >= 0 {
-    if f.hasRecentlyFetched() {
-      log.Printf("recently fetched. wait %v before the next fetch",
-        recentlyFetchedSleepInterval)
+  s, err := f.newScheduler()
+  if err != nil {
+    log.Fatalf("cannot create scheduler: %v", err)
+  }
+  if err = s.Run(f.ctx); err != nil {
+    log.Fatalf("scheduler stopped: %v", err)
+  }
+}
 
-      time.Sleep(recentlyFetchedSleepInterval)
-      f.state.ResetFinished() // reset finished field
-      continue
-    }
-    var err error
+// checkpoint save fetcher state if checkpoint interval passed since the last saving
+//   force save regardless of interval
+func (f *Fetcher) checkpoint(force bool) {
+  f.state.mu.Lock()
+  due := force || time.Since(f.state.checkpointedAt) >= stateCheckpointInterval
+  f.state.mu.Unlock()
 
-    if f.hasSpecTickerId() {
-      err = f.fetchStocks(f.specTickerId)
-    } else {
-      err = f.fetchTickers()
-    }
-    if err != nil {
-      log.Errorf("fetching error: %v. wait %v before the next fetch",
-        err, encounteredErrorSleepInterval)
+  if !due {
+    return
+  }
+  if err := f.saveFetcherState(); err != nil {
+    log.Errorf("cannot checkpoint fetcher state: %v", err)
+  }
+}
 
-      time.Sleep(encounteredErrorSleepInterval)
-      tryLeft--
-      continue
-    }
-    f.state.SetUpdatedTime(timeutils.NotTimeUTC())
-    f.state.SetFinished() // set finished field
+func (f *Fetcher) SaveFetcherState() {
+  if err := f.saveFetcherState(); err != nil {
+    log.Errorf("cannot save fetcher state: %v", err)
+  }
+}
 
-    // set retry count again
-    tryLeft = fetcherRetryCount
-    log.Println("successfully fetching finished")
+func (f *Fetcher) saveFetcherState() error {
+  fetcherState := createFetcherState(f.state)
+  fetcherState.MemberId = f.memberId()
 
-    if f.hasSpecTickerId() {
-      return
+  if err := f.storage.PutFetcherState(fetcherState); err != nil {
+    return fmt.Errorf("cannot put fetcher state to storage: %v", err)
+  }
+  if shards := f.state.takeChangedShards(); len(shards) != 0 {
+    if err := f.storage.PutShardStates(shards); err != nil {
+      return fmt.Errorf("cannot put shards progress to storage: %v", err)
     }
-    f.once.Do(func() {
-      f.state.SetModeCode(fetcherModeCurrent)
-    })
   }
-  log.Fatalf("fetching failed and stopped")
-}
+  f.state.mu.Lock()
+  f.state.checkpointedAt = time.Now()
+  f.state.mu.Unlock()
 
-func (f *Fetcher) SaveFetcherState() {
-  if err := f.storage.PutFetcherState(createFetcherState(f.state)); err != nil {
-    log.Fatalf("cannot put fetcher state to storage: %v", err)
-  }
+  return nil
 }
 
 func (f *Fetcher) loadFetcherState() error {
-  state, found, err := f.storage.GetFetcherState()
+  state, found, err := f.storage.GetFetcherState(f.memberId())
   if err != nil {
     return fmt.Errorf("cannot get fetcher state from storage: %v", err)
   }
   if !found {
     return nil
   }
+  cursor := state.Cursor
+  if cursor == "" {
+    // rows saved by older versions keep cursor only in page url
+    cursor = cursorFromURL(state.TickerReqUrl)
+  }
+
+  f.state.mu.Lock()
+  defer f.state.mu.Unlock()
+
   // set fields from storage state
-  f.state.ticker.reqURL = common.StripString(state.TickerReqUrl)
-  f.state.tickerDetails.reqURL = common.StripString(state.TickerDetailsReqUrl)
-  f.state.stocks.reqURL = common.StripString(state.StockReqUrl)
+  f.state.cursor = cursor
+  f.state.lastTickerId = state.LastTickerId
+  // rows saved by older versions may contain api token
+  f.state.pageURL = stripStateReqURL(state.TickerReqUrl)
   f.state.updatedAt = &state.CreatedAt
   f.state.finished = state.Finished
 
+  if state.Finished {
+    f.state.cursor = ""
+    f.state.lastTickerId = ""
+  }
+  log.Infof("loaded fetcher state. finished: %t, cursor: '%s', last ticker: '%s'",
+    f.state.finished, f.state.cursor, f.state.lastTickerId)
+
   return nil
 }
 
@@ -158,10 +315,35 @@ func createFetcherState(state *state) *domain.FetcherState {
   if state == nil {
     return nil
   }
+  state.mu.Lock()
+  defer state.mu.Unlock()
+
   return &domain.FetcherState{
-    TickerReqUrl:        state.ticker.reqURL,
-    TickerDetailsReqUrl: state.tickerDetails.reqURL,
-    StockReqUrl:         state.stocks.reqURL,
-    CreatedAt:           timeutils.NotTimeUTC(),
+    TickerReqUrl: stripStateReqURL(state.pageURL),
+    Cursor:       state.cursor,
+    LastTickerId: state.lastTickerId,
+    CreatedAt:    timeutils.NotTimeUTC(),
+    Finished:     state.finished,
+  }
+}
+
+// stripStateReqURL remove credentials from request url. stored cursors must carry no api token
+func stripStateReqURL(reqURL string) string {
+  reqURL = common.StripString(reqURL)
+  if reqURL == "" {
+    return ""
+  }
+  return httpclient.StripCredentials(reqURL)
+}
+
+// cursorFromURL return value of cursor query param of request url
+func cursorFromURL(reqURL string) string {
+  if reqURL == "" {
+    return ""
+  }
+  parsedURL, err := url.Parse(reqURL)
+  if err != nil {
+    return ""
   }
+  return parsedURL.Query().Get(respCursorKey)
 }
@@ -0,0 +1,79 @@
+package polygon
+
+import (
+  "context"
+  "errors"
+  "scientific-research/internal/fetcher"
+  "strings"
+  "testing"
+)
+
+func TestFetchTickersResumesFromCheckpoint(t *testing.T) {
+  sessions := lastClosedSessions(t, 3)
+  srv := newTestServer(t, sessions, testTickers...)
+  s := newMemStorage()
+  f := newTestFetcher(t, srv.URL, s)
+
+  // the second ticker of the second page fails
+  srv.FailNext(tickersApi+"/DDD", 1, 500)
+  if err := f.fetchTickers(context.Background()); err == nil {
+    t.Fatalf("failed ticker is not reported")
+  }
+  f.checkpoint(true)
+
+  if s.fetcherState == nil || s.fetcherState.Cursor != "2" || s.fetcherState.LastTickerId != "CCC" {
+    t.Fatalf("checkpoint is %+v, want cursor 2 after ticker CCC", s.fetcherState)
+  }
+  if strings.Contains(s.fetcherState.TickerReqUrl, testApiToken) {
+    t.Errorf("checkpoint keeps api token: %s", s.fetcherState.TickerReqUrl)
+  }
+
+  // restarted fetcher continues from checkpoint
+  served := len(srv.Requests())
+  resumed := newTestFetcher(t, srv.URL, s)
+  if err := resumed.loadFetcherState(); err != nil {
+    t.Fatalf("cannot load checkpoint: %v", err)
+  }
+  if err := resumed.fetchTickers(context.Background()); err != nil {
+    t.Fatalf("cannot resume tickers fetching: %v", err)
+  }
+  requests := srv.Requests()[served:]
+
+  if cursors := tickersPageCursors(requests); !equalStrings(cursors, []string{"2", "4"}) {
+    t.Errorf("requested tickers pages %q after restart, want pages from checkpoint", cursors)
+  }
+  if tickerIds := detailsRequests(requests); !equalStrings(tickerIds, []string{"DDD", "EEE"}) {
+    t.Errorf("requested details of %v after restart, want tickers after checkpoint", tickerIds)
+  }
+  for _, tickerId := range testTickers {
+    if len(s.tickerStocks(tickerId)) != len(sessions) {
+      t.Errorf("stocks of ticker %s are not stored", tickerId)
+    }
+  }
+}
+
+func TestResetCursorIsRejectedWhileTickersJobRuns(t *testing.T) {
+  s := newMemStorage()
+  f := newTestFetcher(t, "http://localhost", s)
+  f.state.SetPage("2", "http://localhost/v3/reference/tickers?cursor=2")
+  f.state.SetLastTickerId("CCC")
+
+  f.control.runningKind = jobKindTickers
+  if err := f.ResetCursor(); !errors.Is(err, fetcher.ErrJobRunning) {
+    t.Fatalf("got error %v, want job running", err)
+  }
+  if cursor, lastTickerId := f.state.resumePoint(); cursor != "2" || lastTickerId != "CCC" {
+    t.Errorf("cursor is reset while tickers job runs: '%s' after '%s'", cursor, lastTickerId)
+  }
+
+  f.control.runningKind = ""
+  if err := f.ResetCursor(); err != nil {
+    t.Fatalf("cannot reset cursor: %v", err)
+  }
+  if cursor, lastTickerId := f.state.resumePoint(); cursor != "" || lastTickerId != "" {
+    t.Errorf("cursor is not reset: '%s' after '%s'", cursor, lastTickerId)
+  }
+  if s.fetcherState == nil || s.fetcherState.Cursor != "" {
+    t.Errorf("reset cursor is not saved: %+v", s.fetcherState)
+  }
+}
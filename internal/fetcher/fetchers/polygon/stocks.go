@@ -4,75 +4,227 @@ import (
   "context"
   "fmt"
   "net/url"
+  "scientific-research/internal/calendar"
   "scientific-research/internal/domain"
   "scientific-research/internal/fetcher"
   "scientific-research/internal/httpclient"
+  "scientific-research/internal/objectstore"
+  "scientific-research/internal/outbox"
   "scientific-research/internal/queue"
+  "scientific-research/internal/shard"
   "scientific-research/internal/storage"
+  "scientific-research/internal/tracing"
   "scientific-research/pkg/utils/common"
+  "scientific-research/pkg/utils/logging"
   "scientific-research/pkg/utils/timeutils"
   "scientific-research/pkg/utils/validation"
+  "strings"
   "sync"
   "time"
 
-  log "github.com/sirupsen/logrus"
+  "github.com/sirupsen/logrus"
+  "go.opentelemetry.io/otel/attribute"
+  "go.opentelemetry.io/otel/trace"
+)
+
+var (
+  log    = logging.Logger("polygon")
+  tracer = tracing.Tracer("polygon")
 )
 
 type Fetcher struct {
-  ctx          context.Context
-  client       *httpclient.Client
-  storage      storage.Storage
-  msQueue      queue.MediaServiceQueue
-  state        *state
-  once         *sync.Once
-  specTickerId string
+  ctx         context.Context
+  client      *httpclient.Client
+  storage     storage.Storage
+  msQueue     queue.MediaServiceQueue
+  events      queue.EventsPublisher
+  relay       *outbox.Relay
+  objectStore objectstore.Store
+  shards      *shard.Coordinator
+  calendars   *calendar.Registry
+  state       *state
+  watchlist   *watchlist
+  control     *control
+  apiBaseURL  string
+  settingsMu  sync.RWMutex // guards config and reloadable state fields
+  config      *Config
+  // calendarsRefreshedAt time of the last calendars refresh. scheduled jobs and backfills
+  // refresh calendars concurrently, so it is guarded by calendarsMu
+  calendarsMu          sync.Mutex
+  calendarsRefreshedAt time.Time
+  // now current time of trading sessions ranges
+  now func() time.Time
+}
+
+type fetcherDeps struct {
+  storage     storage.Storage
+  msQueue     queue.MediaServiceQueue
+  events      queue.EventsPublisher
+  shards      *shard.Coordinator
+  httpOptions []httpclient.Options
+  now         func() time.Time
+}
+
+type Option func(d *fetcherDeps)
+
+// WithStorage use specified storage instead of connecting to postgres from config
+func WithStorage(s storage.Storage) Option {
+  return func(d *fetcherDeps) {
+    d.storage = s
+  }
+}
+
+// WithMediaServiceQueue use specified queue instead of connecting to rabbitmq from config
+func WithMediaServiceQueue(q queue.MediaServiceQueue) Option {
+  return func(d *fetcherDeps) {
+    d.msQueue = q
+  }
 }
 
-func NewFetcher(ctx context.Context, config *Config) (fetcher.Fetcher, error) {
-  client := httpclient.NewClient(
+// WithEventsPublisher use specified events publisher instead of connecting to rabbitmq from config
+func WithEventsPublisher(p queue.EventsPublisher) Option {
+  return func(d *fetcherDeps) {
+    d.events = p
+  }
+}
+
+// WithShardCoordinator process only tickers of shards owned by this instance
+func WithShardCoordinator(c *shard.Coordinator) Option {
+  return func(d *fetcherDeps) {
+    d.shards = c
+  }
+}
+
+// WithHttpOptions append options for fetcher http client (e.g. replay transport)
+func WithHttpOptions(options ...httpclient.Options) Option {
+  return func(d *fetcherDeps) {
+    d.httpOptions = append(d.httpOptions, options...)
+  }
+}
+
+// WithClock use specified current time of trading sessions ranges instead of system time
+func WithClock(now func() time.Time) Option {
+  return func(d *fetcherDeps) {
+    d.now = now
+  }
+}
+
+func NewFetcher(ctx context.Context, config *Config, options ...Option) (fetcher.Fetcher, error) {
+  deps := &fetcherDeps{now: time.Now}
+  for _, opt := range options {
+    opt(deps)
+  }
+
+  httpOptions := []httpclient.Options{
     httpclient.WithContext(ctx),
-    httpclient.WithApiToken(
-      apiTokenKey,
-      config.ApiToken,
-    ),
-    httpclient.WithRequestsLimit(
-      polygonReqsLimit,
-      polygonReqPerDur,
-      polygonWaitDur,
-      polygonDeadlineDur,
-    ))
-
-  fetcherState := newFetcherState(
-    config.ModeTotalHours,
-    config.ModeCurrentHours,
-  )
-
-  fetcherStorage, err := storage.NewStorage(ctx, config.StorageConfig)
-  if err != nil {
-    return nil, err
+    newApiTokenOption(config),
+    httpclient.WithRequestsLimit(config.requestsLimit()),
+    httpclient.WithRetries(config.retriesOption()),
+  }
+  client := httpclient.NewClient(append(httpOptions, deps.httpOptions...)...)
+
+  fetcherState := newFetcherState()
+
+  apiBaseURL := strings.TrimSuffix(strings.TrimSpace(config.ApiBaseUrl), "/")
+  if apiBaseURL == "" {
+    apiBaseURL = basePrefixApi
+  }
+
+  fetcherStorage := deps.storage
+  if fetcherStorage == nil {
+    var err error
+    if fetcherStorage, err = storage.NewStorage(ctx, config.StorageConfig); err != nil {
+      return nil, err
+    }
+  }
+  msQueue := deps.msQueue
+  if msQueue == nil {
+    var err error
+    if msQueue, err = queue.NewMediaServiceQueue(ctx, config.QueueConfig); err != nil {
+      return nil, err
+    }
+  }
+
+  // events are not published if events exchange is not configured
+  events := deps.events
+  if events == nil && config.QueueConfig != nil && config.QueueConfig.EventsExchange != "" {
+    var err error
+    if events, err = queue.NewEventsPublisher(ctx, config.QueueConfig); err != nil {
+      return nil, err
+    }
   }
-  msQueue, err := queue.NewMediaServiceQueue(ctx, config.QueueConfig)
+
+  calendars, err := calendar.Default()
   if err != nil {
-    return nil, err
+    return nil, fmt.Errorf("cannot load exchange calendars: %v", err)
   }
 
-  return &Fetcher{
-    ctx:     ctx,
-    client:  client,
-    storage: fetcherStorage,
-    msQueue: msQueue,
-    state:   fetcherState,
-    once:    &sync.Once{},
-  }, nil
+  var objectStore objectstore.Store
+  if objectStoreConfig := config.objectStoreConfig(); objectStoreConfig != nil {
+    var err error
+    if objectStore, err = objectstore.NewStore(objectStoreConfig); err != nil {
+      return nil, err
+    }
+  }
+
+  f := &Fetcher{
+    ctx:         ctx,
+    client:      client,
+    storage:     fetcherStorage,
+    msQueue:     msQueue,
+    events:      events,
+    relay:       outbox.NewRelay(ctx, fetcherStorage, msQueue),
+    objectStore: objectStore,
+    shards:      deps.shards,
+    calendars:   calendars,
+    state:       fetcherState,
+    watchlist:   newWatchlist(),
+    control:     newControl(),
+    apiBaseURL:  apiBaseURL,
+    config:      config,
+    now:         deps.now,
+  }
+  if f.shards != nil {
+    f.shards.OnAcquire(f.handOverShards)
+  }
+  return f, nil
+}
+
+// logger return log entry with log fields of context
+func (f *Fetcher) logger(ctx context.Context) *logrus.Entry {
+  return logging.FromContext(ctx, log)
+}
+
+// startCycle return context of fetching cycle or one-off fetching with new correlation id
+// and started cycle span. span must be ended by caller
+//   job name of scheduled job or one-off operation
+func (f *Fetcher) startCycle(ctx context.Context, job string) (context.Context, trace.Span) {
+  ctx = logging.WithFields(logging.WithCorrelationId(ctx), logrus.Fields{
+    logging.FieldFetcher: FetcherName,
+    logging.FieldJob:     job,
+  })
+
+  return tracer.Start(ctx, "cycle "+job, trace.WithAttributes(
+    attribute.String(logging.FieldFetcher, FetcherName),
+    attribute.String(logging.FieldJob, job),
+    attribute.String(logging.FieldCorrelationId, logging.CorrelationId(ctx)),
+  ))
+}
+
+func withTicker(ctx context.Context, tickerId string) context.Context {
+  return logging.WithField(ctx, logging.FieldTicker, tickerId)
 }
 
-func (f *Fetcher) getTickersResponse(query string) (*tickersResponse, error) {
-  reqURL := getReqUrlFromStateOrNew(f.state.ticker,
-    func() string {
-      return fmt.Sprint(basePrefixApi, tickersApi, "?", query)
-    })
+func newApiTokenOption(config *Config) httpclient.Options {
+  // api auth mode validated by config `oneof` rule
+  if config.ApiAuthMode == apiAuthModeHeader {
+    return httpclient.WithBearerToken(config.ApiToken)
+  }
+  return httpclient.WithApiToken(apiTokenKey, config.ApiToken)
+}
 
-  resp, err := f.client.Get(reqURL)
+func (f *Fetcher) getTickersResponse(ctx context.Context, reqURL string) (*tickersResponse, error) {
+  resp, err := f.client.GetContext(ctx, reqURL)
   if err != nil {
     return nil, fmt.Errorf("cannot get response: %v", err)
   }
@@ -84,15 +236,28 @@ func (f *Fetcher) getTickersResponse(query string) (*tickersResponse, error) {
   return tickersResp, nil
 }
 
-func buildTickersQuery() url.Values {
+func buildTickersQuery(filter TickersFilter) url.Values {
   query := url.Values{}
-  query.Add("active", "true")
-  query.Add("order", "asc")
+  for key, value := range filter {
+    query.Set(key, value)
+  }
+  query.Set("active", "true")
+  query.Set("order", "asc")
+  if query.Get(limitQueryKey) == "" {
+    query.Set(limitQueryKey, tickersPageLimit)
+  }
   return query
 }
 
-func (f *Fetcher) fetchTickerDetails(tickerId string) (*domain.TickerDetails, error) {
-  resp, err := f.getTickerDetailsResponse(tickerId)
+type tickerDetailsWithBranding struct {
+  details  *domain.TickerDetails
+  messages []*domain.PutMessage
+  hashes   []*domain.BrandingHash
+}
+
+// fetchTickerDetails return ticker details with messages to put changed ticker branding
+func (f *Fetcher) fetchTickerDetails(ctx context.Context, tickerId string) (*tickerDetailsWithBranding, error) {
+  resp, err := f.getTickerDetailsResponse(ctx, tickerId)
   if err != nil {
     return nil, fmt.Errorf("cannot get ticker details response: %v", err)
   }
@@ -103,25 +268,27 @@ func (f *Fetcher) fetchTickerDetails(tickerId string) (*domain.TickerDetails, er
     return nil, fmt.Errorf("ticker details results not found")
   }
 
-  if err = f.sendMessagesToPutTickerBranding(tickerId, resp.Results.Branding); err != nil {
-    log.Errorf("cannot send messages to put branding for ticker '%s': %v", tickerId, err)
+  messages, hashes, err := f.formMsgsToPutTickerBranding(ctx, tickerId, resp.Results.Branding)
+  if err != nil {
+    f.logger(ctx).Errorf("cannot form messages to put branding for ticker '%s': %v", tickerId, err)
   }
   details, err := createTickerDetails(resp.Results)
   if err != nil {
     return nil, fmt.Errorf("cannot create ticker details: %v", err)
   }
 
-  return details, nil
+  return &tickerDetailsWithBranding{
+    details:  details,
+    messages: messages,
+    hashes:   hashes,
+  }, nil
 }
 
-func (f *Fetcher) getTickerDetailsResponse(tickerId string) (*tickerDetailsResponse, error) {
-  reqURL := getReqUrlFromStateOrNew(f.state.tickerDetails,
-    func() string {
-      tickerDetailsQuery := fmt.Sprintf(tickerDetailsApi, tickerId)
-      return fmt.Sprint(basePrefixApi, tickerDetailsQuery)
-    })
+func (f *Fetcher) getTickerDetailsResponse(ctx context.Context, tickerId string) (*tickerDetailsResponse, error) {
+  tickerDetailsQuery := fmt.Sprintf(tickerDetailsApi, tickerId)
+  reqURL := fmt.Sprint(f.apiBaseURL, tickerDetailsQuery)
 
-  resp, err := f.client.Get(reqURL)
+  resp, err := f.client.GetContext(ctx, reqURL)
   if err != nil {
     return nil, fmt.Errorf("cannot get response: %v", err)
   }
@@ -133,96 +300,256 @@ func (f *Fetcher) getTickerDetailsResponse(tickerId string) (*tickerDetailsRespo
   return tickerDetailsResp, nil
 }
 
-func (f *Fetcher) fetchTickers() error {
-  query := buildTickersQuery()
-  queryStr := query.Encode()
+// fetchTickers fetch tickers pages with their details and stocks. fetching starts from
+// the page and ticker saved in state, progress is checkpointed after each processed ticker
+func (f *Fetcher) fetchTickers(ctx context.Context) error {
+  query := buildTickersQuery(f.getConfig().TickersFilter)
+  cursor, lastTickerId := f.state.resumePoint()
+
+  // progress saved with other tickers filter does not match pages of current one
+  if (cursor != "" || lastTickerId != "") && !isPageOfQuery(f.state.getPageURL(), query) {
+    f.logger(ctx).Warnf("tickers filter changed since cursor '%s' was saved. start from the first page", cursor)
+    f.state.ResetCursor()
+    cursor, lastTickerId = "", ""
+  }
 
+  if cursor != "" || lastTickerId != "" {
+    f.logger(ctx).Infof("resume tickers fetching from cursor '%s' after ticker '%s'", cursor, lastTickerId)
+  } else {
+    f.state.StartCycle()
+  }
   for {
-    tickersResp, err := f.getTickersResponse(queryStr)
+    if cursor != "" {
+      query.Set(respCursorKey, cursor)
+    }
+    reqURL := fmt.Sprint(f.apiBaseURL, tickersApi, "?", query.Encode())
+    f.state.SetPage(cursor, reqURL)
+
+    nextURL, err := f.fetchTickersPage(ctx, cursor, reqURL, lastTickerId)
     if err != nil {
       return err
     }
-    respStatus := tickersResp.Status
-    cursorURL := tickersResp.NextUrl
+    lastTickerId = ""
 
-    if respStatus != respStatusOK {
-      return fmt.Errorf("bad response status: %s", respStatus)
-    }
-    if tickersResp.Count == 0 || cursorURL == "" {
+    if cursor = cursorFromURL(nextURL); cursor == "" {
       break
     }
-    for _, tickerRespResult := range tickersResp.Results {
-      if tickerRespResult == nil {
-        continue
-      }
-      ticker, err := createTicker(tickerRespResult)
-      if err != nil {
-        return fmt.Errorf("cannot create ticker: %v", err)
-      }
-      if err = f.storage.PutTicker(ticker); err != nil {
-        return fmt.Errorf("cannot put ticker to storage: %v", err)
-      }
+    // the next page starts with empty progress
+    f.state.SetPage(cursor, nextURL)
+    f.checkpoint(true)
+  }
+  return f.catchUpHandedOverShards(ctx)
+}
 
-      tickerDetails, err := f.fetchTickerDetails(ticker.TickerId)
-      if err != nil {
-        return fmt.Errorf("cannot fetch ticker details for ticker %s: %v", ticker.TickerId, err)
-      }
-      if err = f.storage.PutTickerDetails(tickerDetails); err != nil {
-        return fmt.Errorf("cannot put ticker details to storage")
-      }
+// isPageOfQuery return true if tickers page url has the same params as query except cursor.
+// page url without params, e.g. saved by older versions, matches any query
+func isPageOfQuery(pageURL string, query url.Values) bool {
+  parsedURL, err := url.Parse(pageURL)
+  if err != nil || parsedURL.RawQuery == "" {
+    return true
+  }
+  pageQuery := parsedURL.Query()
+  pageQuery.Del(respCursorKey)
+
+  expected := url.Values{}
+  for key, values := range query {
+    expected[key] = values
+  }
+  expected.Del(respCursorKey)
+  return pageQuery.Encode() == expected.Encode()
+}
+
+// fetchTickersPage process owned tickers of page after the last processed ticker
+// and return url of the next page
+func (f *Fetcher) fetchTickersPage(ctx context.Context, cursor, reqURL, lastTickerId string) (nextURL string, err error) {
+  ctx, span := startPageSpan(ctx, cursor)
+  defer func() { tracing.End(span, err) }()
 
-      if err = f.fetchStocks(ticker.TickerId); err != nil {
-        return fmt.Errorf("cannot fetch stocks for ticker %s: %v", ticker.TickerId, err)
+  tickersResp, err := f.getTickersResponse(ctx, reqURL)
+  if err != nil {
+    return "", err
+  }
+  if tickersResp.Status != respStatusOK {
+    return "", fmt.Errorf("bad response status: %s", tickersResp.Status)
+  }
+  span.SetAttributes(attribute.Int("page.tickers", len(tickersResp.Results)))
+
+  for _, tickerRespResult := range skipProcessedTickers(tickersResp.Results, lastTickerId) {
+    if err = f.waitIfPaused(); err != nil {
+      return "", err
+    }
+    if f.ownsTicker(tickerRespResult.Ticker) {
+      if err = f.processTicker(ctx, tickerRespResult); err != nil {
+        return "", err
       }
+      f.addShardProgress(tickerRespResult.Ticker)
     }
+    f.state.SetLastTickerId(tickerRespResult.Ticker)
+    f.checkpoint(false)
+  }
+  return tickersResp.NextUrl, nil
+}
 
-    cursor, err := url.Parse(cursorURL)
-    if err != nil {
-      return fmt.Errorf("cannot parse cursor URL: %s", cursorURL)
-    }
+// startPageSpan start span of tickers page. cursor is empty for the first page
+func startPageSpan(ctx context.Context, cursor string) (context.Context, trace.Span) {
+  return tracer.Start(ctx, "tickers page", trace.WithAttributes(attribute.String("page.cursor", cursor)))
+}
 
-    cursorValue := cursor.Query().Get(respCursorKey)
-    if cursorValue == "" {
-      break
+// ownsTicker return true if tickers are not sharded or ticker shard is owned by this instance
+func (f *Fetcher) ownsTicker(tickerId string) bool {
+  return f.shards == nil || f.shards.Owns(tickerId)
+}
+
+func (f *Fetcher) addShardProgress(tickerId string) {
+  if f.shards == nil {
+    return
+  }
+  f.state.AddShardProgress(FetcherName, f.shards.MemberId(), f.shards.ShardOf(tickerId), tickerId)
+}
+
+// skipProcessedTickers return tickers of page after the last processed one.
+// all tickers are returned if the last processed ticker is not in page
+func skipProcessedTickers(results []*tickerResult, lastTickerId string) []*tickerResult {
+  var filtered []*tickerResult
+  for _, result := range results {
+    if result != nil {
+      filtered = append(filtered, result)
+    }
+  }
+  if lastTickerId == "" {
+    return filtered
+  }
+  for idx, result := range filtered {
+    if result.Ticker == lastTickerId {
+      return filtered[idx+1:]
     }
+  }
+  log.Warnf("last processed ticker '%s' not found in page. process the whole page", lastTickerId)
+  return filtered
+}
+
+// processTicker put ticker, its details and stocks to storage
+func (f *Fetcher) processTicker(ctx context.Context, tickerRespResult *tickerResult) (err error) {
+  ctx, span := tracer.Start(ctx, "ticker", trace.WithAttributes(
+    attribute.String(logging.FieldTicker, tickerRespResult.Ticker),
+  ))
+  defer func() { tracing.End(span, err) }()
 
-    query.Add(respCursorKey, cursorValue)
+  ticker, err := createTicker(tickerRespResult)
+  if err != nil {
+    return fmt.Errorf("cannot create ticker: %v", err)
+  }
+  ctx = withTicker(ctx, ticker.TickerId)
+  tickerStorage := f.storage.WithContext(ctx)
+
+  if err = tickerStorage.PutTicker(ticker); err != nil {
+    return fmt.Errorf("cannot put ticker to storage: %v", err)
+  }
+
+  tickerDetails, err := f.fetchTickerDetails(ctx, ticker.TickerId)
+  if err != nil {
+    return fmt.Errorf("cannot fetch ticker details for ticker %s: %v", ticker.TickerId, err)
+  }
+  if err = tickerStorage.PutTickerDetailsWithMessages(
+    tickerDetails.details,
+    tickerDetails.messages,
+    tickerDetails.hashes,
+  ); err != nil {
+    return fmt.Errorf("cannot put ticker details to storage: %v", err)
+  }
+
+  if err = f.fetchStocks(ctx, ticker.TickerId, false); err != nil {
+    return fmt.Errorf("cannot fetch stocks for ticker %s: %v", ticker.TickerId, err)
   }
   return nil
 }
 
-func (f *Fetcher) getStockDateRange() (string, string) {
-  nowT := time.Now()
-  fromT := nowT
-  toT := nowT
-  sub := f.state.modeCurrentHours
+// getStockDateRange return trading sessions of the next aggregates request for ticker.
+// range starts from the ticker watermark (the day of the last stored bar, so bars of
+// this day are updated) but not earlier than fetcher mode window. watermark day is skipped
+// if its bar was stored after session close. session which is not closed yet is skipped
+// unless withOpenSession is set (intraday job), its partial bar stored before the close
+// keeps watermark day, so the bar is fetched again after the close. no sessions are
+// returned if range has no trading days
+func (f *Fetcher) getStockDateRange(
+  ctx context.Context, tickerId string, withOpenSession bool,
+) ([]*calendar.Session, error) {
+  nowT := f.now().UTC()
+  config := f.getConfig()
+  sub := config.ModeCurrentHours
+
+  if f.state.getModeCode() == fetcherModeTotal {
+    sub = config.ModeTotalHours
+  }
+  fromT := nowT.Add(-time.Duration(sub) * time.Hour)
+
+  syncState, found, err := f.storage.WithContext(ctx).GetTickerSyncState(tickerId, StocksInterval)
+  if err != nil {
+    return nil, fmt.Errorf("cannot get sync state of ticker '%s': %v", tickerId, err)
+  }
+  stocksCalendar := f.stocksCalendar()
 
-  if f.state.modeCode == fetcherModeTotal {
-    sub = f.state.modeTotalHours
+  if found && syncState.LastBarAt.After(fromT) {
+    fromT = syncState.LastBarAt
+
+    session, ok := stocksCalendar.Session(fromT)
+    if ok && syncState.UpdatedAt.After(session.Close) {
+      fromT = session.Date.AddDate(0, 0, 1)
+    }
   }
-  dur := time.Duration(sub) * time.Hour
-  from := fromT.Add(-dur).Format("2006-01-02")
-  to := toT.Format("2006-01-02")
+  sessions := stocksCalendar.Sessions(fromT, nowT)
+  if withOpenSession {
+    return sessions, nil
+  }
+  return closedSessions(sessions, nowT), nil
+}
 
-  return from, to
+// closedSessions return sessions closed before now
+func closedSessions(sessions []*calendar.Session, now time.Time) []*calendar.Session {
+  var closed []*calendar.Session
+  for _, session := range sessions {
+    if session.Close.Before(now) {
+      closed = append(closed, session)
+    }
+  }
+  return closed
 }
 
-func buildStocksReqURL(tickerName, fromDate, toDate string) string {
-  multiplier := 1
-  timespan := "day"
-  rangeQuery := fmt.Sprintf(stocksApi, tickerName, multiplier, timespan, fromDate, toDate)
-  reqURL := fmt.Sprint(basePrefixApi, rangeQuery)
+func buildStocksReqURL(apiBaseURL, tickerName, fromDate, toDate string) string {
+  rangeQuery := fmt.Sprintf(stocksApi, tickerName, stocksMultiplier, stocksTimespan, fromDate, toDate)
+  reqURL := fmt.Sprint(apiBaseURL, rangeQuery)
   return reqURL
 }
 
-func (f *Fetcher) fetchStocks(tickerId string) error {
-  reqURL := getReqUrlFromStateOrNew(f.state.stocks,
-    func() string {
-      fromDate, toDate := f.getStockDateRange()
-      return buildStocksReqURL(tickerId, fromDate, toDate)
-    })
+func (f *Fetcher) fetchStocks(ctx context.Context, tickerId string, withOpenSession bool) error {
+  sessions, err := f.getStockDateRange(ctx, tickerId, withOpenSession)
+  if err != nil {
+    return err
+  }
+  if len(sessions) == 0 {
+    f.logger(ctx).Debugf("no trading sessions since the last bar of ticker '%s'. skip stocks request", tickerId)
+    return nil
+  }
+  return f.fetchStocksOfSessions(ctx, tickerId, sessions)
+}
+
+// fetchStocksOfSessions fetch stocks of ticker for range from the first to the last session
+func (f *Fetcher) fetchStocksOfSessions(ctx context.Context, tickerId string, sessions []*calendar.Session) (err error) {
+  fromDate := sessions[0].Date.Format(dateLayout)
+  toDate := sessions[len(sessions)-1].Date.Format(dateLayout)
 
-  resp, err := f.client.Get(reqURL)
+  ctx, span := tracer.Start(ctx, "stocks", trace.WithAttributes(
+    attribute.String(logging.FieldTicker, tickerId),
+    attribute.String("stocks.from", fromDate),
+    attribute.String("stocks.to", toDate),
+  ))
+  defer func() { tracing.End(span, err) }()
+
+  ctx = withTicker(ctx, tickerId)
+  tickerStorage := f.storage.WithContext(ctx)
+  reqURL := buildStocksReqURL(f.apiBaseURL, tickerId, fromDate, toDate)
+
+  resp, err := f.client.GetContext(ctx, reqURL)
   if err != nil {
     return fmt.Errorf("cannot get response")
   }
@@ -233,23 +560,101 @@ func (f *Fetcher) fetchStocks(tickerId string) error {
   }
 
   if stockResp.QueryCount == 0 {
-    log.Warnf("stock prices not found for ticker: %s", tickerId)
+    f.logger(ctx).Warnf("stock prices not found for ticker '%s' from %s to %s", tickerId, fromDate, toDate)
     return nil
   }
 
+  var stocks []*domain.Stock
   for _, stockRes := range stockResp.StockResults {
     stock, err := createStock(tickerId, stockRes)
     if err != nil {
       return fmt.Errorf("cannot create stock: %v", err)
     }
-    if err = f.storage.PutStock(stock); err != nil {
+    if stock != nil {
+      stocks = append(stocks, stock)
+    }
+  }
+  // the last stored bar is reference of price jump of the first bar
+  lastStored, _, err := tickerStorage.GetLastStock(tickerId, sessions[0].Date)
+  if err != nil {
+    return fmt.Errorf("cannot get the last stock of ticker '%s': %v", tickerId, err)
+  }
+  validation := validateStocks(stocks, sessions, f.getConfig().maxPriceJump(), lastStored)
+  if err = f.quarantineStocks(ctx, tickerStorage, validation.anomalies); err != nil {
+    return err
+  }
+  update := &domain.TickerUpdate{
+    TickerId: tickerId,
+    Interval: StocksInterval,
+  }
+  for _, stock := range validation.valid {
+    stored, err := tickerStorage.PutStock(stock)
+    if err != nil {
       return fmt.Errorf("cannot put stock to storage: %v", err)
     }
+    if stored {
+      f.publishEvent(ctx, domain.NewStockIngestedEvent(StocksInterval, stock))
+      addToTickerUpdate(update, stock)
+    }
+  }
+  if update.IngestedCount != 0 {
+    f.publishEvent(ctx, domain.NewTickerUpdatedEvent(update))
+  }
+  f.detectGaps(ctx, tickerId, sessions, stockResp.StockResults)
+
+  if validation.lastBarAt.IsZero() {
+    return nil
+  }
+  // watermark moves only after all bars of response are stored or quarantined.
+  // quarantined bars are not requested again, they are fixed by backfill
+  if err = tickerStorage.PutTickerSyncState(&domain.TickerSyncState{
+    TickerId:  tickerId,
+    Interval:  StocksInterval,
+    LastBarAt: validation.lastBarAt,
+    UpdatedAt: timeutils.NotTimeUTC(),
+  }); err != nil {
+    return fmt.Errorf("cannot put sync state of ticker '%s': %v", tickerId, err)
   }
+  return nil
+}
+
+// quarantineStocks put bars which failed validation to anomalies instead of stocks
+func (f *Fetcher) quarantineStocks(ctx context.Context, tickerStorage storage.Storage, anomalies []*domain.StockAnomaly) error {
+  if len(anomalies) == 0 {
+    return nil
+  }
+  trace.SpanFromContext(ctx).SetAttributes(attribute.Int("stocks.anomalies", len(anomalies)))
 
+  for _, anomaly := range anomalies {
+    if err := tickerStorage.PutStockAnomaly(anomaly); err != nil {
+      return fmt.Errorf("cannot put stock anomaly to storage: %v", err)
+    }
+    f.logger(ctx).Warnf("stock '%s' quarantined: %s", anomaly.Stock.StockId, anomaly.Reason)
+  }
   return nil
 }
 
+func addToTickerUpdate(update *domain.TickerUpdate, stock *domain.Stock) {
+  if update.IngestedCount == 0 || stock.StockedAt.Before(update.FirstBarAt) {
+    update.FirstBarAt = stock.StockedAt
+  }
+  if stock.StockedAt.After(update.LastBarAt) {
+    update.LastBarAt = stock.StockedAt
+  }
+  update.IngestedCount++
+}
+
+// publishEvent queue event for publishing if events publisher configured. publisher does not
+// wait for broker confirm. events are notifications, publishing errors do not stop fetching
+func (f *Fetcher) publishEvent(ctx context.Context, event *domain.Event) {
+  if f.events == nil {
+    return
+  }
+  if err := f.events.PublishEvent(ctx, event); err != nil {
+    f.logger(ctx).Errorf("cannot publish event '%s' for ticker '%s': %v", event.Type, event.TickerId, err)
+  }
+}
+
 func createTicker(res *tickerResult) (*domain.Ticker, error) {
   if res == nil {
     return nil, nil
@@ -315,19 +720,3 @@ func createStock(tickerId string, res *stockResult) (*domain.Stock, error) {
   }
   return stock, nil
 }
-
-func getReqUrlFromStateOrNew(stateReq *stateReq, newReqURL func() string) string {
-  var reqURL string
-  // if request URL not used and set in state
-  if !stateReq.used && stateReq.reqURL != "" {
-    reqURL = stateReq.reqURL
-    // use it once
-    stateReq.used = true
-  } else {
-    // else form new request URL
-    reqURL = newReqURL()
-    // save it in state
-    stateReq.reqURL = reqURL
-  }
-  return reqURL
-}
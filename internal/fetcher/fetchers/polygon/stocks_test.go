@@ -0,0 +1,248 @@
+package polygon
+
+import (
+  "context"
+  "net/url"
+  "path/filepath"
+  "scientific-research/internal/calendar"
+  "scientific-research/internal/fetcher/fetchers/polygon/polygontest"
+  "scientific-research/internal/httpclient"
+  "scientific-research/internal/httpclient/replay"
+  "strings"
+  "testing"
+  "time"
+)
+
+const testApiToken = "test-token"
+
+// testNow friday evening after the session close
+var testNow = time.Date(2026, 3, 6, 22, 0, 0, 0, time.UTC)
+
+var testTickers = []string{"AAA", "BBB", "CCC", "DDD", "EEE"}
+
+// newTestFetcher create fetcher of fake Polygon server with in-memory storage and fixed clock
+func newTestFetcher(t *testing.T, baseURL string, s *memStorage, options ...Option) *Fetcher {
+  t.Helper()
+
+  config := &Config{
+    ApiToken:         testApiToken,
+    ApiBaseUrl:       baseURL,
+    ModeTotalHours:   14 * 24,
+    ModeCurrentHours: 24,
+    LimitsConfig:     &LimitsConfig{RequestsCount: 1000, RequestsPer: time.Second},
+    RetriesConfig:    &RetriesConfig{RetryCount: 1, WaitInterval: time.Millisecond},
+    CalendarConfig:   &CalendarConfig{DisableRefresh: true},
+  }
+  options = append([]Option{
+    WithStorage(s),
+    WithMediaServiceQueue(memQueue{}),
+    WithClock(func() time.Time { return testNow }),
+  }, options...)
+
+  f, err := NewFetcher(context.Background(), config, options...)
+  if err != nil {
+    t.Fatalf("cannot create fetcher: %v", err)
+  }
+  return f.(*Fetcher)
+}
+
+// newTestServer start fake Polygon server with tickers and their bars of the last closed sessions
+func newTestServer(t *testing.T, sessions []*calendar.Session, tickerIds ...string) *polygontest.Server {
+  t.Helper()
+
+  srv := polygontest.NewServer(polygontest.WithApiToken(testApiToken))
+  t.Cleanup(srv.Close)
+
+  for _, tickerId := range tickerIds {
+    srv.AddTicker(&polygontest.Ticker{
+      Ticker: tickerId,
+      Name:   tickerId + " Inc.",
+      Market: "stocks",
+      Locale: "us",
+      Type:   "CS",
+    })
+    for idx, session := range sessions {
+      price := float64(100 + idx)
+      srv.AddBars(tickerId, &polygontest.Bar{
+        Open:      price,
+        Close:     price + 0.5,
+        High:      price + 1,
+        Low:       price - 1,
+        Volume:    1000,
+        Timestamp: session.Date,
+      })
+    }
+  }
+  return srv
+}
+
+// lastClosedSessions return the last count sessions of stocks exchange closed before testNow
+func lastClosedSessions(t *testing.T, count int) []*calendar.Session {
+  t.Helper()
+
+  registry, err := calendar.Default()
+  if err != nil {
+    t.Fatalf("cannot load calendars: %v", err)
+  }
+  stocksCalendar, err := registry.Get(defaultCalendarExchange)
+  if err != nil {
+    t.Fatalf("cannot get calendar: %v", err)
+  }
+  sessions := closedSessions(stocksCalendar.Sessions(testNow.AddDate(0, 0, -10), testNow), testNow)
+  if len(sessions) < count {
+    t.Fatalf("got %d closed sessions, want at least %d", len(sessions), count)
+  }
+  return sessions[len(sessions)-count:]
+}
+
+// tickersPageCursors return cursors of served tickers pages, empty for the first page
+func tickersPageCursors(requests []string) []string {
+  var cursors []string
+  for _, request := range requests {
+    path, rawQuery, _ := strings.Cut(request, "?")
+    if path != tickersApi {
+      continue
+    }
+    query, _ := url.ParseQuery(rawQuery)
+    cursors = append(cursors, query.Get(respCursorKey))
+  }
+  return cursors
+}
+
+// detailsRequests return tickers of served ticker details requests
+func detailsRequests(requests []string) []string {
+  var tickerIds []string
+  for _, request := range requests {
+    if tickerId := strings.TrimPrefix(request, tickersApi+"/"); tickerId != request {
+      tickerIds = append(tickerIds, tickerId)
+    }
+  }
+  return tickerIds
+}
+
+func equalStrings(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for idx := range a {
+    if a[idx] != b[idx] {
+      return false
+    }
+  }
+  return true
+}
+
+func TestFetchTickersPaginates(t *testing.T) {
+  sessions := lastClosedSessions(t, 3)
+  srv := newTestServer(t, sessions, testTickers...)
+  s := newMemStorage()
+  f := newTestFetcher(t, srv.URL, s)
+
+  if err := f.fetchTickers(context.Background()); err != nil {
+    t.Fatalf("cannot fetch tickers: %v", err)
+  }
+  if cursors := tickersPageCursors(srv.Requests()); !equalStrings(cursors, []string{"", "2", "4"}) {
+    t.Errorf("requested tickers pages %q, want pages by cursors of next urls", cursors)
+  }
+  tickerIds, _ := s.GetTickerIds(true)
+  if !equalStrings(tickerIds, testTickers) {
+    t.Errorf("stored tickers %v, want %v", tickerIds, testTickers)
+  }
+  for _, tickerId := range testTickers {
+    if s.details[tickerId] == nil {
+      t.Errorf("details of ticker %s are not stored", tickerId)
+    }
+    stocks := s.tickerStocks(tickerId)
+    if len(stocks) != len(sessions) {
+      t.Fatalf("stored %d stocks of ticker %s, want %d", len(stocks), tickerId, len(sessions))
+    }
+    syncState, found, _ := s.GetTickerSyncState(tickerId, StocksInterval)
+    if lastBarAt := stocks[len(stocks)-1].StockedAt; !found || !syncState.LastBarAt.Equal(lastBarAt) {
+      t.Errorf("watermark of ticker %s is %v, want the last bar %s", tickerId, syncState, lastBarAt)
+    }
+  }
+  // icon and logo of every ticker
+  if len(s.messages) != 2*len(testTickers) {
+    t.Errorf("put %d branding messages to outbox, want %d", len(s.messages), 2*len(testTickers))
+  }
+}
+
+func TestFetchTickersPaginatesFilteredList(t *testing.T) {
+  srv := newTestServer(t, lastClosedSessions(t, 1), testTickers...)
+  srv.AddTicker(&polygontest.Ticker{Ticker: "BBBW", Market: "stocks", Type: "WARRANT"})
+  srv.AddTicker(&polygontest.Ticker{Ticker: "CCCW", Market: "stocks", Type: "WARRANT"})
+  s := newMemStorage()
+  f := newTestFetcher(t, srv.URL, s)
+  f.config.TickersFilter = TickersFilter{"type": "CS"}
+
+  if err := f.fetchTickers(context.Background()); err != nil {
+    t.Fatalf("cannot fetch tickers: %v", err)
+  }
+  // warrants between common stocks do not shift pages of filtered list
+  if cursors := tickersPageCursors(srv.Requests()); !equalStrings(cursors, []string{"", "2", "4"}) {
+    t.Errorf("requested tickers pages %q, want pages of filtered list", cursors)
+  }
+  if tickerIds, _ := s.GetTickerIds(true); !equalStrings(tickerIds, testTickers) {
+    t.Errorf("stored tickers %v, want %v", tickerIds, testTickers)
+  }
+}
+
+func TestFetchStocksSkipsSyncedTicker(t *testing.T) {
+  sessions := lastClosedSessions(t, 3)
+  srv := newTestServer(t, sessions, "AAPL")
+  s := newMemStorage()
+  f := newTestFetcher(t, srv.URL, s)
+
+  if err := f.fetchStocks(context.Background(), "AAPL", false); err != nil {
+    t.Fatalf("cannot fetch stocks: %v", err)
+  }
+  served := len(srv.Requests())
+
+  // all bars were stored after session close, nothing to request
+  if err := f.fetchStocks(context.Background(), "AAPL", false); err != nil {
+    t.Fatalf("cannot fetch stocks: %v", err)
+  }
+  if requests := srv.Requests()[served:]; len(requests) != 0 {
+    t.Errorf("synced ticker requested again: %v", requests)
+  }
+}
+
+func TestFetchStocksFromReplayedFixture(t *testing.T) {
+  sessions := lastClosedSessions(t, 3)
+  srv := newTestServer(t, sessions, "AAPL")
+  cassettePath := filepath.Join(t.TempDir(), "aggs.json")
+
+  recorder := replay.NewRecorder(cassettePath, nil, apiTokenKey)
+  recorded := newMemStorage()
+  f := newTestFetcher(t, srv.URL, recorded, WithHttpOptions(httpclient.WithTransport(recorder)))
+
+  if err := f.fetchStocks(context.Background(), "AAPL", false); err != nil {
+    t.Fatalf("cannot fetch stocks: %v", err)
+  }
+  if err := recorder.Save(); err != nil {
+    t.Fatalf("cannot save cassette: %v", err)
+  }
+  srv.Close()
+
+  replayer, err := replay.NewReplayer(cassettePath, apiTokenKey)
+  if err != nil {
+    t.Fatalf("cannot load cassette: %v", err)
+  }
+  replayed := newMemStorage()
+  f = newTestFetcher(t, srv.URL, replayed, WithHttpOptions(httpclient.WithTransport(replayer)))
+
+  if err = f.fetchStocks(context.Background(), "AAPL", false); err != nil {
+    t.Fatalf("cannot fetch stocks from cassette: %v", err)
+  }
+  want := recorded.tickerStocks("AAPL")
+  got := replayed.tickerStocks("AAPL")
+  if len(got) != len(sessions) || len(got) != len(want) {
+    t.Fatalf("stored %d stocks from cassette, want %d", len(got), len(want))
+  }
+  for idx := range got {
+    if got[idx].StockId != want[idx].StockId || !got[idx].ClosePrice.Equal(want[idx].ClosePrice) {
+      t.Errorf("stock %d from cassette is %s %s, want %s %s", idx,
+        got[idx].StockId, got[idx].ClosePrice, want[idx].StockId, want[idx].ClosePrice)
+    }
+  }
+}
@@ -0,0 +1,169 @@
+package polygon
+
+import (
+  "context"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/storage"
+  "sort"
+  "sync"
+  "time"
+)
+
+// memStorage in-memory storage of fetcher tests. methods which are not used by
+// tested code panic on nil embedded storage
+type memStorage struct {
+  storage.Storage
+
+  mu           sync.Mutex
+  tickers      []string
+  details      map[string]*domain.TickerDetails
+  messages     []*domain.PutMessage
+  stocks       map[string]*domain.Stock
+  anomalies    []*domain.StockAnomaly
+  syncStates   map[string]*domain.TickerSyncState
+  fetcherState *domain.FetcherState
+}
+
+func newMemStorage() *memStorage {
+  return &memStorage{
+    details:    map[string]*domain.TickerDetails{},
+    stocks:     map[string]*domain.Stock{},
+    syncStates: map[string]*domain.TickerSyncState{},
+  }
+}
+
+func (s *memStorage) WithContext(ctx context.Context) storage.Storage {
+  return s
+}
+
+func (s *memStorage) PutTicker(ticker *domain.Ticker) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  for _, tickerId := range s.tickers {
+    if tickerId == ticker.TickerId {
+      return nil
+    }
+  }
+  s.tickers = append(s.tickers, ticker.TickerId)
+  return nil
+}
+
+func (s *memStorage) PutTickerDetailsWithMessages(
+  details *domain.TickerDetails,
+  messages []*domain.PutMessage,
+  hashes []*domain.BrandingHash,
+) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.details[details.TickerId] = details
+  s.messages = append(s.messages, messages...)
+  return nil
+}
+
+func (s *memStorage) GetBrandingHash(tickerId, brandingType string) (*domain.BrandingHash, bool, error) {
+  return nil, false, nil
+}
+
+func (s *memStorage) PutStock(stock *domain.Stock) (bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  stored, found := s.stocks[stock.StockId]
+  if found && stored.OpenPrice.Equal(stock.OpenPrice) && stored.ClosePrice.Equal(stock.ClosePrice) &&
+    stored.HighestPrice.Equal(stock.HighestPrice) && stored.LowestPrice.Equal(stock.LowestPrice) &&
+    stored.TradingVolume.Equal(stock.TradingVolume) {
+    return false, nil
+  }
+  s.stocks[stock.StockId] = stock
+  return true, nil
+}
+
+func (s *memStorage) PutStockAnomaly(anomaly *domain.StockAnomaly) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.anomalies = append(s.anomalies, anomaly)
+  return nil
+}
+
+func (s *memStorage) GetLastStock(tickerId string, before time.Time) (*domain.Stock, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var last *domain.Stock
+  for _, stock := range s.stocks {
+    if stock.TickerId != tickerId || !stock.StockedAt.Before(before) {
+      continue
+    }
+    if last == nil || stock.StockedAt.After(last.StockedAt) {
+      last = stock
+    }
+  }
+  return last, last != nil, nil
+}
+
+func (s *memStorage) GetTickerSyncState(tickerId, interval string) (*domain.TickerSyncState, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  state, found := s.syncStates[tickerId+interval]
+  return state, found, nil
+}
+
+func (s *memStorage) PutTickerSyncState(state *domain.TickerSyncState) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.syncStates[state.TickerId+state.Interval] = state
+  return nil
+}
+
+func (s *memStorage) PutFetcherState(state *domain.FetcherState) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.fetcherState = state
+  return nil
+}
+
+func (s *memStorage) GetFetcherState(memberId string) (*domain.FetcherState, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.fetcherState, s.fetcherState != nil, nil
+}
+
+func (s *memStorage) GetTickerIds(activeOnly bool) ([]string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  tickerIds := append([]string(nil), s.tickers...)
+  sort.Strings(tickerIds)
+  return tickerIds, nil
+}
+
+// tickerStocks return stored stocks of ticker ordered by time
+func (s *memStorage) tickerStocks(tickerId string) []*domain.Stock {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var stocks []*domain.Stock
+  for _, stock := range s.stocks {
+    if stock.TickerId == tickerId {
+      stocks = append(stocks, stock)
+    }
+  }
+  sort.Slice(stocks, func(i, j int) bool {
+    return stocks[i].StockedAt.Before(stocks[j].StockedAt)
+  })
+  return stocks
+}
+
+// memQueue media service queue which accepts all messages
+type memQueue struct{}
+
+func (memQueue) PublishMessage(ctx context.Context, message *domain.PutMessage) error {
+  return nil
+}
@@ -0,0 +1,349 @@
+package polygon
+
+import (
+  "bufio"
+  "context"
+  "errors"
+  "fmt"
+  "net/url"
+  "os"
+  "scientific-research/internal/scheduler"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
+  "sort"
+  "strings"
+  "sync"
+)
+
+// watchlistEntrySeparators separators of entries in flag value and file line
+const watchlistEntrySeparators = ", \t"
+
+// errTickerNotFound watchlist ticker is unknown or not active. it is skipped without
+// failing the job, retries cannot help until watchlist is fixed
+var errTickerNotFound = errors.New("active ticker not found")
+
+// watchlist tickers set at runtime in addition to config watchlist. entries of config
+// and watchlist file removed at runtime are kept in removed
+type watchlist struct {
+  mu      sync.Mutex
+  entries []string
+  removed map[string]bool
+}
+
+func newWatchlist() *watchlist {
+  return &watchlist{removed: map[string]bool{}}
+}
+
+func (w *watchlist) add(entries []string) {
+  w.mu.Lock()
+  defer w.mu.Unlock()
+
+  for _, entry := range entries {
+    entry = normalizeWatchlistEntry(entry)
+    delete(w.removed, entry)
+
+    if !containsString(w.entries, entry) {
+      w.entries = append(w.entries, entry)
+    }
+  }
+}
+
+func (w *watchlist) remove(entries []string) {
+  w.mu.Lock()
+  defer w.mu.Unlock()
+
+  for _, entry := range entries {
+    entry = normalizeWatchlistEntry(entry)
+    w.removed[entry] = true
+
+    for idx, added := range w.entries {
+      if added == entry {
+        w.entries = append(w.entries[:idx], w.entries[idx+1:]...)
+        break
+      }
+    }
+  }
+}
+
+// merge return entries of config without removed ones followed by runtime entries
+func (w *watchlist) merge(configEntries []string) []string {
+  w.mu.Lock()
+  defer w.mu.Unlock()
+
+  var merged []string
+  for _, entry := range configEntries {
+    entry = normalizeWatchlistEntry(entry)
+    if entry != "" && !w.removed[entry] && !containsString(merged, entry) {
+      merged = append(merged, entry)
+    }
+  }
+  for _, entry := range w.entries {
+    if !containsString(merged, entry) {
+      merged = append(merged, entry)
+    }
+  }
+  return merged
+}
+
+func (w *watchlist) isEmpty() bool {
+  w.mu.Lock()
+  defer w.mu.Unlock()
+
+  return len(w.entries) == 0
+}
+
+// normalizeWatchlistEntry trim entry, ticker ids are upper cased
+func normalizeWatchlistEntry(entry string) string {
+  entry = strings.TrimSpace(entry)
+  if strings.Contains(entry, "=") {
+    return entry
+  }
+  return strings.ToUpper(entry)
+}
+
+func containsString(values []string, value string) bool {
+  for _, v := range values {
+    if v == value {
+      return true
+    }
+  }
+  return false
+}
+
+// watchlistEntries parsed watchlist: ticker ids and patterns of tickers request params
+type watchlistEntries struct {
+  tickerIds []string
+  patterns  []TickersFilter
+}
+
+// SplitWatchlistEntries split flag value or file line to watchlist entries
+func SplitWatchlistEntries(value string) []string {
+  return strings.FieldsFunc(value, func(r rune) bool {
+    return strings.ContainsRune(watchlistEntrySeparators, r)
+  })
+}
+
+// parseWatchlistEntries parse entries. entry is ticker id, e.g. `AAPL`, or pattern of
+// tickers request params in query format, e.g. `exchange=XNAS` or `exchange=XNYS&type=ETF`
+func parseWatchlistEntries(entries []string) (*watchlistEntries, error) {
+  parsed := &watchlistEntries{}
+  seen := map[string]bool{}
+
+  for _, entry := range entries {
+    entry = normalizeWatchlistEntry(entry)
+    if entry == "" || seen[entry] {
+      continue
+    }
+    seen[entry] = true
+
+    if !strings.Contains(entry, "=") {
+      parsed.tickerIds = append(parsed.tickerIds, entry)
+      continue
+    }
+    query, err := url.ParseQuery(entry)
+    if err != nil {
+      return nil, fmt.Errorf("malformed watchlist pattern '%s': %v", entry, err)
+    }
+    pattern := TickersFilter{}
+    for key, values := range query {
+      if key == "" || len(values) != 1 || values[0] == "" {
+        return nil, fmt.Errorf("malformed watchlist pattern '%s': param '%s' must have single value", entry, key)
+      }
+      pattern[key] = values[0]
+    }
+    parsed.patterns = append(parsed.patterns, pattern)
+  }
+  return parsed, nil
+}
+
+// readWatchlistFile read entries of watchlist file. file has entries separated by commas,
+// spaces or new lines, lines starting with `#` are comments
+func readWatchlistFile(path string) ([]string, error) {
+  file, err := os.Open(path)
+  if err != nil {
+    return nil, fmt.Errorf("cannot open watchlist file: %v", err)
+  }
+  defer file.Close()
+
+  var entries []string
+  scanner := bufio.NewScanner(file)
+  for scanner.Scan() {
+    line := strings.TrimSpace(scanner.Text())
+    if line == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    entries = append(entries, SplitWatchlistEntries(line)...)
+  }
+  if err = scanner.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read watchlist file: %v", err)
+  }
+  return entries, nil
+}
+
+// SetWatchlist fetch only watchlist tickers instead of all tickers. entries are added to
+// watchlist of config
+//   entries ticker ids or patterns of tickers request params, e.g. `AAPL` or `exchange=XNAS`
+func (f *Fetcher) SetWatchlist(entries ...string) error {
+  if _, err := parseWatchlistEntries(entries); err != nil {
+    return err
+  }
+  f.watchlist.add(entries)
+  return nil
+}
+
+// hasWatchlist return true if watchlist is set in config or at runtime
+func (f *Fetcher) hasWatchlist() bool {
+  return f.getConfig().watchlistEnabled() || !f.watchlist.isEmpty()
+}
+
+// getWatchlist return entries of config, watchlist file and runtime watchlist.
+// file is read on every call, so it may be edited without restart
+func (f *Fetcher) getWatchlist() ([]string, error) {
+  var entries []string
+  if config := f.getConfig().Watchlist; config != nil {
+    entries = append(entries, config.Tickers...)
+
+    if config.File != "" {
+      fileEntries, err := readWatchlistFile(config.File)
+      if err != nil {
+        return nil, err
+      }
+      entries = append(entries, fileEntries...)
+    }
+  }
+  return f.watchlist.merge(entries), nil
+}
+
+func (f *Fetcher) getWatchlistEntries() (*watchlistEntries, error) {
+  entries, err := f.getWatchlist()
+  if err != nil {
+    return nil, err
+  }
+  return parseWatchlistEntries(entries)
+}
+
+// runWatchlistJob run tickers, details, branding and stocks pipeline for watchlist tickers.
+// failed tickers do not stop the job, they are fetched again on the next run
+func (f *Fetcher) runWatchlistJob(ctx context.Context, info *scheduler.RunInfo) error {
+  f.refreshCalendars(ctx)
+  ctx = f.setModeFromRunInfo(ctx, info)
+
+  entries, err := f.getWatchlistEntries()
+  if err != nil {
+    return fmt.Errorf("cannot get watchlist: %v", err)
+  }
+  run := &watchlistRun{processed: map[string]bool{}}
+
+  for _, tickerId := range entries.tickerIds {
+    if err = f.waitIfPaused(); err != nil {
+      return err
+    }
+    if ctx.Err() != nil {
+      return ctx.Err()
+    }
+    if run.processed[tickerId] || !f.ownsTicker(tickerId) {
+      continue
+    }
+    run.add(ctx, tickerId, f.fetchWatchlistTicker(ctx, tickerId))
+  }
+  for _, pattern := range entries.patterns {
+    if err = f.fetchWatchlistPattern(ctx, pattern, run); err != nil {
+      return fmt.Errorf("cannot fetch tickers of pattern %v: %v", pattern, err)
+    }
+  }
+  f.logger(ctx).Infof("watchlist fetched. tickers: %d, failed: %d", len(run.processed), len(run.failed))
+
+  if len(run.failed) != 0 {
+    sort.Strings(run.failed)
+    return fmt.Errorf("cannot fetch %d of %d watchlist tickers: %s",
+      len(run.failed), len(run.processed), strings.Join(run.failed, ", "))
+  }
+  return nil
+}
+
+// watchlistRun tickers processed by watchlist job run. ticker matching several
+// entries is processed once
+type watchlistRun struct {
+  processed map[string]bool
+  failed    []string
+}
+
+func (r *watchlistRun) add(ctx context.Context, tickerId string, err error) {
+  r.processed[tickerId] = true
+  logger := logging.FromContext(withTicker(ctx, tickerId), log)
+
+  if errors.Is(err, errTickerNotFound) {
+    logger.Warnf("watchlist ticker '%s' skipped: %v", tickerId, err)
+    return
+  }
+  if err != nil {
+    logger.Errorf("cannot fetch watchlist ticker '%s': %v", tickerId, err)
+    r.failed = append(r.failed, tickerId)
+  }
+}
+
+// fetchWatchlistTicker run pipeline for single ticker found by tickers request
+func (f *Fetcher) fetchWatchlistTicker(ctx context.Context, tickerId string) error {
+  query := buildTickersQuery(TickersFilter{tickerQueryKey: tickerId})
+  reqURL := fmt.Sprint(f.apiBaseURL, tickersApi, "?", query.Encode())
+
+  tickersResp, err := f.getTickersResponse(ctx, reqURL)
+  if err != nil {
+    return err
+  }
+  if tickersResp.Status != respStatusOK {
+    return fmt.Errorf("bad response status: %s", tickersResp.Status)
+  }
+  for _, result := range tickersResp.Results {
+    if result != nil && result.Ticker == tickerId {
+      return f.processTicker(ctx, result)
+    }
+  }
+  return errTickerNotFound
+}
+
+// fetchWatchlistPattern run pipeline for all owned tickers of pattern pages
+func (f *Fetcher) fetchWatchlistPattern(ctx context.Context, pattern TickersFilter, run *watchlistRun) error {
+  query := buildTickersQuery(pattern)
+  cursor := ""
+
+  for {
+    reqURL := fmt.Sprint(f.apiBaseURL, tickersApi, "?", query.Encode())
+    nextURL, err := f.fetchWatchlistPatternPage(ctx, cursor, reqURL, run)
+    if err != nil {
+      return err
+    }
+    if cursor = cursorFromURL(nextURL); cursor == "" {
+      return nil
+    }
+    query.Set(respCursorKey, cursor)
+  }
+}
+
+// fetchWatchlistPatternPage run pipeline for owned tickers of pattern page which are not
+// processed in this run and return url of the next page
+func (f *Fetcher) fetchWatchlistPatternPage(ctx context.Context, cursor, reqURL string, run *watchlistRun) (nextURL string, err error) {
+  ctx, span := startPageSpan(ctx, cursor)
+  defer func() { tracing.End(span, err) }()
+
+  tickersResp, err := f.getTickersResponse(ctx, reqURL)
+  if err != nil {
+    return "", err
+  }
+  if tickersResp.Status != respStatusOK {
+    return "", fmt.Errorf("bad response status: %s", tickersResp.Status)
+  }
+  for _, result := range tickersResp.Results {
+    if err = f.waitIfPaused(); err != nil {
+      return "", err
+    }
+    if ctx.Err() != nil {
+      return "", ctx.Err()
+    }
+    if result == nil || run.processed[result.Ticker] || !f.ownsTicker(result.Ticker) {
+      continue
+    }
+    run.add(ctx, result.Ticker, f.processTicker(ctx, result))
+  }
+  return tickersResp.NextUrl, nil
+}
@@ -1,12 +1,15 @@
 package tinkoff // Package tinkoff: unused
 
 import (
+  "scientific-research/internal/fetcher"
+  "scientific-research/pkg/utils/config"
+  "scientific-research/pkg/utils/logging"
   "sync/atomic"
   "time"
-
-  log "github.com/sirupsen/logrus"
 )
 
+var log = logging.Logger("tinkoff")
+
 const (
   retryCount = 5
 
@@ -46,7 +49,7 @@ func (f *Fetcher) ContinuouslyFetch() {
 
   for tryLeft >= 0 {
     if f.HasRelevantState() {
-      log.Printf("recently fetched. wait %v before the next launch",
+      log.Infof("recently fetched. wait %v before the next launch",
         recentlyFetchedSleepInterval)
       time.Sleep(recentlyFetchedSleepInterval)
       continue
@@ -63,15 +66,59 @@ func (f *Fetcher) ContinuouslyFetch() {
     }
     totalFetched := f.getTotalCount()
 
-    log.Printf("succefully fetched %d stocks", totalFetched)
+    log.Infof("succefully fetched %d stocks", totalFetched)
   }
   log.Fatalf("fetching failed and stopped")
 }
 
-func (f *Fetcher) SetTickerId(tickerId string) {
+func (f *Fetcher) SetWatchlist(entries ...string) error {
   panic("implement me")
 }
 
 func (f *Fetcher) SaveFetcherState() {
   panic("implement me")
 }
+
+func (f *Fetcher) ApplyConfig(config config.Config) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) FetchOnce() error {
+  panic("implement me")
+}
+
+func (f *Fetcher) FetchTickerStocks(tickerId string, from, to time.Time) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) Pause() {
+  panic("implement me")
+}
+
+func (f *Fetcher) Resume() {
+  panic("implement me")
+}
+
+func (f *Fetcher) Trigger(jobName string) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) AddWatchlist(entries ...string) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) RemoveWatchlist(entries ...string) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) ResetCursor() error {
+  panic("implement me")
+}
+
+func (f *Fetcher) Backfill(tickerId string, from, to time.Time) error {
+  panic("implement me")
+}
+
+func (f *Fetcher) Status() *fetcher.Status {
+  panic("implement me")
+}
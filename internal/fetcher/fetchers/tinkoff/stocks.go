@@ -4,7 +4,6 @@ import (
   "context"
   "encoding/json"
   "fmt"
-  "log"
   "scientific-research/internal/httpclient"
   "sync/atomic"
   "time"
@@ -105,7 +104,7 @@ func (f *Fetcher) fetchPaginatedStocks() error {
       }
       for range stocksBatch {
         f.countInc(1)
-        log.Printf("total stocks received: %d", f.getTotalCount())
+        log.Infof("total stocks received: %d", f.getTotalCount())
       }
       return nil
     })
@@ -8,27 +8,51 @@ import (
   "io"
   "net/http"
   "net/url"
+  "scientific-research/internal/tracing"
   "scientific-research/pkg/utils/common"
+  "scientific-research/pkg/utils/logging"
   "scientific-research/pkg/utils/retries"
   "strings"
+  "sync"
   "time"
 
   limiter "github.com/UshakovN/token-bucket"
-  log "github.com/sirupsen/logrus"
+  "github.com/sirupsen/logrus"
+  "go.opentelemetry.io/otel/attribute"
+  semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+  "go.opentelemetry.io/otel/trace"
 )
 
+var (
+  log    = logging.Logger("httpclient")
+  tracer = tracing.Tracer("httpclient")
+)
+
+// attemptsKey count of request attempts including retries
+const attemptsKey = attribute.Key("http.attempts")
+
 type Client struct {
   ctx     context.Context
   client  http.Client
-  limiter *rateLimiter
   token   *apiToken
+  mu      sync.RWMutex // guards limiter and retries which can be updated on config reload
+  limiter *rateLimiter
+  retries *retries.Option
+  // maxRespSize max size of response content in bytes, not limited if not positive
+  maxRespSize int64
 }
 
 type apiToken struct {
-  key   string
-  value string
+  key      string
+  value    string
+  inHeader bool
 }
 
+const (
+  headerAuthorization = "Authorization"
+  bearerAuthPrefix    = "Bearer "
+)
+
 type rateLimiter struct {
   limiter     *limiter.TokenBucket
   reqsCount   int
@@ -68,22 +92,113 @@ func WithApiToken(tokenKey, tokenValue string) Options {
   }
 }
 
-func WithRequestsLimit(reqsCount int, perDur, waitDur, deadlineDur time.Duration) Options {
+// WithBearerToken send token in `Authorization: Bearer` header instead of request url
+func WithBearerToken(tokenValue string) Options {
   return func(c *Client) {
-    if reqsCount <= 0 {
-      return
+    c.token = &apiToken{
+      key:      headerAuthorization,
+      value:    tokenValue,
+      inHeader: true,
     }
+  }
+}
+
+func WithRequestsLimit(reqsCount int, perDur, waitDur, deadlineDur time.Duration) Options {
+  return func(c *Client) {
+    c.limiter = newRateLimiter(reqsCount, perDur, waitDur, deadlineDur)
+  }
+}
+
+func newRateLimiter(reqsCount int, perDur, waitDur, deadlineDur time.Duration) *rateLimiter {
+  if reqsCount <= 0 {
+    return nil
+  }
+  return &rateLimiter{
+    limiter: limiter.NewTokenBucket(
+      reqsCount,
+      reqsCount,
+      limiter.SetRefillDuration(perDur),
+    ),
+    reqsCount:   reqsCount,
+    perDur:      perDur,
+    waitDur:     waitDur,
+    deadlineDur: deadlineDur,
+  }
+}
+
+// SetRequestsLimit replace requests limiter of running client. non-positive reqsCount disables limiter.
+// token bucket is kept if requests count and period are not changed, so reload does not refill it
+func (c *Client) SetRequestsLimit(reqsCount int, perDur, waitDur, deadlineDur time.Duration) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  current := c.limiter
+  if current != nil && current.reqsCount == reqsCount && current.perDur == perDur {
     c.limiter = &rateLimiter{
-      limiter: limiter.NewTokenBucket(
-        reqsCount,
-        reqsCount,
-        limiter.SetRefillDuration(perDur),
-      ),
+      limiter:     current.limiter,
       reqsCount:   reqsCount,
       perDur:      perDur,
       waitDur:     waitDur,
       deadlineDur: deadlineDur,
     }
+    return
+  }
+  c.limiter = newRateLimiter(reqsCount, perDur, waitDur, deadlineDur)
+}
+
+// SetRetries replace retry policy of running client. nil option set default policy
+func (c *Client) SetRetries(option *retries.Option) {
+  c.mu.Lock()
+  c.retries = option
+  c.mu.Unlock()
+}
+
+func (c *Client) getLimiter() *rateLimiter {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.limiter
+}
+
+func (c *Client) getRetries() *retries.Option {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.retries
+}
+
+func WithTransport(transport http.RoundTripper) Options {
+  return func(c *Client) {
+    if transport == nil {
+      return
+    }
+    c.client.Transport = transport
+  }
+}
+
+// WithTimeout limit time of single request including reading response body
+func WithTimeout(timeout time.Duration) Options {
+  return func(c *Client) {
+    c.client.Timeout = timeout
+  }
+}
+
+func WithRetries(option *retries.Option) Options {
+  return func(c *Client) {
+    c.retries = option
+  }
+}
+
+// WithMaxResponseSize fail requests which response content is larger than maxSize bytes
+func WithMaxResponseSize(maxSize int64) Options {
+  return func(c *Client) {
+    c.maxRespSize = maxSize
+  }
+}
+
+// WithCheckRedirect check every redirect before following it, redirect is not followed
+// and request fails if check returns error
+func WithCheckRedirect(check func(req *http.Request, via []*http.Request) error) Options {
+  return func(c *Client) {
+    c.client.CheckRedirect = check
   }
 }
 
@@ -131,22 +246,42 @@ type FullResp struct {
 }
 
 func (c *Client) GetFullResp(requestURL string, headers ...Header) (*FullResp, error) {
+  return c.GetFullRespContext(c.ctx, requestURL, headers...)
+}
+
+// GetFullRespContext send get request with context of caller. log entries of request
+// have log fields of context (e.g. correlation id of fetching cycle)
+func (c *Client) GetFullRespContext(ctx context.Context, requestURL string, headers ...Header) (*FullResp, error) {
+  ctx, span := startRequestSpan(ctx, http.MethodGet, requestURL)
+
+  fullResp, err := c.getFullResp(ctx, requestURL, headers)
+  if err == nil {
+    span.SetAttributes(semconv.HTTPStatusCode(fullResp.Code))
+  }
+  tracing.End(span, err)
+
+  return fullResp, err
+}
+
+func (c *Client) getFullResp(ctx context.Context, requestURL string, headers []Header) (*FullResp, error) {
   var (
     resp *http.Response
     err  error
   )
+  attempt := 0
 
   err = retries.DoWithRetry(func() error {
-    resp, err = c.getOnce(requestURL, headers)
+    attempt++
+    resp, err = c.getOnce(ctx, requestURL, headers, attempt)
     return err
-  })
+  }, c.getRetries())
   if err != nil {
     return nil, NewError(requestURL,
       fmt.Errorf("%s request failed: %v", http.MethodGet, err),
     )
   }
 
-  content, err := readResponse(requestURL, resp)
+  content, err := c.readResponse(requestURL, resp)
   if err != nil {
     return nil, err
   }
@@ -161,28 +296,67 @@ func (c *Client) GetFullResp(requestURL string, headers ...Header) (*FullResp, e
 }
 
 func (c *Client) Get(requestURL string, headers ...Header) ([]byte, error) {
-  fullResp, err := c.GetFullResp(requestURL, headers...)
+  return c.GetContext(c.ctx, requestURL, headers...)
+}
+
+// GetContext send get request with context of caller and return response content
+func (c *Client) GetContext(ctx context.Context, requestURL string, headers ...Header) ([]byte, error) {
+  fullResp, err := c.GetFullRespContext(ctx, requestURL, headers...)
   if err != nil {
     return nil, err
   }
   return fullResp.Content, nil
 }
 
-func (c *Client) getOnce(requestURL string, headers []Header) (*http.Response, error) {
-  if err := c.limiter.Wait(c.ctx); err != nil {
+func (c *Client) getOnce(ctx context.Context, requestURL string, headers []Header, attempt int) (*http.Response, error) {
+  logger := requestLogger(ctx, requestURL, attempt)
+  trace.SpanFromContext(ctx).SetAttributes(attemptsKey.Int(attempt))
+
+  if err := c.waitLimiter(ctx, logger); err != nil {
     return nil, fmt.Errorf("limiter wait failed: %v", err)
   }
 
-  req, err := c.formRequest(requestURL, nil)
+  req, err := c.formRequest(ctx, requestURL, nil)
   if err != nil {
     return nil, err
   }
-  resp, err := c.doRequest(req, headers)
-  if err != nil {
-    return nil, err
+  return c.doRequest(req, headers, logger)
+}
+
+// waitLimiter wait for limiter token. waiting is recorded as own span
+func (c *Client) waitLimiter(ctx context.Context, logger *logrus.Entry) error {
+  rl := c.getLimiter()
+  if rl == nil {
+    return nil
   }
+  ctx, span := tracer.Start(ctx, "limiter wait")
+  err := rl.Wait(ctx, logger)
+  tracing.End(span, err)
 
-  return resp, nil
+  return err
+}
+
+// startRequestSpan start client span of request. url credentials are redacted
+func startRequestSpan(ctx context.Context, method, requestURL string) (context.Context, trace.Span) {
+  return tracer.Start(ctx, "HTTP "+method,
+    trace.WithSpanKind(trace.SpanKindClient),
+    trace.WithAttributes(
+      semconv.HTTPMethod(method),
+      semconv.HTTPURL(RedactURL(requestURL)),
+    ),
+  )
+}
+
+// requestLogger return log entry of request attempt. url path has no credentials of query
+func requestLogger(ctx context.Context, requestURL string, attempt int) *logrus.Entry {
+  urlPath := ""
+  if parsedURL, err := url.Parse(requestURL); err == nil {
+    urlPath = parsedURL.Path
+  }
+  return logging.FromContext(ctx, log).WithFields(logrus.Fields{
+    logging.FieldURLPath: urlPath,
+    logging.FieldAttempt: attempt,
+  })
 }
 
 func (t *apiToken) addToRequestURL(reqURL string) (string, error) {
@@ -190,7 +364,7 @@ func (t *apiToken) addToRequestURL(reqURL string) (string, error) {
   // try parse url
   parsedUrl, err := url.Parse(reqURL)
   if err != nil {
-    return "", fmt.Errorf("malformed request url '%s': %v", reqURL, err)
+    return "", fmt.Errorf("malformed request url '%s'", RedactURL(reqURL))
   }
   // parse query
   query := parsedUrl.Query()
@@ -203,43 +377,68 @@ func (t *apiToken) addToRequestURL(reqURL string) (string, error) {
   return reqURL, nil
 }
 
-func (c *Client) formRequest(reqURL string, body io.Reader) (*http.Request, error) {
+func (t *apiToken) addToRequestHeader(req *http.Request) {
+  req.Header.Set(headerAuthorization, fmt.Sprint(bearerAuthPrefix, t.value))
+}
+
+func (c *Client) formRequest(ctx context.Context, reqURL string, body io.Reader) (*http.Request, error) {
   var (
     err error
   )
-  if c.token != nil {
+  if c.token != nil && !c.token.inHeader {
     reqURL, err = c.token.addToRequestURL(reqURL)
     if err != nil {
       return nil, fmt.Errorf("cannot add token to request url: %v", err)
     }
   }
-  req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, reqURL, body)
+  req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, body)
   if err != nil {
-    return nil, NewError(reqURL, fmt.Errorf("cannot create request: %v", err))
+    return nil, NewError(reqURL, fmt.Errorf("cannot create request: %v", redactURLError(err)))
   }
   return req, nil
 }
 
-func (c *Client) doRequest(req *http.Request, headers []Header) (*http.Response, error) {
+func (c *Client) doRequest(req *http.Request, headers []Header, logger *logrus.Entry) (*http.Response, error) {
   req.Header = common.ExtractOptional(headers...).toHttpHeaders()
 
+  if c.token != nil && c.token.inHeader {
+    c.token.addToRequestHeader(req)
+  }
+  startedAt := time.Now()
   resp, err := c.client.Do(req)
+  logger = logger.WithField(logging.FieldDuration, time.Since(startedAt).String())
+
   if err != nil {
+    err = redactURLError(err)
+    logger.Warnf("request failed: %v", err)
     return nil, NewError(req.URL.String(), fmt.Errorf("do request failed: %v", err))
   }
   statusCode := resp.StatusCode
 
   if statusCode >= http.StatusBadRequest {
+    logger.Warnf("request failed with status code %d", statusCode)
     return nil, NewError(req.URL.String(), fmt.Errorf("bad response. got status code: %d", statusCode))
   }
+  logger.Debugf("request done with status code %d", statusCode)
+
   return resp, nil
 }
 
-func readResponse(requestURL string, resp *http.Response) ([]byte, error) {
-  content, err := io.ReadAll(resp.Body)
+func (c *Client) readResponse(requestURL string, resp *http.Response) ([]byte, error) {
+  var reader io.Reader = resp.Body
+  if c.maxRespSize > 0 {
+    // one byte over limit tells that content is too large
+    reader = io.LimitReader(resp.Body, c.maxRespSize+1)
+  }
+  content, err := io.ReadAll(reader)
   if err != nil {
+    _ = resp.Body.Close()
     return nil, NewError(requestURL, fmt.Errorf("cannot read response: %v", err))
   }
+  if c.maxRespSize > 0 && int64(len(content)) > c.maxRespSize {
+    _ = resp.Body.Close()
+    return nil, NewError(requestURL, fmt.Errorf("response content is larger than %d bytes", c.maxRespSize))
+  }
 
   if err = resp.Body.Close(); err != nil {
     return nil, NewError(requestURL, fmt.Errorf("cannot close response reader: %v", err))
@@ -262,20 +461,31 @@ func preparePostPayload(requestURL string, payload any) (io.Reader, error) {
 }
 
 func (c *Client) Post(requestURL string, payload any, headers ...Header) ([]byte, error) {
+  ctx, span := startRequestSpan(c.ctx, http.MethodPost, requestURL)
+
+  content, err := c.post(ctx, requestURL, payload, headers)
+  tracing.End(span, err)
+
+  return content, err
+}
+
+func (c *Client) post(ctx context.Context, requestURL string, payload any, headers []Header) ([]byte, error) {
   var (
     resp *http.Response
     err  error
   )
+  attempt := 0
 
   err = retries.DoWithRetry(func() error {
-    resp, err = c.postOnce(requestURL, payload, headers)
+    attempt++
+    resp, err = c.postOnce(ctx, requestURL, payload, headers, attempt)
     return err
-  })
+  }, c.getRetries())
   if err != nil {
     return nil, NewError(requestURL, fmt.Errorf("post request failed: %v", err))
   }
 
-  content, err := readResponse(requestURL, resp)
+  content, err := c.readResponse(requestURL, resp)
   if err != nil {
     return nil, err
   }
@@ -283,8 +493,11 @@ func (c *Client) Post(requestURL string, payload any, headers ...Header) ([]byte
   return content, nil
 }
 
-func (c *Client) postOnce(requestURL string, payload any, headers []Header) (*http.Response, error) {
-  if err := c.limiter.Wait(c.ctx); err != nil {
+func (c *Client) postOnce(ctx context.Context, requestURL string, payload any, headers []Header, attempt int) (*http.Response, error) {
+  logger := requestLogger(ctx, requestURL, attempt)
+  trace.SpanFromContext(ctx).SetAttributes(attemptsKey.Int(attempt))
+
+  if err := c.waitLimiter(ctx, logger); err != nil {
     return nil, fmt.Errorf("limiter wait failed: %v", err)
   }
 
@@ -292,11 +505,11 @@ func (c *Client) postOnce(requestURL string, payload any, headers []Header) (*ht
   if err != nil {
     return nil, NewError(requestURL, fmt.Errorf("cannot prepare post payload: %v", err))
   }
-  req, err := c.formRequest(requestURL, body)
+  req, err := c.formRequest(ctx, requestURL, body)
   if err != nil {
     return nil, err
   }
-  resp, err := c.doRequest(req, headers)
+  resp, err := c.doRequest(req, headers, logger)
   if err != nil {
     return nil, err
   }
@@ -308,8 +521,10 @@ func (c *Client) ParseResponse(bytes []byte, resp any) error {
   return json.Unmarshal(bytes, resp)
 }
 
-func (l *rateLimiter) Wait(ctx context.Context) error {
-  if l.limiter == nil {
+// Wait wait for limiter token until deadline of limiter
+//   logger log entry of request waiting for token
+func (l *rateLimiter) Wait(ctx context.Context, logger *logrus.Entry) error {
+  if l == nil || l.limiter == nil {
     return nil
   }
 
@@ -330,7 +545,7 @@ func (l *rateLimiter) Wait(ctx context.Context) error {
 
       untilDeadlineDur := deadlineTime.Sub(time.Now()).Round(time.Second)
 
-      log.Infof("limiter: sent %d requests in %s. limit reached. sleep on %s. until waiting deadline: %s",
+      logger.Infof("limiter: sent %d requests in %s. limit reached. sleep on %s. until waiting deadline: %s",
         l.reqsCount, l.perDur, l.waitDur, untilDeadlineDur)
 
       time.Sleep(l.waitDur)
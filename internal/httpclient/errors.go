@@ -20,5 +20,5 @@ func (e *Error) Error() string {
   if e == nil {
     return ""
   }
-  return fmt.Sprintf(errorPattern, e.URL, e.Err)
+  return fmt.Sprintf(errorPattern, RedactURL(e.URL), e.Err)
 }
@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+  "errors"
+  "net/url"
+  "strings"
+)
+
+const redactedValue = "REDACTED"
+
+// sensitiveParams query params which values must never appear in logs, errors or storage
+var sensitiveParams = []string{
+  "apikey",
+  "api_key",
+  "token",
+  "access_token",
+}
+
+func isSensitiveParam(key string) bool {
+  key = strings.ToLower(key)
+  for _, param := range sensitiveParams {
+    if key == param {
+      return true
+    }
+  }
+  return false
+}
+
+// RedactURL replace values of credential query params and user password with placeholder
+//   rawURL any url, fully replaced with placeholder if it cannot be parsed
+func RedactURL(rawURL string) string {
+  parsedURL, err := url.Parse(rawURL)
+  if err != nil {
+    return redactedValue
+  }
+  if _, hasPassword := parsedURL.User.Password(); hasPassword {
+    parsedURL.User = url.UserPassword(parsedURL.User.Username(), redactedValue)
+  }
+  query := parsedURL.Query()
+  redacted := false
+
+  for key := range query {
+    if !isSensitiveParam(key) {
+      continue
+    }
+    query.Set(key, redactedValue)
+    redacted = true
+  }
+  if redacted {
+    parsedURL.RawQuery = query.Encode()
+  }
+  return parsedURL.String()
+}
+
+// StripCredentials remove credential query params and user info from url
+//   rawURL any url, returned as is if it cannot be parsed
+func StripCredentials(rawURL string) string {
+  parsedURL, err := url.Parse(rawURL)
+  if err != nil {
+    return rawURL
+  }
+  parsedURL.User = nil
+  query := parsedURL.Query()
+  stripped := false
+
+  for key := range query {
+    if !isSensitiveParam(key) {
+      continue
+    }
+    query.Del(key)
+    stripped = true
+  }
+  if stripped {
+    parsedURL.RawQuery = query.Encode()
+  }
+  return parsedURL.String()
+}
+
+// redactURLError redact url of url.Error in chain of err. url.Error of request creating and
+// doing contains full request url with credentials
+func redactURLError(err error) error {
+  var urlErr *url.Error
+  if errors.As(err, &urlErr) {
+    urlErr.URL = RedactURL(urlErr.URL)
+  }
+  return err
+}
@@ -0,0 +1,214 @@
+package replay
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "net/url"
+  "os"
+  "path/filepath"
+  "scientific-research/internal/httpclient"
+  "sync"
+)
+
+// Interaction single recorded request and response pair
+type Interaction struct {
+  Method     string            `json:"method"`
+  URL        string            `json:"url"`
+  StatusCode int               `json:"status_code"`
+  Headers    map[string]string `json:"headers,omitempty"`
+  Body       []byte            `json:"body,omitempty"`
+}
+
+// Cassette ordered list of interactions stored in fixture file
+type Cassette struct {
+  Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadCassette read cassette from fixture file
+//   path path to json fixture file
+func LoadCassette(path string) (*Cassette, error) {
+  b, err := os.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("cannot read cassette file: %v", err)
+  }
+  cassette := &Cassette{}
+  if err = json.Unmarshal(b, cassette); err != nil {
+    return nil, fmt.Errorf("cannot parse cassette file: %v", err)
+  }
+  return cassette, nil
+}
+
+// Save write cassette to fixture file
+//   path path to json fixture file
+func (c *Cassette) Save(path string) error {
+  b, err := json.MarshalIndent(c, "", "  ")
+  if err != nil {
+    return fmt.Errorf("cannot marshal cassette: %v", err)
+  }
+  if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+    return fmt.Errorf("cannot create cassette directory: %v", err)
+  }
+  if err = os.WriteFile(path, b, 0o644); err != nil {
+    return fmt.Errorf("cannot write cassette file: %v", err)
+  }
+  return nil
+}
+
+// Recorder http.RoundTripper which forwards requests to the underlying
+// transport and records every interaction into the cassette
+type Recorder struct {
+  mu        sync.Mutex
+  path      string
+  transport http.RoundTripper
+  ignored   []string
+  cassette  *Cassette
+}
+
+// NewRecorder create recorder for fixture file
+//   path path to json fixture file
+//   transport underlying transport. http.DefaultTransport used if nil
+//   ignoredParams query params which will be dropped from recorded URLs (e.g. api tokens)
+func NewRecorder(path string, transport http.RoundTripper, ignoredParams ...string) *Recorder {
+  if transport == nil {
+    transport = http.DefaultTransport
+  }
+  return &Recorder{
+    path:      path,
+    transport: transport,
+    ignored:   ignoredParams,
+    cassette:  &Cassette{},
+  }
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+  resp, err := r.transport.RoundTrip(req)
+  if err != nil {
+    return nil, err
+  }
+  body, err := io.ReadAll(resp.Body)
+  if err != nil {
+    return nil, fmt.Errorf("cannot read response body: %v", err)
+  }
+  if err = resp.Body.Close(); err != nil {
+    return nil, fmt.Errorf("cannot close response body: %v", err)
+  }
+  resp.Body = io.NopCloser(bytes.NewReader(body))
+
+  headers := map[string]string{}
+  for key := range resp.Header {
+    headers[key] = resp.Header.Get(key)
+  }
+  r.mu.Lock()
+  r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+    Method:     req.Method,
+    URL:        stripQueryParams(req.URL, r.ignored),
+    StatusCode: resp.StatusCode,
+    Headers:    headers,
+    Body:       body,
+  })
+  r.mu.Unlock()
+
+  return resp, nil
+}
+
+// Save write recorded interactions to fixture file
+func (r *Recorder) Save() error {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+  return r.cassette.Save(r.path)
+}
+
+// Replayer http.RoundTripper which serves responses from the cassette
+// without network. interactions with the same method and URL replayed in recorded order
+type Replayer struct {
+  mu      sync.Mutex
+  ignored []string
+  pending map[string][]*Interaction
+}
+
+// NewReplayer create replayer from fixture file
+//   path path to json fixture file
+//   ignoredParams query params which will be ignored while matching requests
+func NewReplayer(path string, ignoredParams ...string) (*Replayer, error) {
+  cassette, err := LoadCassette(path)
+  if err != nil {
+    return nil, err
+  }
+  return NewReplayerFromCassette(cassette, ignoredParams...)
+}
+
+// NewReplayerFromCassette create replayer from loaded cassette
+//   cassette recorded interactions
+//   ignoredParams query params which will be ignored while matching requests
+func NewReplayerFromCassette(cassette *Cassette, ignoredParams ...string) (*Replayer, error) {
+  if cassette == nil {
+    return nil, fmt.Errorf("cassette is a nil")
+  }
+  pending := map[string][]*Interaction{}
+
+  for idx, interaction := range cassette.Interactions {
+    parsedURL, err := url.Parse(interaction.URL)
+    if err != nil {
+      // parse error contains url with credentials
+      return nil, fmt.Errorf("malformed url of interaction %d", idx)
+    }
+    key := interactionKey(interaction.Method, stripQueryParams(parsedURL, ignoredParams))
+    pending[key] = append(pending[key], interaction)
+  }
+  return &Replayer{
+    ignored: ignoredParams,
+    pending: pending,
+  }, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+  key := interactionKey(req.Method, stripQueryParams(req.URL, r.ignored))
+
+  r.mu.Lock()
+  interactions := r.pending[key]
+  if len(interactions) == 0 {
+    r.mu.Unlock()
+    // request url keeps credentials which are not ignored
+    return nil, fmt.Errorf("interaction not recorded: %s", interactionKey(req.Method,
+      httpclient.RedactURL(stripQueryParams(req.URL, r.ignored))))
+  }
+  interaction := interactions[0]
+  // keep last interaction for repeated requests
+  if len(interactions) > 1 {
+    r.pending[key] = interactions[1:]
+  }
+  r.mu.Unlock()
+
+  header := http.Header{}
+  for key, value := range interaction.Headers {
+    header.Set(key, value)
+  }
+  return &http.Response{
+    Status:        http.StatusText(interaction.StatusCode),
+    StatusCode:    interaction.StatusCode,
+    Proto:         "HTTP/1.1",
+    ProtoMajor:    1,
+    ProtoMinor:    1,
+    Header:        header,
+    Body:          io.NopCloser(bytes.NewReader(interaction.Body)),
+    ContentLength: int64(len(interaction.Body)),
+    Request:       req,
+  }, nil
+}
+
+func interactionKey(method, reqURL string) string {
+  return fmt.Sprint(method, " ", reqURL)
+}
+
+func stripQueryParams(reqURL *url.URL, params []string) string {
+  stripped := *reqURL
+  query := stripped.Query()
+  for _, param := range params {
+    query.Del(param)
+  }
+  stripped.RawQuery = query.Encode()
+  return stripped.String()
+}
@@ -0,0 +1,112 @@
+package replay
+
+import (
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "path/filepath"
+  "scientific-research/internal/httpclient"
+  "scientific-research/pkg/utils/retries"
+  "strings"
+  "testing"
+)
+
+const (
+  testApiKey = "apiKey"
+  testSecret = "s3cr3t"
+)
+
+func newRequest(t *testing.T, reqURL string) *http.Request {
+  t.Helper()
+
+  req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+  if err != nil {
+    t.Fatalf("cannot create request: %v", err)
+  }
+  return req
+}
+
+func TestRecordAndReplay(t *testing.T) {
+  srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    _, _ = io.WriteString(w, `{"ticker":"`+r.URL.Query().Get("ticker")+`"}`)
+  }))
+  defer srv.Close()
+
+  cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+  recorder := NewRecorder(cassettePath, nil, testApiKey)
+  for _, ticker := range []string{"AAPL", "MSFT"} {
+    resp, err := recorder.RoundTrip(newRequest(t, srv.URL+"/v3/tickers?ticker="+ticker+"&apiKey="+testSecret))
+    if err != nil {
+      t.Fatalf("cannot record request: %v", err)
+    }
+    _ = resp.Body.Close()
+  }
+  if err := recorder.Save(); err != nil {
+    t.Fatalf("cannot save cassette: %v", err)
+  }
+
+  cassette, err := LoadCassette(cassettePath)
+  if err != nil {
+    t.Fatalf("cannot load cassette: %v", err)
+  }
+  for _, interaction := range cassette.Interactions {
+    if strings.Contains(interaction.URL, testSecret) {
+      t.Errorf("recorded url keeps api key: %s", interaction.URL)
+    }
+  }
+
+  replayer, err := NewReplayer(cassettePath, testApiKey)
+  if err != nil {
+    t.Fatalf("cannot create replayer: %v", err)
+  }
+  // replayed request has other api key
+  resp, err := replayer.RoundTrip(newRequest(t, srv.URL+"/v3/tickers?apiKey=other&ticker=MSFT"))
+  if err != nil {
+    t.Fatalf("cannot replay request: %v", err)
+  }
+  body, _ := io.ReadAll(resp.Body)
+  if resp.StatusCode != http.StatusOK || string(body) != `{"ticker":"MSFT"}` {
+    t.Errorf("replayed response %d '%s'", resp.StatusCode, body)
+  }
+}
+
+func TestReplayErrorsHaveNoCredentials(t *testing.T) {
+  cassette := &Cassette{Interactions: []*Interaction{
+    {Method: http.MethodGet, URL: "https://api.polygon.io/v3/tickers?ticker=AAPL", StatusCode: http.StatusOK},
+  }}
+  // api key is not ignored by replayer
+  replayer, err := NewReplayerFromCassette(cassette)
+  if err != nil {
+    t.Fatalf("cannot create replayer: %v", err)
+  }
+  _, err = replayer.RoundTrip(newRequest(t, "https://api.polygon.io/v3/tickers?ticker=MSFT&apiKey="+testSecret))
+  if err == nil {
+    t.Fatalf("not recorded interaction is replayed")
+  }
+  if strings.Contains(err.Error(), testSecret) {
+    t.Errorf("replay error contains api key: %v", err)
+  }
+
+  // the error reaches caller through client of fetchers
+  client := httpclient.NewClient(
+    httpclient.WithTransport(replayer),
+    httpclient.WithApiToken(testApiKey, testSecret),
+    httpclient.WithRetries(&retries.Option{RetryCount: 1}),
+  )
+  if _, err = client.Get("https://api.polygon.io/v3/tickers?ticker=MSFT"); err == nil {
+    t.Fatalf("not recorded interaction is replayed")
+  } else if strings.Contains(err.Error(), testSecret) {
+    t.Errorf("client error contains api key: %v", err)
+  }
+
+  cassette.Interactions = append(cassette.Interactions, &Interaction{
+    Method: http.MethodGet,
+    URL:    "https://api.polygon.io/%zz?apiKey=" + testSecret,
+  })
+  if _, err = NewReplayerFromCassette(cassette); err == nil {
+    t.Errorf("malformed interaction url is loaded")
+  } else if strings.Contains(err.Error(), testSecret) {
+    t.Errorf("cassette error contains api key: %v", err)
+  }
+}
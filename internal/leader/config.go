@@ -0,0 +1,15 @@
+package leader
+
+import "time"
+
+// Config leader election through lease in postgres. only lease holder runs fetching,
+// standby instances take over when lease expires. leader stops fetching safety margin
+// before the lease expires if it cannot renew the lease
+type Config struct {
+  Enabled       bool          `yaml:"enabled" env:"LEADER_ELECTION_ENABLED"`
+  FetcherId     string        `yaml:"fetcher_id" env:"LEADER_ELECTION_FETCHER_ID"`
+  HolderId      string        `yaml:"holder_id" env:"LEADER_ELECTION_HOLDER_ID"`
+  LeaseDuration time.Duration `yaml:"lease_duration" env:"LEADER_ELECTION_LEASE_DURATION"`
+  RenewInterval time.Duration `yaml:"renew_interval" env:"LEADER_ELECTION_RENEW_INTERVAL"`
+  SafetyMargin  time.Duration `yaml:"safety_margin" env:"LEADER_ELECTION_SAFETY_MARGIN"`
+}
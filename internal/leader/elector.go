@@ -0,0 +1,177 @@
+package leader
+
+import (
+  "context"
+  "fmt"
+  "os"
+  "scientific-research/internal/domain"
+  "scientific-research/pkg/utils/logging"
+  "sync/atomic"
+  "time"
+)
+
+var log = logging.Logger("leader")
+
+const (
+  defaultLeaseDuration = 30 * time.Second
+  defaultRenewInterval = 10 * time.Second
+  defaultSafetyMargin  = 5 * time.Second
+)
+
+// LeaseStorage storage of fetcher leases
+type LeaseStorage interface {
+  AcquireLease(fetcherId, holderId string, duration time.Duration) (*domain.FetcherLease, bool, error)
+  ReleaseLease(fetcherId, holderId string) error
+}
+
+// Elector acquire lease of fetcher and renew it while leading
+type Elector struct {
+  storage       LeaseStorage
+  fetcherId     string
+  holderId      string
+  leaseDuration time.Duration
+  renewInterval time.Duration
+  safetyMargin  time.Duration
+  leading       atomic.Bool
+}
+
+// NewElector create elector for fetcher. holder id defaults to `<hostname>-<pid>`
+//   storage lease storage
+//   config leader election config
+//   defaultFetcherId fetcher id used if config has no fetcher id
+func NewElector(storage LeaseStorage, config *Config, defaultFetcherId string) (*Elector, error) {
+  if storage == nil {
+    return nil, fmt.Errorf("lease storage is a nil")
+  }
+  if config == nil {
+    config = &Config{}
+  }
+  e := &Elector{
+    storage:       storage,
+    fetcherId:     config.FetcherId,
+    holderId:      config.HolderId,
+    leaseDuration: config.LeaseDuration,
+    renewInterval: config.RenewInterval,
+    safetyMargin:  config.SafetyMargin,
+  }
+  if e.fetcherId == "" {
+    e.fetcherId = defaultFetcherId
+  }
+  if e.holderId == "" {
+    hostname, err := os.Hostname()
+    if err != nil {
+      return nil, fmt.Errorf("cannot get hostname for holder id: %v", err)
+    }
+    e.holderId = fmt.Sprint(hostname, "-", os.Getpid())
+  }
+  if e.leaseDuration <= 0 {
+    e.leaseDuration = defaultLeaseDuration
+  }
+  if e.renewInterval <= 0 {
+    e.renewInterval = defaultRenewInterval
+  }
+  if e.safetyMargin <= 0 {
+    e.safetyMargin = defaultSafetyMargin
+  }
+  if e.renewInterval+e.safetyMargin >= e.leaseDuration {
+    return nil, fmt.Errorf("renew interval %v and safety margin %v must be less than lease duration %v",
+      e.renewInterval, e.safetyMargin, e.leaseDuration)
+  }
+  return e, nil
+}
+
+// IsLeader return true if instance holds the lease
+func (e *Elector) IsLeader() bool {
+  return e.leading.Load()
+}
+
+// Run try to acquire lease every renew interval and renew it while leading. blocks until
+// context is done, lease is released then. onStartedLeading is called once lease acquired.
+// onStoppedLeading is called if other instance took the lease or safety margin before
+// the lease expires if it is not renewed. work of the leader must be fenced there
+func (e *Elector) Run(ctx context.Context, onStartedLeading func(), onStoppedLeading func()) {
+  log.Infof("start leader election for fetcher '%s' as '%s'. lease duration: %v",
+    e.fetcherId, e.holderId, e.leaseDuration)
+
+  ticker := time.NewTicker(e.renewInterval)
+  defer ticker.Stop()
+
+  // fence fires safety margin before the lease expires unless the lease is renewed
+  fence := time.NewTimer(e.leaseDuration)
+  fence.Stop()
+  defer fence.Stop()
+
+  var fenceAt time.Time
+
+  for {
+    attemptedAt := time.Now()
+    lease, acquired, err := e.storage.AcquireLease(e.fetcherId, e.holderId, e.leaseDuration)
+    switch {
+    case err != nil:
+      log.Errorf("cannot acquire lease of fetcher '%s': %v", e.fetcherId, err)
+    case !acquired:
+      if e.IsLeader() {
+        e.stopLeading(onStoppedLeading, "lease is taken by other instance")
+      }
+    case e.IsLeader() && !time.Now().Before(fenceAt):
+      // lease is renewed too late, the leader has worked without a safe lease
+      e.stopLeading(onStoppedLeading, "lease is renewed after safety margin")
+    default:
+      // local clock is used from the attempt start, so the round trip shortens the lease
+      fenceAt = attemptedAt.Add(lease.ExpiresAt.Sub(lease.UpdatedAt) - e.safetyMargin)
+      resetTimer(fence, time.Until(fenceAt))
+
+      if !e.IsLeader() {
+        e.leading.Store(true)
+        log.Infof("'%s' became leader of fetcher '%s' until %s", e.holderId, e.fetcherId, lease.ExpiresAt)
+        onStartedLeading()
+      }
+    }
+
+    select {
+    case <-ctx.Done():
+      e.release()
+      return
+    case <-fence.C:
+      if e.IsLeader() {
+        e.stopLeading(onStoppedLeading, "lease is not renewed before safety margin")
+      }
+      // the next attempt is made by schedule, ticker is not drained
+      select {
+      case <-ctx.Done():
+        e.release()
+        return
+      case <-ticker.C:
+      }
+    case <-ticker.C:
+    }
+  }
+}
+
+func (e *Elector) stopLeading(onStoppedLeading func(), reason string) {
+  e.leading.Store(false)
+  log.Errorf("'%s' lost leadership of fetcher '%s': %s", e.holderId, e.fetcherId, reason)
+  onStoppedLeading()
+}
+
+// resetTimer reset timer dropping its fired but not received value
+func resetTimer(timer *time.Timer, d time.Duration) {
+  if !timer.Stop() {
+    select {
+    case <-timer.C:
+    default:
+    }
+  }
+  timer.Reset(d)
+}
+
+func (e *Elector) release() {
+  if !e.leading.Swap(false) {
+    return
+  }
+  if err := e.storage.ReleaseLease(e.fetcherId, e.holderId); err != nil {
+    log.Errorf("cannot release lease of fetcher '%s': %v", e.fetcherId, err)
+    return
+  }
+  log.Infof("'%s' released lease of fetcher '%s'", e.holderId, e.fetcherId)
+}
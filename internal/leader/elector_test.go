@@ -0,0 +1,176 @@
+package leader
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/domain"
+  "sync"
+  "testing"
+  "time"
+)
+
+// memLeaseStorage lease storage which answers attempts by acquire func
+type memLeaseStorage struct {
+  mu       sync.Mutex
+  attempts int
+  released bool
+  // acquire return lease duration by database clock for attempt, zero if lease is not acquired
+  acquire func(attempt int) (time.Duration, error)
+}
+
+func (s *memLeaseStorage) AcquireLease(fetcherId, holderId string, duration time.Duration) (*domain.FetcherLease, bool, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.attempts++
+  leaseDuration, err := s.acquire(s.attempts)
+  if err != nil || leaseDuration == 0 {
+    return nil, false, err
+  }
+  // database clock differs from local one
+  updatedAt := time.Date(2026, 3, 6, 22, 0, 0, 0, time.UTC)
+  return &domain.FetcherLease{
+    FetcherId: fetcherId,
+    HolderId:  holderId,
+    ExpiresAt: updatedAt.Add(leaseDuration),
+    UpdatedAt: updatedAt,
+  }, true, nil
+}
+
+func (s *memLeaseStorage) ReleaseLease(fetcherId, holderId string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.released = true
+  return nil
+}
+
+func newTestElector(t *testing.T, storage LeaseStorage, leaseDuration, renewInterval, safetyMargin time.Duration) *Elector {
+  t.Helper()
+
+  e, err := NewElector(storage, &Config{
+    HolderId:      "test",
+    LeaseDuration: leaseDuration,
+    RenewInterval: renewInterval,
+    SafetyMargin:  safetyMargin,
+  }, "polygon")
+  if err != nil {
+    t.Fatalf("cannot create elector: %v", err)
+  }
+  return e
+}
+
+// runElector run elector until leadership is lost or timeout passed. return time from
+// the start of leading to its stop, zero if leadership is not lost
+func runElector(t *testing.T, e *Elector, timeout time.Duration) time.Duration {
+  t.Helper()
+
+  ctx, cancel := context.WithTimeout(context.Background(), timeout)
+  defer cancel()
+
+  var startedAt time.Time
+  var leadingFor time.Duration
+  started := 0
+  done := make(chan struct{})
+  go func() {
+    defer close(done)
+    e.Run(ctx,
+      func() {
+        started++
+        startedAt = time.Now()
+      },
+      func() {
+        leadingFor = time.Since(startedAt)
+        cancel()
+      },
+    )
+  }()
+  <-done
+
+  if started != 1 {
+    t.Fatalf("leading is started %d times, want once", started)
+  }
+  return leadingFor
+}
+
+func TestElectorFencesBeforeLeaseExpires(t *testing.T) {
+  storage := &memLeaseStorage{acquire: func(attempt int) (time.Duration, error) {
+    if attempt == 1 {
+      return 600 * time.Millisecond, nil
+    }
+    return 0, fmt.Errorf("connection refused")
+  }}
+  e := newTestElector(t, storage, 600*time.Millisecond, 200*time.Millisecond, 200*time.Millisecond)
+
+  leadingFor := runElector(t, e, 2*time.Second)
+  if leadingFor == 0 {
+    t.Fatalf("leadership is not lost when lease cannot be renewed")
+  }
+  // fence is due safety margin before expiry, not on the next renew tick after it
+  if leadingFor >= 600*time.Millisecond-100*time.Millisecond {
+    t.Errorf("leading is stopped after %v, want before lease expires with safety margin", leadingFor)
+  }
+  if e.IsLeader() {
+    t.Errorf("elector is leader after fence")
+  }
+}
+
+func TestElectorUsesLeaseExpirationOfDatabase(t *testing.T) {
+  // database returns lease shorter than requested, fence is due before the first renew tick
+  storage := &memLeaseStorage{acquire: func(attempt int) (time.Duration, error) {
+    if attempt == 1 {
+      return 300 * time.Millisecond, nil
+    }
+    return 0, fmt.Errorf("connection refused")
+  }}
+  e := newTestElector(t, storage, 2*time.Second, time.Second, 100*time.Millisecond)
+
+  leadingFor := runElector(t, e, 2*time.Second)
+  if leadingFor == 0 || leadingFor >= 300*time.Millisecond {
+    t.Errorf("leading is stopped after %v, want before database lease expires", leadingFor)
+  }
+}
+
+func TestElectorStopsWhenLeaseIsTaken(t *testing.T) {
+  storage := &memLeaseStorage{acquire: func(attempt int) (time.Duration, error) {
+    if attempt == 1 {
+      return time.Second, nil
+    }
+    return 0, nil
+  }}
+  e := newTestElector(t, storage, time.Second, 100*time.Millisecond, 100*time.Millisecond)
+
+  leadingFor := runElector(t, e, 2*time.Second)
+  if leadingFor == 0 || leadingFor >= 500*time.Millisecond {
+    t.Errorf("leading is stopped after %v, want on the next renew", leadingFor)
+  }
+}
+
+func TestElectorKeepsRenewedLease(t *testing.T) {
+  storage := &memLeaseStorage{acquire: func(attempt int) (time.Duration, error) {
+    return 300 * time.Millisecond, nil
+  }}
+  e := newTestElector(t, storage, 300*time.Millisecond, 50*time.Millisecond, 100*time.Millisecond)
+
+  if leadingFor := runElector(t, e, 700*time.Millisecond); leadingFor != 0 {
+    t.Errorf("leading is stopped after %v while lease is renewed", leadingFor)
+  }
+  if storage.attempts < 5 {
+    t.Errorf("lease is renewed %d times, want renewing every interval", storage.attempts)
+  }
+  if !storage.released || e.IsLeader() {
+    t.Errorf("lease is not released on exit")
+  }
+}
+
+func TestNewElectorChecksIntervals(t *testing.T) {
+  _, err := NewElector(&memLeaseStorage{}, &Config{
+    HolderId:      "test",
+    LeaseDuration: 10 * time.Second,
+    RenewInterval: 6 * time.Second,
+    SafetyMargin:  4 * time.Second,
+  }, "polygon")
+  if err == nil {
+    t.Errorf("lease is renewed later than safety margin before expiry")
+  }
+}
@@ -0,0 +1,63 @@
+package media
+
+import (
+  "fmt"
+  "scientific-research/internal/objectstore"
+  "scientific-research/internal/queue/rabbitmq"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/config"
+  "scientific-research/pkg/utils/logging"
+  "scientific-research/pkg/utils/validation"
+  "time"
+)
+
+// Config media service config. referenced content is pulled only from `object_store`
+// (object store of fetcher: `file://` urls under its dir and urls of its base url host)
+// and from Polygon hosts
+type Config struct {
+  Dir             string              `yaml:"dir" env:"MEDIA_DIR" required:"true"`
+  MaxRedeliveries int                 `yaml:"max_redeliveries" env:"MEDIA_MAX_REDELIVERIES" validate:"min=1"`
+  FetchTimeout    time.Duration       `yaml:"fetch_timeout" env:"MEDIA_FETCH_TIMEOUT"`
+  MaxMessageSize  int                 `yaml:"max_message_size" env:"MEDIA_MAX_MESSAGE_SIZE" validate:"min=1"`
+  QueueConfig     *rabbitmq.Config    `yaml:"queue_config" required:"true"`
+  PolygonAuth     *PolygonAuthConfig  `yaml:"polygon_auth"`
+  ObjectStore     *objectstore.Config `yaml:"object_store"`
+  Logging         *logging.Config     `yaml:"logging"`
+  Tracing         *tracing.Config     `yaml:"tracing"`
+}
+
+// PolygonAuthConfig Polygon API credentials for content referenced by Polygon urls, e.g.
+// branding images sent in reference mode. references have no credentials, token is
+// sent only to listed hosts (`api.polygon.io` by default)
+type PolygonAuthConfig struct {
+  ApiToken    string   `yaml:"api_token" env:"MEDIA_POLYGON_API_TOKEN"`
+  ApiAuthMode string   `yaml:"api_auth_mode" env:"MEDIA_POLYGON_API_AUTH_MODE" validate:"oneof=query header"`
+  Hosts       []string `yaml:"hosts"`
+}
+
+// Enabled return true if referenced Polygon content is pulled with credentials
+func (c *PolygonAuthConfig) Enabled() bool {
+  return c != nil && c.ApiToken != ""
+}
+
+// hosts return Polygon hosts of referenced content
+func (c *PolygonAuthConfig) hosts() []string {
+  if c == nil || len(c.Hosts) == 0 {
+    return []string{defaultPolygonHost}
+  }
+  return c.Hosts
+}
+
+func NewConfig() *Config {
+  return &Config{}
+}
+
+func (c *Config) Parse(configPath string) error {
+  if c == nil {
+    return fmt.Errorf("media config is a nil")
+  }
+  if err := config.ParseYamlConfig(configPath, c); err != nil {
+    return fmt.Errorf("cannot parse yaml config: %v", err)
+  }
+  return validation.ValidateStruct(c)
+}
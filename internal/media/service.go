@@ -0,0 +1,283 @@
+package media
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "net/http"
+  "net/url"
+  "os"
+  "path/filepath"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/httpclient"
+  "scientific-research/internal/objectstore"
+  "scientific-research/internal/queue"
+  "scientific-research/pkg/utils/logging"
+  "scientific-research/pkg/utils/retries"
+  "strings"
+  "time"
+)
+
+var log = logging.Logger("media")
+
+const (
+  defaultFetchTimeout   = 30 * time.Second
+  defaultMaxMessageSize = 16 * 1024 * 1024 // bytes
+  maxRedirects          = 10
+  // failed fetches are retried by queue redeliveries
+  fetchRetryCount = 1
+
+  schemeFile  = "file"
+  schemeHttp  = "http"
+  schemeHttps = "https"
+
+  polygonApiTokenKey    = "apiKey"
+  polygonAuthModeHeader = "header"
+  defaultPolygonHost    = "api.polygon.io"
+)
+
+// Service media service. consumes put messages from media service queue
+// and stores their content on local disk
+type Service struct {
+  consumer queue.MediaServiceConsumer
+  store    Store
+  client   *httpclient.Client
+  // polygonClient client with Polygon credentials, nil if they are not configured
+  polygonClient *httpclient.Client
+  polygonHosts  map[string]bool
+  // allowedHosts hosts of http(s) references: Polygon hosts and object store host
+  allowedHosts map[string]bool
+  // objectStoreDir real path of `file://` references dir, empty if file references are not allowed
+  objectStoreDir string
+  maxMessageSize int64
+}
+
+func NewService(ctx context.Context, config *Config) (*Service, error) {
+  if config == nil {
+    return nil, fmt.Errorf("media config is a nil")
+  }
+  store, err := NewStore(config.Dir)
+  if err != nil {
+    return nil, fmt.Errorf("cannot create media store: %v", err)
+  }
+  consumer, err := queue.NewMediaServiceConsumer(ctx, config.QueueConfig, config.MaxRedeliveries)
+  if err != nil {
+    return nil, fmt.Errorf("cannot create media service consumer: %v", err)
+  }
+  service, err := newService(ctx, config, store, consumer)
+  if err != nil {
+    _ = consumer.Close()
+    return nil, err
+  }
+  return service, nil
+}
+
+// newService create service which stores content of consumed messages to store
+func newService(ctx context.Context, config *Config, store Store, consumer queue.MediaServiceConsumer) (*Service, error) {
+  fetchTimeout := config.FetchTimeout
+  if fetchTimeout <= 0 {
+    fetchTimeout = defaultFetchTimeout
+  }
+  maxMessageSize := config.MaxMessageSize
+  if maxMessageSize <= 0 {
+    maxMessageSize = defaultMaxMessageSize
+  }
+  service := &Service{
+    consumer:       consumer,
+    store:          store,
+    allowedHosts:   hostsSet(config.PolygonAuth.hosts()),
+    maxMessageSize: int64(maxMessageSize),
+  }
+  if err := service.allowObjectStore(config.ObjectStore); err != nil {
+    return nil, err
+  }
+  clientOptions := []httpclient.Options{
+    httpclient.WithContext(ctx),
+    httpclient.WithTimeout(fetchTimeout),
+    httpclient.WithRetries(&retries.Option{RetryCount: fetchRetryCount}),
+    httpclient.WithMaxResponseSize(service.maxMessageSize),
+    httpclient.WithCheckRedirect(service.checkRedirect),
+  }
+  service.client = httpclient.NewClient(clientOptions...)
+
+  if config.PolygonAuth.Enabled() {
+    service.polygonClient = httpclient.NewClient(append(clientOptions, newPolygonTokenOption(config.PolygonAuth))...)
+    service.polygonHosts = hostsSet(config.PolygonAuth.hosts())
+  }
+  return service, nil
+}
+
+// allowObjectStore allow references to files of object store dir and urls of its base url host
+func (s *Service) allowObjectStore(config *objectstore.Config) error {
+  if config == nil {
+    return nil
+  }
+  if config.BaseUrl != "" {
+    baseURL, err := url.Parse(config.BaseUrl)
+    if err != nil {
+      return fmt.Errorf("cannot parse object store base url: %v", err)
+    }
+    s.allowedHosts[strings.ToLower(baseURL.Hostname())] = true
+  }
+  dir, err := filepath.Abs(config.Dir)
+  if err != nil {
+    return fmt.Errorf("cannot get absolute path of object store dir: %v", err)
+  }
+  if err = os.MkdirAll(dir, 0o755); err != nil {
+    return fmt.Errorf("cannot create object store dir: %v", err)
+  }
+  // referenced files are compared with dir by real paths, so symlinks cannot point outside of it
+  if s.objectStoreDir, err = filepath.EvalSymlinks(dir); err != nil {
+    return fmt.Errorf("cannot resolve object store dir: %v", err)
+  }
+  return nil
+}
+
+func newPolygonTokenOption(config *PolygonAuthConfig) httpclient.Options {
+  if config.ApiAuthMode == polygonAuthModeHeader {
+    return httpclient.WithBearerToken(config.ApiToken)
+  }
+  return httpclient.WithApiToken(polygonApiTokenKey, config.ApiToken)
+}
+
+func hostsSet(hosts []string) map[string]bool {
+  set := map[string]bool{}
+  for _, host := range hosts {
+    set[strings.ToLower(strings.TrimSpace(host))] = true
+  }
+  return set
+}
+
+// checkRedirect stop redirects to hosts which are not allowed for references
+func (s *Service) checkRedirect(req *http.Request, via []*http.Request) error {
+  if len(via) >= maxRedirects {
+    return fmt.Errorf("stopped after %d redirects", maxRedirects)
+  }
+  if !s.allowedHosts[strings.ToLower(req.URL.Hostname())] {
+    return fmt.Errorf("redirect to not allowed host '%s'", req.URL.Hostname())
+  }
+  return nil
+}
+
+// clientFor return client for referenced url. Polygon credentials are sent to Polygon hosts only
+func (s *Service) clientFor(refURL *url.URL) *httpclient.Client {
+  if s.polygonClient != nil && s.polygonHosts[strings.ToLower(refURL.Hostname())] {
+    return s.polygonClient
+  }
+  return s.client
+}
+
+// ContinuouslyConsume handle messages until service closed
+func (s *Service) ContinuouslyConsume() {
+  s.consumer.ContinuouslyConsume(s.HandleMessage)
+}
+
+// HandleMessage store message content. content referenced by message is pulled
+// and verified with message checksum
+func (s *Service) HandleMessage(message *domain.PutMessage) error {
+  if message == nil || message.MetaInfo == nil {
+    return fmt.Errorf("%w: message or message meta info is a nil", queue.ErrMalformedMessage)
+  }
+  metaInfo := message.MetaInfo
+  content := message.Content
+
+  if int64(len(content)) > s.maxMessageSize {
+    return fmt.Errorf("%w: content is larger than %d bytes", queue.ErrMalformedMessage, s.maxMessageSize)
+  }
+  if metaInfo.ContentRef != "" {
+    var err error
+    if content, err = s.fetchContent(metaInfo.ContentRef); err != nil {
+      return err
+    }
+    if metaInfo.ContentChecksum != "" {
+      if checksum := domain.ContentChecksum(content); checksum != metaInfo.ContentChecksum {
+        return fmt.Errorf("referenced content checksum mismatch: expected '%s', got '%s'",
+          metaInfo.ContentChecksum, checksum)
+      }
+    }
+  }
+  stored, err := s.store.Put(metaInfo, content)
+  if err != nil {
+    return fmt.Errorf("cannot store content of message '%s': %w", metaInfo.MessageId, err)
+  }
+  if !stored {
+    log.Infof("content '%s/%s' already exists and overwrite is not allowed. skip message '%s'",
+      metaInfo.Section, metaInfo.Name, metaInfo.MessageId)
+    return nil
+  }
+  log.Infof("stored content '%s/%s' of message '%s'. size: %d",
+    metaInfo.Section, metaInfo.Name, metaInfo.MessageId, len(content))
+
+  return nil
+}
+
+func (s *Service) fetchContent(contentRef string) ([]byte, error) {
+  refURL, err := url.Parse(contentRef)
+  if err != nil {
+    return nil, fmt.Errorf("%w: cannot parse content reference: %v", queue.ErrMalformedMessage, err)
+  }
+  switch refURL.Scheme {
+  case schemeFile:
+    return s.readObject(refURL.Path)
+
+  case schemeHttp, schemeHttps:
+    if !s.allowedHosts[strings.ToLower(refURL.Hostname())] {
+      return nil, fmt.Errorf("%w: content reference host '%s' is not allowed", queue.ErrMalformedMessage, refURL.Hostname())
+    }
+    content, err := s.clientFor(refURL).Get(contentRef)
+    if err != nil {
+      return nil, fmt.Errorf("cannot get referenced content: %v", err)
+    }
+    return content, nil
+  }
+  return nil, fmt.Errorf("%w: unsupported content reference scheme '%s'", queue.ErrMalformedMessage, refURL.Scheme)
+}
+
+// readObject read referenced file of object store. files outside of object store dir are rejected
+func (s *Service) readObject(filePath string) ([]byte, error) {
+  if s.objectStoreDir == "" {
+    return nil, fmt.Errorf("%w: file references are not allowed without object store", queue.ErrMalformedMessage)
+  }
+  errOutside := fmt.Errorf("%w: referenced file '%s' is outside of object store", queue.ErrMalformedMessage, filePath)
+
+  filePath = filepath.Clean(filePath)
+  if !isInsideDir(s.objectStoreDir, filePath) {
+    return nil, errOutside
+  }
+  realPath, err := filepath.EvalSymlinks(filePath)
+  if err != nil {
+    return nil, fmt.Errorf("cannot resolve referenced file: %v", err)
+  }
+  if !isInsideDir(s.objectStoreDir, realPath) {
+    return nil, errOutside
+  }
+
+  file, err := os.Open(realPath)
+  if err != nil {
+    return nil, fmt.Errorf("cannot open referenced file: %v", err)
+  }
+  defer file.Close()
+
+  // one byte over limit tells that content is too large
+  content, err := io.ReadAll(io.LimitReader(file, s.maxMessageSize+1))
+  if err != nil {
+    return nil, fmt.Errorf("cannot read referenced file: %v", err)
+  }
+  if int64(len(content)) > s.maxMessageSize {
+    return nil, fmt.Errorf("%w: referenced file is larger than %d bytes", queue.ErrMalformedMessage, s.maxMessageSize)
+  }
+  return content, nil
+}
+
+// isInsideDir return true if absolute path is inside of dir
+func isInsideDir(dir, path string) bool {
+  relPath, err := filepath.Rel(dir, path)
+  if err != nil || filepath.IsAbs(relPath) {
+    return false
+  }
+  return relPath != "." && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+func (s *Service) Close() error {
+  return s.consumer.Close()
+}
@@ -0,0 +1,173 @@
+package media
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "os"
+  "path/filepath"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/objectstore"
+  "scientific-research/internal/queue"
+  "strings"
+  "testing"
+)
+
+const testMaxMessageSize = 16
+
+// memStore media store which keeps put content by name
+type memStore map[string][]byte
+
+func (s memStore) Put(metaInfo *domain.PutMessageMetaInfo, content []byte) (bool, error) {
+  s[metaInfo.Name] = content
+  return true, nil
+}
+
+func newTestService(t *testing.T, config *Config) (*Service, memStore) {
+  t.Helper()
+
+  config.MaxMessageSize = testMaxMessageSize
+  store := memStore{}
+  service, err := newService(context.Background(), config, store, nil)
+  if err != nil {
+    t.Fatalf("cannot create media service: %v", err)
+  }
+  return service, store
+}
+
+func writeFile(t *testing.T, path, content string) {
+  t.Helper()
+
+  if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+    t.Fatalf("cannot create dir: %v", err)
+  }
+  if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+    t.Fatalf("cannot write file: %v", err)
+  }
+}
+
+func fileRef(path string) string {
+  return (&url.URL{Scheme: schemeFile, Path: filepath.ToSlash(path)}).String()
+}
+
+func refMessage(contentRef string) *domain.PutMessage {
+  return &domain.PutMessage{
+    MetaInfo: &domain.PutMessageMetaInfo{
+      Name:       "AAPL",
+      Section:    "icon",
+      ContentRef: contentRef,
+    },
+  }
+}
+
+func TestFileReferencesAreReadFromObjectStoreOnly(t *testing.T) {
+  root := t.TempDir()
+  objectsDir := filepath.Join(root, "objects")
+  writeFile(t, filepath.Join(objectsDir, "branding", "AAPL.png"), "image")
+  writeFile(t, filepath.Join(objectsDir, "branding", "large.png"), strings.Repeat("x", testMaxMessageSize+1))
+  writeFile(t, filepath.Join(root, "secret"), "secret")
+  if err := os.Symlink(filepath.Join(root, "secret"), filepath.Join(objectsDir, "link.png")); err != nil {
+    t.Fatalf("cannot create symlink: %v", err)
+  }
+
+  service, store := newTestService(t, &Config{ObjectStore: &objectstore.Config{Dir: objectsDir}})
+
+  if err := service.HandleMessage(refMessage(fileRef(filepath.Join(objectsDir, "branding", "AAPL.png")))); err != nil {
+    t.Fatalf("cannot handle message of object store file: %v", err)
+  }
+  if content := string(store["AAPL"]); content != "image" {
+    t.Errorf("stored content '%s', want referenced file content", content)
+  }
+
+  tests := []struct {
+    name       string
+    contentRef string
+  }{
+    {"outside file", fileRef(filepath.Join(root, "secret"))},
+    {"parent path", "file://" + filepath.ToSlash(objectsDir) + "/../secret"},
+    {"symlink to outside file", fileRef(filepath.Join(objectsDir, "link.png"))},
+    {"object store dir", fileRef(objectsDir)},
+    {"relative path", "file:secret"},
+    {"file larger than max size", fileRef(filepath.Join(objectsDir, "branding", "large.png"))},
+  }
+  for _, test := range tests {
+    err := service.HandleMessage(refMessage(test.contentRef))
+    if !errors.Is(err, queue.ErrMalformedMessage) {
+      t.Errorf("%s: got error %v, want malformed message", test.name, err)
+    }
+  }
+}
+
+func TestFileReferencesNeedObjectStore(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "AAPL.png")
+  writeFile(t, path, "image")
+  service, _ := newTestService(t, &Config{})
+
+  if err := service.HandleMessage(refMessage(fileRef(path))); !errors.Is(err, queue.ErrMalformedMessage) {
+    t.Errorf("got error %v, want malformed message", err)
+  }
+}
+
+func TestHttpReferencesArePulledFromAllowedHosts(t *testing.T) {
+  other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    _, _ = fmt.Fprint(w, "other")
+  }))
+  defer other.Close()
+  otherURL, _ := url.Parse(other.URL)
+
+  srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    switch r.URL.Path {
+    case "/branding/AAPL.png":
+      _, _ = fmt.Fprint(w, "image")
+    case "/branding/large.png":
+      _, _ = fmt.Fprint(w, strings.Repeat("x", testMaxMessageSize+1))
+    case "/branding/moved.png":
+      // the same server by other host name
+      http.Redirect(w, r, fmt.Sprint("http://localhost:", otherURL.Port(), "/"), http.StatusFound)
+    default:
+      http.NotFound(w, r)
+    }
+  }))
+  defer srv.Close()
+
+  service, store := newTestService(t, &Config{
+    ObjectStore: &objectstore.Config{Dir: t.TempDir(), BaseUrl: srv.URL},
+  })
+
+  if err := service.HandleMessage(refMessage(srv.URL + "/branding/AAPL.png")); err != nil {
+    t.Fatalf("cannot handle message of object store url: %v", err)
+  }
+  if content := string(store["AAPL"]); content != "image" {
+    t.Errorf("stored content '%s', want referenced content", content)
+  }
+
+  err := service.HandleMessage(refMessage(fmt.Sprint("http://localhost:", otherURL.Port(), "/")))
+  if !errors.Is(err, queue.ErrMalformedMessage) {
+    t.Errorf("not allowed host: got error %v, want malformed message", err)
+  }
+  if err = service.HandleMessage(refMessage(srv.URL + "/branding/moved.png")); err == nil {
+    t.Errorf("redirect to not allowed host is followed")
+  }
+  if err = service.HandleMessage(refMessage(srv.URL + "/branding/large.png")); err == nil ||
+    !strings.Contains(err.Error(), "larger than") {
+    t.Errorf("large content: got error %v, want size error", err)
+  }
+}
+
+func TestInlineContentLargerThanMaxSize(t *testing.T) {
+  service, store := newTestService(t, &Config{})
+
+  err := service.HandleMessage(&domain.PutMessage{
+    MetaInfo: &domain.PutMessageMetaInfo{Name: "AAPL", Section: "icon"},
+    Content:  []byte(strings.Repeat("x", testMaxMessageSize+1)),
+  })
+  if !errors.Is(err, queue.ErrMalformedMessage) {
+    t.Errorf("got error %v, want malformed message", err)
+  }
+  if len(store) != 0 {
+    t.Errorf("large content is stored")
+  }
+}
@@ -0,0 +1,109 @@
+package media
+
+import (
+  "errors"
+  "fmt"
+  "mime"
+  "os"
+  "path/filepath"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/queue"
+  "strings"
+)
+
+// knownExtensions preferred file extensions of content types. mime package
+// returns extensions in alphabetical order, e.g. `.jfif` for jpeg
+var knownExtensions = map[string]string{
+  "image/png":     ".png",
+  "image/jpeg":    ".jpg",
+  "image/svg+xml": ".svg",
+  "image/gif":     ".gif",
+  "image/webp":    ".webp",
+}
+
+// Store media content storage
+type Store interface {
+  // Put store content by message section and name. returns false if content
+  // exists and message does not allow overwriting
+  Put(metaInfo *domain.PutMessageMetaInfo, content []byte) (bool, error)
+}
+
+// diskStore store content in `<dir>/<section>/<name><ext>` files,
+// extension is chosen by content type
+type diskStore struct {
+  dir string
+}
+
+func NewStore(dir string) (Store, error) {
+  dir, err := filepath.Abs(dir)
+  if err != nil {
+    return nil, fmt.Errorf("cannot get absolute path of media dir: %v", err)
+  }
+  if err = os.MkdirAll(dir, 0o755); err != nil {
+    return nil, fmt.Errorf("cannot create media dir: %v", err)
+  }
+  log.Infof("init media store in '%s'", dir)
+
+  return &diskStore{dir: dir}, nil
+}
+
+func (s *diskStore) Put(metaInfo *domain.PutMessageMetaInfo, content []byte) (bool, error) {
+  if metaInfo == nil {
+    return false, fmt.Errorf("message meta info is a nil")
+  }
+  section, err := checkPathElement(metaInfo.Section)
+  if err != nil {
+    return false, fmt.Errorf("%w: malformed section: %v", queue.ErrMalformedMessage, err)
+  }
+  name, err := checkPathElement(metaInfo.Name)
+  if err != nil {
+    return false, fmt.Errorf("%w: malformed name: %v", queue.ErrMalformedMessage, err)
+  }
+  filePath := filepath.Join(s.dir, section, fmt.Sprint(name, extensionByType(metaInfo.ContentType)))
+
+  if !metaInfo.Overwrite {
+    _, err = os.Stat(filePath)
+    if err == nil {
+      return false, nil
+    }
+    if !errors.Is(err, os.ErrNotExist) {
+      return false, fmt.Errorf("cannot stat media file: %v", err)
+    }
+  }
+  if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+    return false, fmt.Errorf("cannot create media section dir: %v", err)
+  }
+  // write to temporary file first, so readers never see partial files
+  tmpPath := fmt.Sprint(filePath, ".tmp")
+  if err = os.WriteFile(tmpPath, content, 0o644); err != nil {
+    return false, fmt.Errorf("cannot write media file: %v", err)
+  }
+  if err = os.Rename(tmpPath, filePath); err != nil {
+    return false, fmt.Errorf("cannot move media file: %v", err)
+  }
+  return true, nil
+}
+
+// checkPathElement check section or name is a single path element
+func checkPathElement(element string) (string, error) {
+  element = strings.TrimSpace(element)
+  if element == "" || element == "." || element == ".." || strings.ContainsAny(element, `/\`) {
+    return "", fmt.Errorf("'%s' is not a valid path element", element)
+  }
+  return element, nil
+}
+
+func extensionByType(contentType string) string {
+  mediaType, _, err := mime.ParseMediaType(contentType)
+  if err != nil {
+    return ""
+  }
+  if ext, ok := knownExtensions[mediaType]; ok {
+    return ext
+  }
+  exts, err := mime.ExtensionsByType(mediaType)
+  if err != nil || len(exts) == 0 {
+    return ""
+  }
+  return exts[0]
+}
@@ -0,0 +1,6 @@
+package objectstore
+
+type Config struct {
+  Dir     string `yaml:"dir" env:"OBJECT_STORE_DIR" required:"true"`
+  BaseUrl string `yaml:"base_url" env:"OBJECT_STORE_BASE_URL" validate:"url"`
+}
@@ -0,0 +1,71 @@
+package objectstore
+
+import (
+  "fmt"
+  "net/url"
+  "os"
+  "path"
+  "path/filepath"
+  "scientific-research/pkg/utils/logging"
+  "strings"
+)
+
+var log = logging.Logger("objectstore")
+
+// Store object store for payloads which are too large to send through the queue
+type Store interface {
+  // Put store content by key and return reference URL to it
+  Put(key, contentType string, content []byte) (string, error)
+}
+
+// localStore object store on local filesystem. stand-in for object-store-compatible
+// storage: objects are served by any static file server from base url
+type localStore struct {
+  dir     string
+  baseURL string
+}
+
+func NewStore(config *Config) (Store, error) {
+  if config == nil {
+    return nil, fmt.Errorf("object store config is a nil")
+  }
+  dir, err := filepath.Abs(config.Dir)
+  if err != nil {
+    return nil, fmt.Errorf("cannot get absolute path of object store dir: %v", err)
+  }
+  if err = os.MkdirAll(dir, 0o755); err != nil {
+    return nil, fmt.Errorf("cannot create object store dir: %v", err)
+  }
+  log.Infof("init local object store in '%s'", dir)
+
+  return &localStore{
+    dir:     dir,
+    baseURL: strings.TrimSuffix(config.BaseUrl, "/"),
+  }, nil
+}
+
+func (s *localStore) Put(key, _ string, content []byte) (string, error) {
+  key = path.Clean(strings.TrimPrefix(key, "/"))
+  if key == "." || strings.HasPrefix(key, "..") {
+    return "", fmt.Errorf("malformed object key: '%s'", key)
+  }
+  objectPath := filepath.Join(s.dir, filepath.FromSlash(key))
+
+  if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+    return "", fmt.Errorf("cannot create object dir: %v", err)
+  }
+  // write to temporary file first, so readers never see partial objects
+  tmpPath := fmt.Sprint(objectPath, ".tmp")
+  if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+    return "", fmt.Errorf("cannot write object: %v", err)
+  }
+  if err := os.Rename(tmpPath, objectPath); err != nil {
+    return "", fmt.Errorf("cannot move object: %v", err)
+  }
+
+  if s.baseURL != "" {
+    return fmt.Sprint(s.baseURL, "/", key), nil
+  }
+  fileURL := &url.URL{Scheme: "file", Path: filepath.ToSlash(objectPath)}
+  return fileURL.String(), nil
+}
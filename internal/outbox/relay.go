@@ -0,0 +1,136 @@
+package outbox
+
+import (
+  "context"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/queue"
+  "scientific-research/internal/storage"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
+  "time"
+
+  "go.opentelemetry.io/otel/attribute"
+)
+
+var (
+  log    = logging.Logger("outbox")
+  tracer = tracing.Tracer("outbox")
+)
+
+const (
+  relayBatchSize     = 100
+  relayIdleInterval  = 10 * time.Second
+  relayErrorInterval = 1 * time.Minute
+  // relayClaimLease lease of claimed batch, it must cover publishing of the whole batch
+  relayClaimLease = 5 * time.Minute
+  // relayMaxAttempts attempts of message before it becomes dead letter
+  relayMaxAttempts = 10
+)
+
+// Relay publish pending outbox messages to media service queue and mark them sent.
+// message can be published more than once if marking failed (at-least-once delivery).
+// relays of all replicas may run at once, every batch is claimed by single relay
+type Relay struct {
+  ctx     context.Context
+  storage storage.Storage
+  msQueue queue.MediaServiceQueue
+}
+
+func NewRelay(ctx context.Context, storage storage.Storage, msQueue queue.MediaServiceQueue) *Relay {
+  return &Relay{
+    ctx:     ctx,
+    storage: storage,
+    msQueue: msQueue,
+  }
+}
+
+// ContinuouslyRelay relay pending messages until context done
+func (r *Relay) ContinuouslyRelay() {
+  for {
+    sent, err := r.relayBatch()
+    waitInterval := time.Duration(0)
+
+    switch {
+    case err != nil:
+      log.Errorf("outbox relay error: %v. wait %v before the next try", err, relayErrorInterval)
+      waitInterval = relayErrorInterval
+    case sent == 0:
+      waitInterval = relayIdleInterval
+    }
+
+    select {
+    case <-r.ctx.Done():
+      return
+    case <-time.After(waitInterval):
+    }
+  }
+}
+
+// RelayPending relay pending messages until outbox is empty. return count of sent messages
+func (r *Relay) RelayPending() (int, error) {
+  var total int
+  for {
+    sent, err := r.relayBatch()
+    total += sent
+    if err != nil || sent == 0 {
+      return total, err
+    }
+  }
+}
+
+// relayBatch publish single batch of pending messages. return count of sent messages.
+// every batch has own correlation id in log entries and own trace. published messages
+// carry trace of batch, not trace of fetching cycle which stored them
+func (r *Relay) relayBatch() (sent int, err error) {
+  ctx, span := tracer.Start(logging.WithCorrelationId(r.ctx), "outbox relay batch")
+  defer func() {
+    span.SetAttributes(attribute.Int("outbox.sent", sent))
+    tracing.End(span, err)
+  }()
+  batchStorage := r.storage.WithContext(ctx)
+  logger := logging.FromContext(ctx, log)
+
+  messages, err := batchStorage.ClaimOutboxMessages(relayBatchSize, relayClaimLease)
+  if err != nil {
+    return 0, err
+  }
+
+  for idx, message := range messages {
+    if err = r.msQueue.PublishMessage(ctx, message.Message); err != nil {
+      dead, markErr := batchStorage.MarkOutboxMessageFailed(message.MessageId, err.Error(), relayMaxAttempts)
+      if markErr != nil {
+        logger.Errorf("cannot mark outbox message %d failed: %v", message.MessageId, markErr)
+      }
+      if dead {
+        // dead letter must not block the rest of outbox
+        logger.Errorf("outbox message %d failed %d attempts, moved to dead letters: %v",
+          message.MessageId, relayMaxAttempts, err)
+        continue
+      }
+      // keep order of messages. the rest of batch will be sent on the next try
+      r.release(ctx, messages[idx+1:])
+      return sent, err
+    }
+    if err = batchStorage.MarkOutboxMessageSent(message.MessageId); err != nil {
+      r.release(ctx, messages[idx+1:])
+      return sent, err
+    }
+    sent++
+  }
+  if sent != 0 {
+    logger.Infof("outbox relay sent %d messages", sent)
+  }
+  return sent, nil
+}
+
+// release claim of not published messages of batch, errors are logged only. claim expires
+// after lease if release failed
+func (r *Relay) release(ctx context.Context, messages []*domain.OutboxMessage) {
+  messageIds := make([]int64, 0, len(messages))
+  for _, message := range messages {
+    messageIds = append(messageIds, message.MessageId)
+  }
+  if err := r.storage.WithContext(ctx).ReleaseOutboxMessages(messageIds); err != nil {
+    logging.FromContext(ctx, log).Errorf("cannot release %d claimed outbox messages: %v", len(messageIds), err)
+  }
+}
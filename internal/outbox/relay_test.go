@@ -0,0 +1,205 @@
+package outbox
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/storage"
+  "sync"
+  "testing"
+  "time"
+)
+
+// memOutbox in-memory outbox storage
+type memOutbox struct {
+  storage.Storage
+
+  mu       sync.Mutex
+  messages []*memMessage
+}
+
+type memMessage struct {
+  message  *domain.OutboxMessage
+  claimed  bool
+  sent     bool
+  dead     bool
+  attempts int
+}
+
+func newMemOutbox(names ...string) *memOutbox {
+  s := &memOutbox{}
+  for idx, name := range names {
+    s.messages = append(s.messages, &memMessage{
+      message: &domain.OutboxMessage{
+        MessageId: int64(idx + 1),
+        Message: &domain.PutMessage{
+          MetaInfo: &domain.PutMessageMetaInfo{Name: name},
+        },
+      },
+    })
+  }
+  return s
+}
+
+func (s *memOutbox) WithContext(ctx context.Context) storage.Storage {
+  return s
+}
+
+func (s *memOutbox) ClaimOutboxMessages(limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  var claimed []*domain.OutboxMessage
+  for _, m := range s.messages {
+    if len(claimed) == limit {
+      break
+    }
+    if m.sent || m.dead || m.claimed {
+      continue
+    }
+    m.claimed = true
+    message := *m.message
+    message.Attempts = m.attempts
+    claimed = append(claimed, &message)
+  }
+  return claimed, nil
+}
+
+func (s *memOutbox) MarkOutboxMessageSent(messageId int64) error {
+  m := s.get(messageId)
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  m.sent = true
+  m.claimed = false
+  m.attempts++
+  return nil
+}
+
+func (s *memOutbox) MarkOutboxMessageFailed(messageId int64, reason string, maxAttempts int) (bool, error) {
+  m := s.get(messageId)
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  m.claimed = false
+  m.attempts++
+  m.dead = m.attempts >= maxAttempts
+  return m.dead, nil
+}
+
+func (s *memOutbox) ReleaseOutboxMessages(messageIds []int64) error {
+  for _, messageId := range messageIds {
+    m := s.get(messageId)
+
+    s.mu.Lock()
+    m.claimed = false
+    s.mu.Unlock()
+  }
+  return nil
+}
+
+func (s *memOutbox) get(messageId int64) *memMessage {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.messages[messageId-1]
+}
+
+// memQueue queue which records published message names and fails messages from failing
+type memQueue struct {
+  failing   map[string]bool
+  published []string
+}
+
+func (q *memQueue) PublishMessage(ctx context.Context, message *domain.PutMessage) error {
+  if q.failing[message.MetaInfo.Name] {
+    return fmt.Errorf("message '%s' rejected", message.MetaInfo.Name)
+  }
+  q.published = append(q.published, message.MetaInfo.Name)
+  return nil
+}
+
+func equalNames(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for idx := range a {
+    if a[idx] != b[idx] {
+      return false
+    }
+  }
+  return true
+}
+
+func TestRelayPendingPublishesInOrder(t *testing.T) {
+  outboxStorage := newMemOutbox("a", "b", "c")
+  msQueue := &memQueue{}
+  relay := NewRelay(context.Background(), outboxStorage, msQueue)
+
+  sent, err := relay.RelayPending()
+  if err != nil {
+    t.Fatalf("cannot relay: %v", err)
+  }
+  if sent != 3 {
+    t.Errorf("sent %d messages, want 3", sent)
+  }
+  if want := []string{"a", "b", "c"}; !equalNames(msQueue.published, want) {
+    t.Errorf("published %v, want %v", msQueue.published, want)
+  }
+  if sent, err = relay.RelayPending(); err != nil || sent != 0 {
+    t.Errorf("sent messages are relayed again: %d, %v", sent, err)
+  }
+}
+
+func TestRelayStopsOnFailureAndReleasesBatch(t *testing.T) {
+  outboxStorage := newMemOutbox("a", "b", "c")
+  msQueue := &memQueue{failing: map[string]bool{"b": true}}
+  relay := NewRelay(context.Background(), outboxStorage, msQueue)
+
+  sent, err := relay.relayBatch()
+  if err == nil {
+    t.Fatalf("failed message is not reported")
+  }
+  if sent != 1 {
+    t.Errorf("sent %d messages, want 1", sent)
+  }
+  if want := []string{"a"}; !equalNames(msQueue.published, want) {
+    t.Errorf("published %v, want %v", msQueue.published, want)
+  }
+  // message after failed one is not sent out of order and not left claimed
+  if m := outboxStorage.get(3); m.sent || m.claimed {
+    t.Errorf("message after failed one is sent: %t, claimed: %t", m.sent, m.claimed)
+  }
+
+  msQueue.failing = nil
+  if _, err = relay.RelayPending(); err != nil {
+    t.Fatalf("cannot relay: %v", err)
+  }
+  if want := []string{"a", "b", "c"}; !equalNames(msQueue.published, want) {
+    t.Errorf("published %v, want %v", msQueue.published, want)
+  }
+}
+
+func TestRelayMovesMessageToDeadLetters(t *testing.T) {
+  outboxStorage := newMemOutbox("a", "poison", "c")
+  msQueue := &memQueue{failing: map[string]bool{"poison": true}}
+  relay := NewRelay(context.Background(), outboxStorage, msQueue)
+
+  for attempt := 1; attempt < relayMaxAttempts; attempt++ {
+    if _, err := relay.relayBatch(); err == nil {
+      t.Fatalf("attempt %d: failed message is not reported", attempt)
+    }
+  }
+  // the last attempt moves message to dead letters and the rest of outbox is published
+  if _, err := relay.relayBatch(); err != nil {
+    t.Fatalf("cannot relay after dead letter: %v", err)
+  }
+  if want := []string{"a", "c"}; !equalNames(msQueue.published, want) {
+    t.Errorf("published %v, want %v", msQueue.published, want)
+  }
+  if m := outboxStorage.get(2); !m.dead || m.attempts != relayMaxAttempts {
+    t.Errorf("message is dead: %t after %d attempts, want dead after %d", m.dead, m.attempts, relayMaxAttempts)
+  }
+}
@@ -0,0 +1,119 @@
+package queue
+
+import (
+  "context"
+  "scientific-research/internal/queue/rabbitmq"
+  "sync"
+  "time"
+
+  ampq "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+  defaultBufferSize    = 1000
+  bufferReplayInterval = 1 * time.Minute
+)
+
+type bufferedPublishing struct {
+  exchange   string
+  key        string
+  publishing ampq.Publishing
+}
+
+// publishBuffer bounded local buffer of publishings which failed to publish.
+// oldest publishings are dropped when buffer is full
+type publishBuffer struct {
+  mu    sync.Mutex
+  size  int
+  items []*bufferedPublishing
+}
+
+func newPublishBuffer(size int) *publishBuffer {
+  return &publishBuffer{
+    size: size,
+  }
+}
+
+// push add publishing to the end of buffer. return true if the oldest one was dropped
+func (b *publishBuffer) push(item *bufferedPublishing) bool {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+
+  dropped := false
+  if len(b.items) >= b.size {
+    b.items = b.items[1:]
+    dropped = true
+  }
+  b.items = append(b.items, item)
+  return dropped
+}
+
+// pushFront return not replayed publishings to the beginning of buffer keeping their order
+func (b *publishBuffer) pushFront(items []*bufferedPublishing) {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+
+  b.items = append(items, b.items...)
+  if overflow := len(b.items) - b.size; overflow > 0 {
+    b.items = b.items[overflow:]
+  }
+}
+
+// drain take all buffered publishings
+func (b *publishBuffer) drain() []*bufferedPublishing {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+
+  items := b.items
+  b.items = nil
+  return items
+}
+
+func (b *publishBuffer) len() int {
+  b.mu.Lock()
+  defer b.mu.Unlock()
+  return len(b.items)
+}
+
+// continuouslyReplay replay buffered publishings after reconnect and periodically
+//   target publishing target name for logs
+func continuouslyReplay(ctx context.Context, mq rabbitmq.Client, buffer *publishBuffer, target string) {
+  reconnected := mq.NotifyReconnect(make(chan struct{}, 1))
+  ticker := time.NewTicker(bufferReplayInterval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-reconnected:
+    case <-ticker.C:
+    }
+    replayBuffered(ctx, mq, buffer, target)
+  }
+}
+
+func replayBuffered(ctx context.Context, mq rabbitmq.Client, buffer *publishBuffer, target string) {
+  items := buffer.drain()
+  if len(items) == 0 {
+    return
+  }
+  log.Infof("replay %d buffered messages to %s", len(items), target)
+
+  for idx, item := range items {
+    if err := mq.PublishWithContext(
+      ctx,
+      item.exchange,
+      item.key,
+      publishMandatory,
+      publishImmediate,
+      item.publishing,
+    ); err != nil {
+      buffer.pushFront(items[idx:])
+      log.Warnf("replay of buffered messages to %s stopped: %v. left: %d",
+        target, err, buffer.len())
+      return
+    }
+  }
+  log.Infof("all buffered messages replayed to %s", target)
+}
@@ -0,0 +1,225 @@
+package queue
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/domain/envelope"
+  "scientific-research/internal/queue/rabbitmq"
+  "scientific-research/internal/tracing"
+  "time"
+
+  ampq "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+  consumerTag       = ""
+  consumerAutoAck   = false
+  consumerExclusive = false
+  consumerNoLocal   = false
+
+  headerRedeliveryCount  = "x-redelivery-count"
+  headerRedeliveryReason = "x-redelivery-reason"
+  deadQueueSuffix        = ".dead"
+
+  defaultMaxRedeliveries = 5
+  consumeWaitInterval    = 10 * time.Second
+)
+
+// ErrMalformedMessage message can never be handled, it is moved to dead queue without redeliveries
+var ErrMalformedMessage = errors.New("malformed message")
+
+// MessageHandler handle decoded message. wrap ErrMalformedMessage to skip redeliveries
+type MessageHandler func(message *domain.PutMessage) error
+
+type MediaServiceConsumer interface {
+  ContinuouslyConsume(handler MessageHandler)
+  Close() error
+}
+
+// mediaServiceConsumer consume media service queue with manual acks.
+// failed message is published to the queue again with incremented redelivery count header
+// and original delivery is acked. after max redeliveries message is moved to dead queue
+type mediaServiceConsumer struct {
+  ctx             context.Context
+  cancel          context.CancelFunc
+  mq              rabbitmq.Client
+  key             string
+  deadKey         string
+  persistent      bool
+  maxRedeliveries int
+}
+
+// NewMediaServiceConsumer create consumer of media service queue and its dead queue
+//   config queue config
+//   maxRedeliveries redeliveries count of failed message before moving it to dead queue.
+//   default value is used if not positive
+func NewMediaServiceConsumer(ctx context.Context, config *rabbitmq.Config, maxRedeliveries int) (MediaServiceConsumer, error) {
+  if config == nil {
+    return nil, fmt.Errorf("queue config is a nil")
+  }
+  mq, err := rabbitmq.NewClient(config)
+  if err != nil {
+    return nil, fmt.Errorf("cannot create new queue client: %v", err)
+  }
+  if maxRedeliveries <= 0 {
+    maxRedeliveries = defaultMaxRedeliveries
+  }
+  deadKey := fmt.Sprint(config.QueueKey, deadQueueSuffix)
+
+  log.Infof("init queue '%s' consumer for media service. dead queue: '%s', max redeliveries: %d",
+    config.QueueKey, deadKey, maxRedeliveries)
+
+  var args ampq.Table
+  for _, key := range []string{config.QueueKey, deadKey} {
+    if _, err = mq.QueueDeclare(
+      key,
+      config.Durable,
+      queueAutoDelete,
+      queueExclusive,
+      queueNoWait,
+      args,
+    ); err != nil {
+      _ = mq.Close()
+      return nil, fmt.Errorf("cannot declare queue '%s': %v", key, err)
+    }
+  }
+  ctx, cancel := context.WithCancel(ctx)
+
+  return &mediaServiceConsumer{
+    ctx:             ctx,
+    cancel:          cancel,
+    mq:              mq,
+    key:             config.QueueKey,
+    deadKey:         deadKey,
+    persistent:      config.Persistent,
+    maxRedeliveries: maxRedeliveries,
+  }, nil
+}
+
+// ContinuouslyConsume consume messages until consumer closed. consuming is restarted
+// after connection recovery
+func (c *mediaServiceConsumer) ContinuouslyConsume(handler MessageHandler) {
+  reconnected := c.mq.NotifyReconnect(make(chan struct{}, 1))
+
+  for {
+    deliveries, err := c.mq.Consume(
+      c.key,
+      consumerTag,
+      consumerAutoAck,
+      consumerExclusive,
+      consumerNoLocal,
+      queueNoWait,
+      nil,
+    )
+    if err != nil {
+      log.Errorf("cannot consume queue '%s': %v", c.key, err)
+    } else {
+      log.Infof("start consuming queue '%s'", c.key)
+      c.consumeDeliveries(deliveries, handler)
+    }
+    select {
+    case <-c.ctx.Done():
+      return
+    case <-reconnected:
+    case <-time.After(consumeWaitInterval):
+    }
+  }
+}
+
+// consumeDeliveries handle deliveries until channel closed or consumer closed
+func (c *mediaServiceConsumer) consumeDeliveries(deliveries <-chan ampq.Delivery, handler MessageHandler) {
+  for {
+    select {
+    case <-c.ctx.Done():
+      return
+    case delivery, ok := <-deliveries:
+      if !ok {
+        log.Warnf("deliveries of queue '%s' stopped", c.key)
+        return
+      }
+      c.handleDelivery(&delivery, handler)
+    }
+  }
+}
+
+func (c *mediaServiceConsumer) handleDelivery(delivery *ampq.Delivery, handler MessageHandler) {
+  _, span := startConsumeSpan(c.ctx, c.key, delivery)
+
+  message, err := envelope.Decode(delivery.Headers, delivery.Body)
+  if err != nil {
+    err = fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+  } else {
+    err = handler(message)
+  }
+  tracing.End(span, err)
+  if err == nil {
+    if err = delivery.Ack(false); err != nil {
+      log.Errorf("cannot ack message '%s': %v", delivery.MessageId, err)
+    }
+    return
+  }
+  redeliveryCount := redeliveryCountOf(delivery)
+
+  if errors.Is(err, ErrMalformedMessage) || redeliveryCount >= c.maxRedeliveries {
+    log.Errorf("cannot handle message '%s' after %d redeliveries: %v. move to dead queue '%s'",
+      delivery.MessageId, redeliveryCount, err, c.deadKey)
+    c.redeliver(delivery, c.deadKey, redeliveryCount, err)
+    return
+  }
+  log.Warnf("cannot handle message '%s': %v. redelivery %d of %d",
+    delivery.MessageId, err, redeliveryCount+1, c.maxRedeliveries)
+  c.redeliver(delivery, c.key, redeliveryCount+1, err)
+}
+
+// redeliver publish copy of delivery to queue and ack original delivery.
+// original delivery is returned to the queue if publishing failed
+func (c *mediaServiceConsumer) redeliver(delivery *ampq.Delivery, key string, redeliveryCount int, reason error) {
+  headers := ampq.Table{}
+  for header, value := range delivery.Headers {
+    headers[header] = value
+  }
+  headers[headerRedeliveryCount] = int64(redeliveryCount)
+  headers[headerRedeliveryReason] = reason.Error()
+
+  deliveryMode := ampq.Transient
+  if c.persistent {
+    deliveryMode = ampq.Persistent
+  }
+  err := c.mq.PublishWithContext(c.ctx, publishExchange, key, publishMandatory, publishImmediate, ampq.Publishing{
+    Headers:       headers,
+    Body:          delivery.Body,
+    ContentType:   delivery.ContentType,
+    MessageId:     delivery.MessageId,
+    CorrelationId: delivery.CorrelationId,
+    DeliveryMode:  deliveryMode,
+    Timestamp:     delivery.Timestamp,
+  })
+  if err != nil {
+    log.Errorf("cannot publish message '%s' to queue '%s': %v. return it to queue '%s'",
+      delivery.MessageId, key, err, c.key)
+    if err = delivery.Nack(false, true); err != nil {
+      log.Errorf("cannot nack message '%s': %v", delivery.MessageId, err)
+    }
+    return
+  }
+  if err = delivery.Ack(false); err != nil {
+    log.Errorf("cannot ack message '%s': %v", delivery.MessageId, err)
+  }
+}
+
+func redeliveryCountOf(delivery *ampq.Delivery) int {
+  switch count := delivery.Headers[headerRedeliveryCount].(type) {
+  case int32:
+    return int(count)
+  case int64:
+    return int(count)
+  }
+  return 0
+}
+
+func (c *mediaServiceConsumer) Close() error {
+  c.cancel()
+  return c.mq.Close()
+}
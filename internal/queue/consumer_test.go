@@ -0,0 +1,172 @@
+package queue
+
+import (
+  "context"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/domain/envelope"
+  "scientific-research/internal/queue/rabbitmq"
+  "testing"
+
+  ampq "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+  testQueueKey     = "media_service_queue"
+  testDeadQueueKey = testQueueKey + deadQueueSuffix
+)
+
+type published struct {
+  key        string
+  publishing ampq.Publishing
+}
+
+// memClient queue client which records published messages. publishing fails if failPublish is set
+type memClient struct {
+  rabbitmq.Client
+
+  failPublish bool
+  published   []*published
+}
+
+func (c *memClient) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg ampq.Publishing) error {
+  if c.failPublish {
+    return fmt.Errorf("channel closed")
+  }
+  c.published = append(c.published, &published{key: key, publishing: msg})
+  return nil
+}
+
+// memAcknowledger record acks and nacks of delivery
+type memAcknowledger struct {
+  acks     int
+  requeues int
+}
+
+func (a *memAcknowledger) Ack(tag uint64, multiple bool) error {
+  a.acks++
+  return nil
+}
+
+func (a *memAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+  if requeue {
+    a.requeues++
+  }
+  return nil
+}
+
+func (a *memAcknowledger) Reject(tag uint64, requeue bool) error {
+  return a.Nack(tag, false, requeue)
+}
+
+func newTestConsumer(mq rabbitmq.Client, maxRedeliveries int) *mediaServiceConsumer {
+  return &mediaServiceConsumer{
+    ctx:             context.Background(),
+    mq:              mq,
+    key:             testQueueKey,
+    deadKey:         testDeadQueueKey,
+    maxRedeliveries: maxRedeliveries,
+  }
+}
+
+// newDelivery return delivery of valid message with redelivery count header if count is positive
+func newDelivery(t *testing.T, ack ampq.Acknowledger, redeliveryCount int64) *ampq.Delivery {
+  t.Helper()
+
+  headers, err := envelope.EncodeHeaders(&domain.PutMessageMetaInfo{Name: "AAPL", Section: "icon"})
+  if err != nil {
+    t.Fatalf("cannot encode headers: %v", err)
+  }
+  if redeliveryCount > 0 {
+    headers[headerRedeliveryCount] = redeliveryCount
+  }
+  return &ampq.Delivery{
+    Acknowledger: ack,
+    Headers:      headers,
+    Body:         []byte("image"),
+    MessageId:    headers[envelope.HeaderMessageId].(string),
+  }
+}
+
+func TestHandleDeliveryAcksHandledMessage(t *testing.T) {
+  mq := &memClient{}
+  ack := &memAcknowledger{}
+  handled := 0
+
+  newTestConsumer(mq, 3).handleDelivery(newDelivery(t, ack, 0), func(message *domain.PutMessage) error {
+    handled++
+    return nil
+  })
+  if handled != 1 || ack.acks != 1 || len(mq.published) != 0 {
+    t.Errorf("handled %d times, acked %d times, published %d copies, want handled and acked once",
+      handled, ack.acks, len(mq.published))
+  }
+}
+
+func TestHandleDeliveryRedeliversFailedMessage(t *testing.T) {
+  tests := []struct {
+    name            string
+    redeliveryCount int64
+    handlerErr      error
+    wantKey         string
+    wantCount       int64
+  }{
+    {"first failure", 0, fmt.Errorf("timeout"), testQueueKey, 1},
+    {"failure before max redeliveries", 2, fmt.Errorf("timeout"), testQueueKey, 3},
+    {"failure after max redeliveries", 3, fmt.Errorf("timeout"), testDeadQueueKey, 3},
+    {"malformed message", 0, fmt.Errorf("%w: bad name", ErrMalformedMessage), testDeadQueueKey, 0},
+  }
+  for _, test := range tests {
+    mq := &memClient{}
+    ack := &memAcknowledger{}
+    delivery := newDelivery(t, ack, test.redeliveryCount)
+
+    newTestConsumer(mq, 3).handleDelivery(delivery, func(message *domain.PutMessage) error {
+      return test.handlerErr
+    })
+    if len(mq.published) != 1 || ack.acks != 1 {
+      t.Errorf("%s: published %d copies, acked %d times, want single copy and ack", test.name, len(mq.published), ack.acks)
+      continue
+    }
+    copied := mq.published[0]
+    if copied.key != test.wantKey {
+      t.Errorf("%s: message is published to '%s', want '%s'", test.name, copied.key, test.wantKey)
+    }
+    if count := copied.publishing.Headers[headerRedeliveryCount]; count != test.wantCount {
+      t.Errorf("%s: redelivery count is %v, want %d", test.name, count, test.wantCount)
+    }
+    if reason := copied.publishing.Headers[headerRedeliveryReason]; reason != test.handlerErr.Error() {
+      t.Errorf("%s: redelivery reason is %v", test.name, reason)
+    }
+    if string(copied.publishing.Body) != "image" || copied.publishing.MessageId != delivery.MessageId {
+      t.Errorf("%s: published copy differs from delivery", test.name)
+    }
+  }
+}
+
+func TestHandleDeliveryMovesUndecodableMessageToDeadQueue(t *testing.T) {
+  mq := &memClient{}
+  ack := &memAcknowledger{}
+  delivery := newDelivery(t, ack, 0)
+  delete(delivery.Headers, envelope.HeaderName)
+
+  newTestConsumer(mq, 3).handleDelivery(delivery, func(message *domain.PutMessage) error {
+    t.Errorf("undecodable message is handled")
+    return nil
+  })
+  if len(mq.published) != 1 || mq.published[0].key != testDeadQueueKey {
+    t.Errorf("undecodable message is not moved to dead queue")
+  }
+}
+
+func TestHandleDeliveryRequeuesWhenRedeliveryFailed(t *testing.T) {
+  mq := &memClient{failPublish: true}
+  ack := &memAcknowledger{}
+
+  newTestConsumer(mq, 3).handleDelivery(newDelivery(t, ack, 0), func(message *domain.PutMessage) error {
+    return fmt.Errorf("timeout")
+  })
+  if ack.acks != 0 || ack.requeues != 1 {
+    t.Errorf("acked %d times, requeued %d times, want message returned to queue", ack.acks, ack.requeues)
+  }
+}
@@ -0,0 +1,195 @@
+package queue
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/internal/queue/rabbitmq"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
+  "strings"
+
+  ampq "github.com/rabbitmq/amqp091-go"
+  "github.com/sirupsen/logrus"
+  "go.opentelemetry.io/otel/trace"
+)
+
+const (
+  exchangeKindTopic  = "topic"
+  exchangeAutoDelete = false
+  exchangeInternal   = false
+
+  eventContentType = "application/json"
+  routingKeySep    = "."
+  // `.` separates routing key words, tickers like `BRK.A` are sent as `BRK_A`
+  routingWordReplacer = "_"
+)
+
+// EventsPublisher publish ingestion events to topic exchange. routing key is
+// `<event type>.<interval>.<ticker>`, e.g. `stock.ingested.1day.AAPL`, so consumers
+// can bind to `stock.ingested.*.AAPL` or `ticker.updated.#`
+type EventsPublisher interface {
+  PublishEvent(ctx context.Context, event *domain.Event) error
+}
+
+type eventsPublisher struct {
+  mq         rabbitmq.Client
+  exchange   string
+  persistent bool
+  buffer     *publishBuffer
+  pending    chan *pendingEvent
+}
+
+// pendingEvent event accepted by PublishEvent and not published yet
+type pendingEvent struct {
+  publishing *bufferedPublishing
+  span       trace.Span
+  logger     *logrus.Entry
+}
+
+func NewEventsPublisher(ctx context.Context, config *rabbitmq.Config) (EventsPublisher, error) {
+  if config == nil {
+    return nil, fmt.Errorf("queue config is a nil")
+  }
+  if config.EventsExchange == "" {
+    return nil, fmt.Errorf("events exchange is not set")
+  }
+  mq, err := rabbitmq.NewClient(config)
+  if err != nil {
+    return nil, fmt.Errorf("cannot create new queue client: %v", err)
+  }
+  log.Infof("init events publisher to exchange '%s'. durable: %t, persistent: %t",
+    config.EventsExchange, config.Durable, config.Persistent)
+
+  var args ampq.Table
+  if err = mq.ExchangeDeclare(
+    config.EventsExchange,
+    exchangeKindTopic,
+    config.Durable,
+    exchangeAutoDelete,
+    exchangeInternal,
+    queueNoWait,
+    args,
+  ); err != nil {
+    _ = mq.Close()
+    return nil, fmt.Errorf("cannot declare events exchange: %v", err)
+  }
+  bufferSize := config.BufferSize
+  if bufferSize <= 0 {
+    bufferSize = defaultBufferSize
+  }
+
+  ep := &eventsPublisher{
+    mq:         mq,
+    exchange:   config.EventsExchange,
+    persistent: config.Persistent,
+    buffer:     newPublishBuffer(bufferSize),
+    pending:    make(chan *pendingEvent, bufferSize),
+  }
+  go ep.continuouslyPublish(ctx)
+  go continuouslyReplay(ctx, mq, ep.buffer, fmt.Sprintf("exchange '%s'", ep.exchange))
+
+  return ep, nil
+}
+
+// PublishEvent queue event for publishing and return without waiting for publisher confirm,
+// so ingestion is not blocked by broker. events are published in order by background
+// goroutine, failed events and events which do not fit the queue are buffered locally
+// and replayed once the broker is back. events are notifications, delivery is best-effort
+func (ep *eventsPublisher) PublishEvent(ctx context.Context, event *domain.Event) error {
+  if event == nil {
+    return fmt.Errorf("event is a nil")
+  }
+  body, err := json.Marshal(event)
+  if err != nil {
+    return fmt.Errorf("cannot marshal event: %v", err)
+  }
+  deliveryMode := ampq.Transient
+  if ep.persistent {
+    deliveryMode = ampq.Persistent
+  }
+  publishing := ampq.Publishing{
+    ContentType:  eventContentType,
+    Type:         event.Type,
+    MessageId:    event.EventId,
+    DeliveryMode: deliveryMode,
+    Timestamp:    event.Timestamp,
+    Body:         body,
+  }
+  key := EventRoutingKey(event)
+
+  // span is ended by publishing goroutine
+  _, span := startPublishSpan(ctx, ep.exchange, key, &publishing)
+  pending := &pendingEvent{
+    publishing: &bufferedPublishing{
+      exchange:   ep.exchange,
+      key:        key,
+      publishing: publishing,
+    },
+    span:   span,
+    logger: logging.FromContext(ctx, log),
+  }
+  select {
+  case ep.pending <- pending:
+  default:
+    err = fmt.Errorf("publishing queue is full")
+    tracing.End(span, err)
+    ep.bufferEvent(pending, err)
+  }
+  return nil
+}
+
+// continuouslyPublish publish queued events with publisher confirms until context is done
+func (ep *eventsPublisher) continuouslyPublish(ctx context.Context) {
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case pending := <-ep.pending:
+      ep.publish(ctx, pending)
+    }
+  }
+}
+
+func (ep *eventsPublisher) publish(ctx context.Context, pending *pendingEvent) {
+  item := pending.publishing
+  err := ep.mq.PublishWithContext(
+    trace.ContextWithSpan(ctx, pending.span),
+    item.exchange,
+    item.key,
+    publishMandatory,
+    publishImmediate,
+    item.publishing,
+  )
+  tracing.End(pending.span, err)
+
+  if err != nil {
+    ep.bufferEvent(pending, err)
+    return
+  }
+  pending.logger.Debugf("event '%s' published to exchange '%s'", item.key, ep.exchange)
+}
+
+func (ep *eventsPublisher) bufferEvent(pending *pendingEvent, err error) {
+  dropped := ep.buffer.push(pending.publishing)
+  pending.logger.Warnf("cannot publish event '%s' to exchange '%s': %v. event buffered",
+    pending.publishing.key, ep.exchange, err)
+
+  if dropped {
+    pending.logger.Errorf("publish buffer of exchange '%s' is full. the oldest event dropped", ep.exchange)
+  }
+}
+
+// EventRoutingKey return routing key of event: `<event type>.<interval>.<ticker>`
+func EventRoutingKey(event *domain.Event) string {
+  return strings.Join([]string{
+    event.Type,
+    routingWord(event.Interval),
+    routingWord(event.TickerId),
+  }, routingKeySep)
+}
+
+func routingWord(value string) string {
+  return strings.ReplaceAll(value, routingKeySep, routingWordReplacer)
+}
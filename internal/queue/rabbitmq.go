@@ -4,15 +4,18 @@ import (
   "context"
   "fmt"
   "scientific-research/internal/domain"
+  "scientific-research/internal/domain/envelope"
   "scientific-research/internal/queue/rabbitmq"
+  "scientific-research/internal/tracing"
+  "scientific-research/pkg/utils/logging"
   "scientific-research/pkg/utils/timeutils"
 
   ampq "github.com/rabbitmq/amqp091-go"
-  log "github.com/sirupsen/logrus"
 )
 
+var log = logging.Logger("queue")
+
 const (
-  queueDurable    = false
   queueAutoDelete = false
   queueExclusive  = false
   queueNoWait     = false
@@ -22,14 +25,18 @@ const (
   publishImmediate = false
 )
 
+// MediaServiceQueue publish messages to media service queue. log entries of publishing
+// have log fields of context (e.g. correlation id of fetching cycle).
+// messages are not buffered locally: durability is provided by outbox, which keeps
+// message until it is published
 type MediaServiceQueue interface {
-  SendMessage(message *domain.PutMessage) error
+  PublishMessage(ctx context.Context, message *domain.PutMessage) error
 }
 
 type mediaServiceQueue struct {
-  ctx context.Context
-  mq  rabbitmq.Client
-  key string
+  mq         rabbitmq.Client
+  key        string
+  persistent bool
 }
 
 func NewMediaServiceQueue(ctx context.Context, config *rabbitmq.Config) (MediaServiceQueue, error) {
@@ -38,11 +45,12 @@ func NewMediaServiceQueue(ctx context.Context, config *rabbitmq.Config) (MediaSe
     return nil, fmt.Errorf("cannot create new queue client: %v", err)
   }
   var args ampq.Table
-  log.Infof("init queue '%s' client for media service", config.QueueKey)
+  log.Infof("init queue '%s' client for media service. durable: %t, persistent: %t",
+    config.QueueKey, config.Durable, config.Persistent)
 
   if _, err = mq.QueueDeclare(
     config.QueueKey,
-    queueDurable,
+    config.Durable,
     queueAutoDelete,
     queueExclusive,
     queueNoWait,
@@ -50,47 +58,76 @@ func NewMediaServiceQueue(ctx context.Context, config *rabbitmq.Config) (MediaSe
   ); err != nil {
     return nil, fmt.Errorf("cannot declare new queue: %v", err)
   }
-
   return &mediaServiceQueue{
-    ctx: ctx,
-    mq:  mq,
-    key: config.QueueKey,
+    mq:         mq,
+    key:        config.QueueKey,
+    persistent: config.Persistent,
   }, nil
 }
 
-func (msq *mediaServiceQueue) SendMessage(message *domain.PutMessage) error {
+// PublishMessage publish message and wait for publisher confirm without local buffering.
+// callers keep undelivered messages themselves (e.g. outbox relay)
+func (msq *mediaServiceQueue) PublishMessage(ctx context.Context, message *domain.PutMessage) error {
   if message == nil {
     return fmt.Errorf("message is a nil")
   }
-  publishing, err := formPublishingFromMessage(message)
+  logger := logging.FromContext(ctx, log)
+
+  publishing, err := formPublishingFromMessage(message, msq.persistent)
   if err != nil {
     return fmt.Errorf("cannot form publishing: %v", err)
   }
+  if err = msq.publish(ctx, *publishing); err != nil {
+    return err
+  }
+  logger.Infof("message with name '%s' for section '%s' send to queue '%s'",
+    message.MetaInfo.Name, message.MetaInfo.Section, msq.key)
+
+  return nil
+}
+
+// publish publish message to queue. trace context is injected into headers of publishing
+func (msq *mediaServiceQueue) publish(ctx context.Context, publishing ampq.Publishing) error {
+  ctx, span := startPublishSpan(ctx, msq.key, msq.key, &publishing)
 
-  if err = msq.mq.PublishWithContext(
-    msq.ctx,
+  err := msq.mq.PublishWithContext(
+    ctx,
     publishExchange,
     msq.key,
     publishMandatory,
     publishImmediate,
-    *publishing,
-  ); err != nil {
+    publishing,
+  )
+  tracing.End(span, err)
+
+  if err != nil {
     return fmt.Errorf("cannot publish message: %v", err)
   }
-  log.Infof("message with name '%s' for section '%s' send to queue '%s'",
-    message.MetaInfo.Name, message.MetaInfo.Section, msq.key)
-
   return nil
 }
 
-func formPublishingFromMessage(message *domain.PutMessage) (*ampq.Publishing, error) {
-  headers, err := message.MetaInfo.MarshalMap()
+func formPublishingFromMessage(message *domain.PutMessage, persistent bool) (*ampq.Publishing, error) {
+  if message == nil {
+    return nil, fmt.Errorf("message is a nil")
+  }
+  headers, err := envelope.EncodeHeaders(message.MetaInfo)
   if err != nil {
-    return nil, fmt.Errorf("cannot marshal message meta info as map: %v", err)
+    return nil, fmt.Errorf("cannot encode message headers: %v", err)
   }
+  deliveryMode := ampq.Transient
+  if persistent {
+    deliveryMode = ampq.Persistent
+  }
+  // message id is generated by encoding if meta info has no id
+  messageId, _ := headers[envelope.HeaderMessageId].(string)
+
   return &ampq.Publishing{
-    Headers:   headers,
-    Body:      message.Content,
-    Timestamp: timeutils.NotTimeUTC(),
+    Headers:       headers,
+    Body:          message.Content,
+    ContentType:   message.MetaInfo.ContentType,
+    MessageId:     messageId,
+    CorrelationId: message.MetaInfo.CorrelationId,
+    DeliveryMode:  deliveryMode,
+    Timestamp:     timeutils.NotTimeUTC(),
   }, nil
 }
@@ -2,45 +2,310 @@ package rabbitmq
 
 import (
   "context"
+  "errors"
   "fmt"
+  "scientific-research/pkg/utils/logging"
   "scientific-research/pkg/utils/retries"
+  "sync"
+  "time"
 
   ampq "github.com/rabbitmq/amqp091-go"
 )
 
+var log = logging.Logger("rabbitmq")
+
+const (
+  defaultConfirmTimeout = 10 * time.Second
+  reconnectWaitInterval = 10 * time.Second
+)
+
+var ErrNotConnected = errors.New("rabbitmq client not connected")
+
 type Client interface {
   QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args ampq.Table) (ampq.Queue, error)
+  ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args ampq.Table) error
   PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg ampq.Publishing) error
   Consume(queue, cons string, autoAck, excl, noLocal, noWait bool, args ampq.Table) (<-chan ampq.Delivery, error)
+  NotifyReconnect(receiver chan struct{}) chan struct{}
+  Close() error
+}
+
+type exchangeDeclaration struct {
+  name       string
+  kind       string
+  durable    bool
+  autoDelete bool
+  internal   bool
+  noWait     bool
+  args       ampq.Table
+}
+
+type queueDeclaration struct {
+  name       string
+  durable    bool
+  autoDelete bool
+  exclusive  bool
+  noWait     bool
+  args       ampq.Table
+}
+
+// client channel in publisher confirms mode. connection and channel are
+// recovered automatically when broker closes them, declared exchanges and queues are declared again
+type client struct {
+  strConn        string
+  confirmTimeout time.Duration
+
+  mu        sync.RWMutex
+  conn      *ampq.Connection
+  ch        *ampq.Channel
+  exchanges []*exchangeDeclaration
+  queues    []*queueDeclaration
+  receivers []chan struct{}
+  closed    bool
 }
 
 func NewClient(config *Config) (Client, error) {
-  strConn := config.ConnectString()
+  c := &client{
+    strConn:        config.ConnectString(),
+    confirmTimeout: defaultConfirmTimeout,
+  }
+  if config.ConfirmTimeout > 0 {
+    c.confirmTimeout = config.ConfirmTimeout
+  }
+  conn, ch, err := c.connect()
+  if err != nil {
+    return nil, err
+  }
+  c.conn = conn
+  c.ch = ch
+
+  go c.continuouslyRecover(conn, ch)
 
+  return c, nil
+}
+
+func (c *client) connect() (*ampq.Connection, *ampq.Channel, error) {
   var (
     conn *ampq.Connection
     ch   *ampq.Channel
     err  error
   )
   err = retries.DoWithRetry(func() error {
-    if conn, err = ampq.Dial(strConn); err != nil {
+    if conn, err = ampq.Dial(c.strConn); err != nil {
       return fmt.Errorf("cannot connect to rabbitmq: %v", err)
     }
     return nil
   })
   if err != nil {
-    return nil, fmt.Errorf("connection to rabbitmq failed: %v", err)
+    return nil, nil, fmt.Errorf("connection to rabbitmq failed: %v", err)
   }
 
   err = retries.DoWithRetry(func() error {
     if ch, err = conn.Channel(); err != nil {
       return fmt.Errorf("cannot open ampq server channel: %v", err)
     }
+    if err = ch.Confirm(false); err != nil {
+      return fmt.Errorf("cannot put channel into confirm mode: %v", err)
+    }
     return nil
   })
   if err != nil {
-    return nil, fmt.Errorf("ampq server channel opening failed: %v", err)
+    _ = conn.Close()
+    return nil, nil, fmt.Errorf("ampq server channel opening failed: %v", err)
+  }
+
+  return conn, ch, nil
+}
+
+// continuouslyRecover wait for connection or channel close and reconnect until client closed
+func (c *client) continuouslyRecover(conn *ampq.Connection, ch *ampq.Channel) {
+  for {
+    connClosed := conn.NotifyClose(make(chan *ampq.Error, 1))
+    chClosed := ch.NotifyClose(make(chan *ampq.Error, 1))
+
+    var reason *ampq.Error
+    select {
+    case reason = <-connClosed:
+    case reason = <-chClosed:
+    }
+    if c.isClosed() {
+      return
+    }
+    log.Warnf("rabbitmq connection lost: %v. reconnecting", reason)
+
+    c.mu.Lock()
+    c.ch = nil
+    c.mu.Unlock()
+    _ = conn.Close()
+
+    for {
+      var err error
+      if conn, ch, err = c.connect(); err == nil {
+        if err = c.redeclare(ch); err == nil {
+          break
+        }
+        _ = conn.Close()
+      }
+      if c.isClosed() {
+        return
+      }
+      log.Errorf("rabbitmq reconnection failed: %v. wait %v before the next try", err, reconnectWaitInterval)
+      time.Sleep(reconnectWaitInterval)
+    }
+
+    c.mu.Lock()
+    if c.closed {
+      c.mu.Unlock()
+      _ = conn.Close()
+      return
+    }
+    c.conn = conn
+    c.ch = ch
+    receivers := c.receivers
+    c.mu.Unlock()
+
+    log.Infof("rabbitmq connection recovered")
+
+    for _, receiver := range receivers {
+      select {
+      case receiver <- struct{}{}:
+      default:
+      }
+    }
+  }
+}
+
+func (c *client) redeclare(ch *ampq.Channel) error {
+  c.mu.RLock()
+  exchanges := c.exchanges
+  queues := c.queues
+  c.mu.RUnlock()
+
+  for _, e := range exchanges {
+    if err := ch.ExchangeDeclare(e.name, e.kind, e.durable, e.autoDelete, e.internal, e.noWait, e.args); err != nil {
+      return fmt.Errorf("cannot declare exchange '%s' again: %v", e.name, err)
+    }
   }
+  for _, q := range queues {
+    if _, err := ch.QueueDeclare(q.name, q.durable, q.autoDelete, q.exclusive, q.noWait, q.args); err != nil {
+      return fmt.Errorf("cannot declare queue '%s' again: %v", q.name, err)
+    }
+  }
+  return nil
+}
 
-  return ch, nil
+func (c *client) channel() (*ampq.Channel, error) {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+
+  if c.ch == nil {
+    return nil, ErrNotConnected
+  }
+  return c.ch, nil
+}
+
+func (c *client) isClosed() bool {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.closed
+}
+
+func (c *client) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args ampq.Table) (ampq.Queue, error) {
+  ch, err := c.channel()
+  if err != nil {
+    return ampq.Queue{}, err
+  }
+  queue, err := ch.QueueDeclare(name, durable, autoDelete, exclusive, noWait, args)
+  if err != nil {
+    return ampq.Queue{}, err
+  }
+  c.mu.Lock()
+  c.queues = append(c.queues, &queueDeclaration{
+    name:       name,
+    durable:    durable,
+    autoDelete: autoDelete,
+    exclusive:  exclusive,
+    noWait:     noWait,
+    args:       args,
+  })
+  c.mu.Unlock()
+
+  return queue, nil
+}
+
+func (c *client) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args ampq.Table) error {
+  ch, err := c.channel()
+  if err != nil {
+    return err
+  }
+  if err = ch.ExchangeDeclare(name, kind, durable, autoDelete, internal, noWait, args); err != nil {
+    return err
+  }
+  c.mu.Lock()
+  c.exchanges = append(c.exchanges, &exchangeDeclaration{
+    name:       name,
+    kind:       kind,
+    durable:    durable,
+    autoDelete: autoDelete,
+    internal:   internal,
+    noWait:     noWait,
+    args:       args,
+  })
+  c.mu.Unlock()
+
+  return nil
+}
+
+// PublishWithContext publish message and wait for broker confirmation with confirm timeout
+func (c *client) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg ampq.Publishing) error {
+  ch, err := c.channel()
+  if err != nil {
+    return err
+  }
+  confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+  if err != nil {
+    return fmt.Errorf("cannot publish: %v", err)
+  }
+  ctx, cancel := context.WithTimeout(ctx, c.confirmTimeout)
+  defer cancel()
+
+  acked, err := confirmation.WaitContext(ctx)
+  if err != nil {
+    return fmt.Errorf("publisher confirm not received in %v: %v", c.confirmTimeout, err)
+  }
+  if !acked {
+    return fmt.Errorf("message nacked by broker")
+  }
+  return nil
+}
+
+func (c *client) Consume(queue, cons string, autoAck, excl, noLocal, noWait bool, args ampq.Table) (<-chan ampq.Delivery, error) {
+  ch, err := c.channel()
+  if err != nil {
+    return nil, err
+  }
+  return ch.Consume(queue, cons, autoAck, excl, noLocal, noWait, args)
+}
+
+// NotifyReconnect register receiver which gets a value after each connection recovery.
+// values are dropped if receiver is not ready
+func (c *client) NotifyReconnect(receiver chan struct{}) chan struct{} {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.receivers = append(c.receivers, receiver)
+  return receiver
+}
+
+func (c *client) Close() error {
+  c.mu.Lock()
+  c.closed = true
+  conn := c.conn
+  c.ch = nil
+  c.mu.Unlock()
+
+  if conn == nil || conn.IsClosed() {
+    return nil
+  }
+  return conn.Close()
 }
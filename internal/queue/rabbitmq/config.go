@@ -1,15 +1,23 @@
 package rabbitmq
 
-import "fmt"
+import (
+  "fmt"
+  "time"
+)
 
 const conn = "amqp://%s:%s@%s:%d/"
 
 type Config struct {
-  User     string `yaml:"user" required:"true"`
-  Password string `yaml:"password" required:"true"`
-  Host     string `yaml:"host" required:"true"`
-  Port     int    `yaml:"port" required:"true"`
-  QueueKey string `yaml:"queue_key" required:"true"`
+  User           string        `yaml:"user" env:"RABBITMQ_USER" required:"true"`
+  Password       string        `yaml:"password" env:"RABBITMQ_PASSWORD" required:"true"`
+  Host           string        `yaml:"host" env:"RABBITMQ_HOST" required:"true"`
+  Port           int           `yaml:"port" env:"RABBITMQ_PORT" required:"true" validate:"min=1,max=65535"`
+  QueueKey       string        `yaml:"queue_key" env:"RABBITMQ_QUEUE_KEY" required:"true"`
+  Durable        bool          `yaml:"durable" env:"RABBITMQ_DURABLE"`
+  Persistent     bool          `yaml:"persistent" env:"RABBITMQ_PERSISTENT"`
+  ConfirmTimeout time.Duration `yaml:"confirm_timeout" env:"RABBITMQ_CONFIRM_TIMEOUT"`
+  BufferSize     int           `yaml:"buffer_size" env:"RABBITMQ_BUFFER_SIZE" validate:"min=1"`
+  EventsExchange string        `yaml:"events_exchange" env:"RABBITMQ_EVENTS_EXCHANGE"`
 }
 
 func (c *Config) ConnectString() string {
@@ -0,0 +1,71 @@
+package queue
+
+import (
+  "context"
+  "scientific-research/internal/tracing"
+
+  ampq "github.com/rabbitmq/amqp091-go"
+  "go.opentelemetry.io/otel"
+  semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+  "go.opentelemetry.io/otel/trace"
+)
+
+const messagingSystem = "rabbitmq"
+
+var tracer = tracing.Tracer("queue")
+
+// headersCarrier AMQP headers as carrier of trace context
+type headersCarrier ampq.Table
+
+func (c headersCarrier) Get(key string) string {
+  value, _ := c[key].(string)
+  return value
+}
+
+func (c headersCarrier) Set(key, value string) {
+  c[key] = value
+}
+
+func (c headersCarrier) Keys() []string {
+  keys := make([]string, 0, len(c))
+  for key := range c {
+    keys = append(keys, key)
+  }
+  return keys
+}
+
+// startPublishSpan start span of publishing and inject trace context into publishing headers,
+// so consumer continues trace of publisher
+//   destination queue or exchange name
+//   routingKey routing key of publishing
+func startPublishSpan(ctx context.Context, destination, routingKey string, publishing *ampq.Publishing) (context.Context, trace.Span) {
+  ctx, span := tracer.Start(ctx, destination+" publish",
+    trace.WithSpanKind(trace.SpanKindProducer),
+    trace.WithAttributes(
+      semconv.MessagingSystem(messagingSystem),
+      semconv.MessagingDestinationName(destination),
+      semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+    ),
+  )
+  if publishing.Headers == nil {
+    publishing.Headers = ampq.Table{}
+  }
+  otel.GetTextMapPropagator().Inject(ctx, headersCarrier(publishing.Headers))
+
+  return ctx, span
+}
+
+// startConsumeSpan start span of delivery handling with trace context of publisher
+//   source queue name
+func startConsumeSpan(ctx context.Context, source string, delivery *ampq.Delivery) (context.Context, trace.Span) {
+  if delivery.Headers != nil {
+    ctx = otel.GetTextMapPropagator().Extract(ctx, headersCarrier(delivery.Headers))
+  }
+  return tracer.Start(ctx, source+" process",
+    trace.WithSpanKind(trace.SpanKindConsumer),
+    trace.WithAttributes(
+      semconv.MessagingSystem(messagingSystem),
+      semconv.MessagingSourceName(source),
+    ),
+  )
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+  "fmt"
+  "time"
+)
+
+// Config schedule of fetcher jobs
+type Config struct {
+  Timezone string       `yaml:"timezone" env:"SCHEDULER_TIMEZONE"`
+  Jobs     []*JobConfig `yaml:"jobs"`
+}
+
+// JobConfig single scheduled job
+type JobConfig struct {
+  Name string `yaml:"name" required:"true"`
+  // Kind what job does, defined by job runner, e.g. `tickers` or `bars`
+  Kind string `yaml:"kind" required:"true"`
+  // Cron cron expression evaluated in job timezone
+  Cron string `yaml:"cron" required:"true"`
+  // Timezone overrides scheduler timezone
+  Timezone string `yaml:"timezone"`
+  // Calendar exchange calendar, runs on days without trading session are skipped
+  Calendar string `yaml:"calendar"`
+  // RunOnStart run job on start if it has never run
+  RunOnStart bool `yaml:"run_on_start"`
+  // RetryInterval interval of the next try after failed run if it comes before scheduled run
+  RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+// Validate check job names, kinds, cron expressions and timezones
+func (c *Config) Validate() error {
+  if c == nil {
+    return nil
+  }
+  if _, err := LoadLocation(c.Timezone); err != nil {
+    return fmt.Errorf("cannot load scheduler timezone: %v", err)
+  }
+  names := map[string]bool{}
+  for idx, job := range c.Jobs {
+    if job == nil || job.Name == "" || job.Kind == "" {
+      return fmt.Errorf("job %d must have name and kind", idx)
+    }
+    if names[job.Name] {
+      return fmt.Errorf("job '%s' is duplicated", job.Name)
+    }
+    names[job.Name] = true
+
+    if _, err := ParseCron(job.Cron); err != nil {
+      return fmt.Errorf("cannot parse schedule of job '%s': %v", job.Name, err)
+    }
+    if _, err := LoadLocation(job.Timezone); err != nil {
+      return fmt.Errorf("cannot load timezone of job '%s': %v", job.Name, err)
+    }
+  }
+  return nil
+}
@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// maxNextSearch limit of schedule search, expressions like `0 0 30 2 *` never match
+const maxNextSearch = 5 * 366 * 24 * time.Hour
+
+var descriptors = map[string]string{
+  "@yearly":   "0 0 1 1 *",
+  "@annually": "0 0 1 1 *",
+  "@monthly":  "0 0 1 * *",
+  "@weekly":   "0 0 * * 0",
+  "@daily":    "0 0 * * *",
+  "@midnight": "0 0 * * *",
+  "@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+  "jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+  "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+  "sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type cronField struct {
+  min, max int
+  names    map[string]int
+}
+
+var (
+  minuteField = cronField{min: 0, max: 59}
+  hourField   = cronField{min: 0, max: 23}
+  domField    = cronField{min: 1, max: 31}
+  monthField  = cronField{min: 1, max: 12, names: monthNames}
+  // 7 is sunday as well
+  dowField = cronField{min: 0, max: 7, names: dayNames}
+)
+
+// Schedule parsed cron expression with standard five fields:
+// minute, hour, day of month, month, day of week
+type Schedule struct {
+  expr    string
+  minutes []bool
+  hours   []bool
+  doms    []bool
+  months  []bool
+  dows    []bool
+  // day matches if day of month or day of week matches, when both fields are restricted
+  domStar bool
+  dowStar bool
+}
+
+// ParseCron parse cron expression. fields support `*`, lists `1,15`, ranges `1-5`,
+// steps `*/5` and `8-18/2`, month and day names (`jan`, `mon-fri`) and descriptors
+// `@hourly`, `@daily`, `@weekly`, `@monthly`, `@yearly`
+//   expr cron expression
+func ParseCron(expr string) (*Schedule, error) {
+  spec := strings.TrimSpace(expr)
+  if descriptor, ok := descriptors[strings.ToLower(spec)]; ok {
+    spec = descriptor
+  }
+  fields := strings.Fields(spec)
+  if len(fields) != 5 {
+    return nil, fmt.Errorf("cron expression '%s' must have 5 fields, got %d", expr, len(fields))
+  }
+  s := &Schedule{expr: expr}
+
+  var err error
+  parsers := []struct {
+    field  cronField
+    value  string
+    target *[]bool
+  }{
+    {minuteField, fields[0], &s.minutes},
+    {hourField, fields[1], &s.hours},
+    {domField, fields[2], &s.doms},
+    {monthField, fields[3], &s.months},
+    {dowField, fields[4], &s.dows},
+  }
+  for _, parser := range parsers {
+    if *parser.target, err = parseField(parser.value, parser.field); err != nil {
+      return nil, fmt.Errorf("malformed cron expression '%s': %v", expr, err)
+    }
+  }
+  // sunday may be set as 0 or 7
+  if s.dows[7] {
+    s.dows[0] = true
+  }
+  s.domStar = fields[2] == "*" || fields[2] == "?"
+  s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+  return s, nil
+}
+
+func (s *Schedule) String() string {
+  return s.expr
+}
+
+func parseField(value string, field cronField) ([]bool, error) {
+  matches := make([]bool, field.max+1)
+
+  for _, part := range strings.Split(value, ",") {
+    rangePart, stepPart, hasStep := strings.Cut(part, "/")
+    step := 1
+    if hasStep {
+      parsed, err := strconv.Atoi(stepPart)
+      if err != nil || parsed <= 0 {
+        return nil, fmt.Errorf("malformed step '%s'", stepPart)
+      }
+      step = parsed
+    }
+
+    var from, to int
+    switch {
+    case rangePart == "*" || rangePart == "?":
+      from, to = field.min, field.max
+    case strings.Contains(rangePart, "-"):
+      fromPart, toPart, _ := strings.Cut(rangePart, "-")
+      var err error
+      if from, err = parseValue(fromPart, field); err != nil {
+        return nil, err
+      }
+      if to, err = parseValue(toPart, field); err != nil {
+        return nil, err
+      }
+      if from > to {
+        return nil, fmt.Errorf("malformed range '%s'", rangePart)
+      }
+    default:
+      var err error
+      if from, err = parseValue(rangePart, field); err != nil {
+        return nil, err
+      }
+      to = from
+      // `5/15` means from 5 to max with step
+      if hasStep {
+        to = field.max
+      }
+    }
+    for v := from; v <= to; v += step {
+      matches[v] = true
+    }
+  }
+  return matches, nil
+}
+
+func parseValue(value string, field cronField) (int, error) {
+  if named, ok := field.names[strings.ToLower(value)]; ok {
+    return named, nil
+  }
+  parsed, err := strconv.Atoi(value)
+  if err != nil {
+    return 0, fmt.Errorf("malformed value '%s'", value)
+  }
+  if parsed < field.min || parsed > field.max {
+    return 0, fmt.Errorf("value %d out of range %d-%d", parsed, field.min, field.max)
+  }
+  return parsed, nil
+}
+
+// Next return the first time after t matching schedule in location of t.
+// zero time is returned if schedule never matches
+func (s *Schedule) Next(t time.Time) time.Time {
+  loc := t.Location()
+  next := t.Truncate(time.Minute).Add(time.Minute)
+  limit := t.Add(maxNextSearch)
+
+  for next.Before(limit) {
+    if !s.months[int(next.Month())] {
+      next = advance(next, time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, loc))
+      continue
+    }
+    if !s.dayMatches(next) {
+      next = advance(next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, loc))
+      continue
+    }
+    if !s.hours[next.Hour()] {
+      // wall clock hour step, absolute hours are not aligned in zones like Asia/Kolkata
+      next = advance(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, loc))
+      continue
+    }
+    if !s.minutes[next.Minute()] {
+      next = next.Add(time.Minute)
+      continue
+    }
+    return next
+  }
+  return time.Time{}
+}
+
+// advance return candidate if it is after t. wall clock time of candidate may fall into
+// DST gap or repeated hour and be normalized back, then the next minute is returned
+func advance(t, candidate time.Time) time.Time {
+  if candidate.After(t) {
+    return candidate
+  }
+  return t.Add(time.Minute)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+  domMatches := s.doms[t.Day()]
+  dowMatches := s.dows[int(t.Weekday())]
+
+  if s.domStar || s.dowStar {
+    return domMatches && dowMatches
+  }
+  return domMatches || dowMatches
+}
@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+  "testing"
+  "time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+  t.Helper()
+
+  location, err := time.LoadLocation(name)
+  if err != nil {
+    t.Fatalf("cannot load location '%s': %v", name, err)
+  }
+  return location
+}
+
+func TestParseCronRejectsMalformedExpressions(t *testing.T) {
+  for _, expr := range []string{
+    "",
+    "* * * *",
+    "* * * * * *",
+    "60 * * * *",
+    "* 24 * * *",
+    "* * 0 * *",
+    "* * * 13 *",
+    "* * * * 8",
+    "*/0 * * * *",
+    "5-1 * * * *",
+    "a * * * *",
+    "* * * foo *",
+    "@every",
+  } {
+    if _, err := ParseCron(expr); err == nil {
+      t.Errorf("expression '%s' parsed, error expected", expr)
+    }
+  }
+}
+
+func TestScheduleNext(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+
+  tests := []struct {
+    name string
+    expr string
+    from time.Time
+    want time.Time
+  }{
+    {
+      name: "every minute",
+      expr: "* * * * *",
+      from: time.Date(2026, 3, 2, 10, 7, 30, 0, time.UTC),
+      want: time.Date(2026, 3, 2, 10, 8, 0, 0, time.UTC),
+    },
+    {
+      name: "daily descriptor",
+      expr: "@daily",
+      from: time.Date(2026, 3, 2, 10, 7, 0, 0, time.UTC),
+      want: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "steps within range",
+      expr: "*/15 8-18/2 * * *",
+      from: time.Date(2026, 3, 2, 9, 50, 0, 0, time.UTC),
+      want: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "weekday names",
+      expr: "30 17 * * mon-fri",
+      from: time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC),
+      want: time.Date(2026, 3, 9, 17, 30, 0, 0, time.UTC),
+    },
+    {
+      name: "sunday as 7",
+      expr: "0 6 * * 7",
+      from: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+      want: time.Date(2026, 3, 8, 6, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "day of month or day of week when both are restricted",
+      expr: "0 0 15 * mon",
+      from: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+      want: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "month names and the next year",
+      expr: "0 0 1 jan *",
+      from: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+      want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "leap day",
+      expr: "0 0 29 2 *",
+      from: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+      want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+    },
+    {
+      name: "half hour offset zone",
+      expr: "0 17 * * *",
+      from: time.Date(2026, 3, 2, 10, 7, 0, 0, mustLoadLocation(t, "Asia/Kolkata")),
+      want: time.Date(2026, 3, 2, 17, 0, 0, 0, mustLoadLocation(t, "Asia/Kolkata")),
+    },
+    {
+      name: "quarter hour offset zone",
+      expr: "0 17 * * *",
+      from: time.Date(2026, 3, 2, 10, 7, 0, 0, mustLoadLocation(t, "Asia/Kathmandu")),
+      want: time.Date(2026, 3, 2, 17, 0, 0, 0, mustLoadLocation(t, "Asia/Kathmandu")),
+    },
+    {
+      name: "wall clock hour after spring forward",
+      expr: "0 17 * * *",
+      from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+      want: time.Date(2026, 3, 8, 17, 0, 0, 0, newYork),
+    },
+    {
+      name: "skipped wall clock time of spring forward",
+      expr: "30 2 * * *",
+      from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+      want: time.Date(2026, 3, 9, 2, 30, 0, 0, newYork),
+    },
+    {
+      name: "wall clock hour after fall back",
+      expr: "0 17 * * *",
+      from: time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+      want: time.Date(2026, 11, 1, 17, 0, 0, 0, newYork),
+    },
+  }
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      schedule, err := ParseCron(test.expr)
+      if err != nil {
+        t.Fatalf("cannot parse '%s': %v", test.expr, err)
+      }
+      if got := schedule.Next(test.from); !got.Equal(test.want) {
+        t.Errorf("next of '%s' after %s is %s, want %s", test.expr, test.from, got, test.want)
+      }
+    })
+  }
+}
+
+func TestScheduleNextNeverMatches(t *testing.T) {
+  schedule, err := ParseCron("0 0 30 2 *")
+  if err != nil {
+    t.Fatalf("cannot parse: %v", err)
+  }
+  if next := schedule.Next(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+    t.Errorf("next is %s, zero time expected", next)
+  }
+}
+
+func TestScheduleNextIsAfterEveryStep(t *testing.T) {
+  kolkata := mustLoadLocation(t, "Asia/Kolkata")
+  schedule, err := ParseCron("*/5 9-16 * * mon-fri")
+  if err != nil {
+    t.Fatalf("cannot parse: %v", err)
+  }
+  next := time.Date(2026, 3, 6, 16, 50, 0, 0, kolkata)
+  want := []time.Time{
+    time.Date(2026, 3, 6, 16, 55, 0, 0, kolkata),
+    time.Date(2026, 3, 9, 9, 0, 0, 0, kolkata),
+    time.Date(2026, 3, 9, 9, 5, 0, 0, kolkata),
+  }
+  for _, expected := range want {
+    next = schedule.Next(next)
+    if !next.Equal(expected) {
+      t.Fatalf("next is %s, want %s", next, expected)
+    }
+  }
+}
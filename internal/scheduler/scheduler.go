@@ -0,0 +1,320 @@
+package scheduler
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/pkg/utils/logging"
+  "scientific-research/pkg/utils/timeutils"
+  "time"
+)
+
+var log = logging.Logger("scheduler")
+
+const (
+  defaultRetryInterval = 10 * time.Minute
+  triggersBufferSize   = 16
+
+  jobStatusSucceeded = "succeeded"
+  jobStatusFailed    = "failed"
+)
+
+// ErrUnknownJob job with name is not scheduled
+var ErrUnknownJob = errors.New("unknown job")
+
+// JobStorage storage of last and next runs of jobs
+type JobStorage interface {
+  GetJobState(jobName, ownerId string) (*domain.JobState, bool, error)
+  PutJobState(state *domain.JobState) error
+}
+
+// Calendar exchange calendar. runs on days without trading session are skipped
+type Calendar interface {
+  IsTradingDay(t time.Time) bool
+}
+
+// RunInfo info about previous runs passed to job
+type RunInfo struct {
+  // LastSuccessAt time of the last succeeded run, nil if job has never succeeded
+  LastSuccessAt *time.Time
+}
+
+// Job scheduled job
+type Job struct {
+  Name          string
+  Schedule      *Schedule
+  Location      *time.Location
+  Calendar      Calendar
+  RunOnStart    bool
+  RetryInterval time.Duration
+  Run           func(ctx context.Context, info *RunInfo) error
+
+  state *domain.JobState
+}
+
+// Scheduler run jobs one by one at their scheduled time. last and next runs of jobs are
+// persisted, missed runs (e.g. while instance was down) are run once on start
+type Scheduler struct {
+  storage  JobStorage
+  ownerId  string
+  jobs     []*Job
+  triggers chan string
+}
+
+// NewScheduler create scheduler
+//   storage job storage
+//   ownerId id of instance owning job states, empty if jobs are not sharded
+func NewScheduler(storage JobStorage, ownerId string) *Scheduler {
+  return &Scheduler{
+    storage:  storage,
+    ownerId:  ownerId,
+    triggers: make(chan string, triggersBufferSize),
+  }
+}
+
+// NewJob create job from config
+//   config job config
+//   defaultLocation location used if job has no timezone
+//   calendars calendars by name
+//   run job handler
+func NewJob(
+  config *JobConfig,
+  defaultLocation *time.Location,
+  calendars func(name string) (Calendar, error),
+  run func(ctx context.Context, info *RunInfo) error,
+) (*Job, error) {
+  if config == nil {
+    return nil, fmt.Errorf("job config is a nil")
+  }
+  schedule, err := ParseCron(config.Cron)
+  if err != nil {
+    return nil, fmt.Errorf("cannot parse schedule of job '%s': %v", config.Name, err)
+  }
+  location := defaultLocation
+  if config.Timezone != "" {
+    if location, err = time.LoadLocation(config.Timezone); err != nil {
+      return nil, fmt.Errorf("cannot load timezone of job '%s': %v", config.Name, err)
+    }
+  }
+  var calendar Calendar
+  if config.Calendar != "" {
+    if calendar, err = calendars(config.Calendar); err != nil {
+      return nil, fmt.Errorf("cannot get calendar of job '%s': %v", config.Name, err)
+    }
+  }
+  return &Job{
+    Name:          config.Name,
+    Schedule:      schedule,
+    Location:      location,
+    Calendar:      calendar,
+    RunOnStart:    config.RunOnStart,
+    RetryInterval: config.RetryInterval,
+    Run:           run,
+  }, nil
+}
+
+// LoadLocation load scheduler timezone. UTC is used if timezone is empty
+func LoadLocation(timezone string) (*time.Location, error) {
+  if timezone == "" {
+    return time.UTC, nil
+  }
+  return time.LoadLocation(timezone)
+}
+
+func (s *Scheduler) Add(job *Job) error {
+  if job == nil || job.Schedule == nil || job.Run == nil {
+    return fmt.Errorf("job, its schedule or handler is a nil")
+  }
+  for _, added := range s.jobs {
+    if added.Name == job.Name {
+      return fmt.Errorf("job '%s' already added", job.Name)
+    }
+  }
+  if job.Location == nil {
+    job.Location = time.UTC
+  }
+  if job.RetryInterval <= 0 {
+    job.RetryInterval = defaultRetryInterval
+  }
+  s.jobs = append(s.jobs, job)
+  return nil
+}
+
+// Trigger run job immediately after running job finishes. all jobs are run if name is empty.
+// jobs must be added before
+func (s *Scheduler) Trigger(jobName string) error {
+  if jobName != "" && s.findJob(jobName) == nil {
+    return fmt.Errorf("%w: '%s'", ErrUnknownJob, jobName)
+  }
+  select {
+  case s.triggers <- jobName:
+    return nil
+  default:
+    return fmt.Errorf("too many pending triggers")
+  }
+}
+
+func (s *Scheduler) findJob(jobName string) *Job {
+  for _, job := range s.jobs {
+    if job.Name == jobName {
+      return job
+    }
+  }
+  return nil
+}
+
+// Run run jobs until context is done
+func (s *Scheduler) Run(ctx context.Context) error {
+  if len(s.jobs) == 0 {
+    return fmt.Errorf("no jobs scheduled")
+  }
+  for _, job := range s.jobs {
+    if err := s.loadJob(job); err != nil {
+      return err
+    }
+  }
+  for {
+    s.applyPendingTriggers()
+
+    job := s.nextJob()
+    wait := time.Until(job.state.NextRunAt)
+
+    if wait > 0 {
+      log.Infof("next job '%s' at %s", job.Name, job.state.NextRunAt.In(job.Location).Format(time.RFC3339))
+
+      timer := time.NewTimer(wait)
+      select {
+      case <-ctx.Done():
+        timer.Stop()
+        return nil
+      case jobName := <-s.triggers:
+        timer.Stop()
+        s.applyTrigger(jobName)
+        continue
+      case <-timer.C:
+      }
+    }
+    s.runJob(ctx, job)
+  }
+}
+
+// loadJob load persisted state of job and set its next run
+func (s *Scheduler) loadJob(job *Job) error {
+  state, found, err := s.storage.GetJobState(job.Name, s.ownerId)
+  if err != nil {
+    return fmt.Errorf("cannot get state of job '%s': %v", job.Name, err)
+  }
+  now := timeutils.NotTimeUTC()
+
+  if !found {
+    state = &domain.JobState{
+      JobName: job.Name,
+      OwnerId: s.ownerId,
+    }
+    state.NextRunAt = s.nextRun(job, now)
+    if job.RunOnStart {
+      state.NextRunAt = now
+    }
+  } else if state.NextRunAt.Before(now) {
+    log.Infof("job '%s' missed run at %s. run it now", job.Name, state.NextRunAt.Format(time.RFC3339))
+  }
+  job.state = state
+
+  if err = s.storage.PutJobState(job.state); err != nil {
+    return fmt.Errorf("cannot put state of job '%s': %v", job.Name, err)
+  }
+  return nil
+}
+
+// applyPendingTriggers apply triggers received while job was running
+func (s *Scheduler) applyPendingTriggers() {
+  for {
+    select {
+    case jobName := <-s.triggers:
+      s.applyTrigger(jobName)
+    default:
+      return
+    }
+  }
+}
+
+// applyTrigger make triggered jobs due now
+func (s *Scheduler) applyTrigger(jobName string) {
+  now := timeutils.NotTimeUTC()
+  for _, job := range s.jobs {
+    if jobName == "" || job.Name == jobName {
+      log.Infof("job '%s' triggered", job.Name)
+      job.state.NextRunAt = now
+    }
+  }
+}
+
+func (s *Scheduler) nextJob() *Job {
+  next := s.jobs[0]
+  for _, job := range s.jobs[1:] {
+    if job.state.NextRunAt.Before(next.state.NextRunAt) {
+      next = job
+    }
+  }
+  return next
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job *Job) {
+  startedAt := timeutils.NotTimeUTC()
+  log.Infof("run job '%s'", job.Name)
+
+  err := job.Run(ctx, &RunInfo{LastSuccessAt: job.state.LastSuccessAt})
+
+  finishedAt := timeutils.NotTimeUTC()
+  job.state.LastRunAt = &startedAt
+  job.state.NextRunAt = s.nextRun(job, finishedAt)
+
+  if err != nil {
+    job.state.LastStatus = jobStatusFailed
+    job.state.LastError = err.Error()
+
+    if retryAt := finishedAt.Add(job.RetryInterval); retryAt.Before(job.state.NextRunAt) {
+      job.state.NextRunAt = retryAt
+    }
+    log.Errorf("job '%s' failed: %v. next run at %s", job.Name, err, job.state.NextRunAt.Format(time.RFC3339))
+  } else {
+    job.state.LastStatus = jobStatusSucceeded
+    job.state.LastError = ""
+    job.state.LastSuccessAt = &startedAt
+
+    log.Infof("job '%s' succeeded in %v", job.Name, finishedAt.Sub(startedAt).Round(time.Second))
+  }
+  job.state.UpdatedAt = finishedAt
+
+  if err = s.storage.PutJobState(job.state); err != nil {
+    log.Errorf("cannot put state of job '%s': %v", job.Name, err)
+  }
+}
+
+// nextRun return the next run of job after t skipping days without trading session
+func (s *Scheduler) nextRun(job *Job, t time.Time) time.Time {
+  next := t.In(job.Location)
+  limit := t.Add(maxNextSearch)
+
+  for {
+    next = job.Schedule.Next(next)
+    if next.IsZero() || next.After(limit) {
+      log.Errorf("job '%s' schedule '%s' never matches", job.Name, job.Schedule)
+      return t.Add(maxNextSearch)
+    }
+    if job.Calendar == nil || job.Calendar.IsTradingDay(next) {
+      return next.UTC()
+    }
+  }
+}
+
+// Weekdays calendar with trading sessions from monday to friday
+var Weekdays Calendar = weekdaysCalendar{}
+
+type weekdaysCalendar struct{}
+
+func (weekdaysCalendar) IsTradingDay(t time.Time) bool {
+  weekday := t.Weekday()
+  return weekday != time.Saturday && weekday != time.Sunday
+}
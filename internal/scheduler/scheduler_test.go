@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+  "context"
+  "testing"
+  "time"
+)
+
+func TestNextRunSkipsDaysWithoutSession(t *testing.T) {
+  newYork := mustLoadLocation(t, "America/New_York")
+  job, err := NewJob(
+    &JobConfig{Name: "bars", Kind: "bars", Cron: "30 17 * * *", Calendar: "weekdays"},
+    newYork,
+    func(name string) (Calendar, error) {
+      return Weekdays, nil
+    },
+    func(ctx context.Context, info *RunInfo) error {
+      return nil
+    },
+  )
+  if err != nil {
+    t.Fatalf("cannot create job: %v", err)
+  }
+  s := NewScheduler(nil, "")
+
+  // friday evening after the run
+  from := time.Date(2026, 3, 6, 18, 0, 0, 0, newYork)
+  want := time.Date(2026, 3, 9, 17, 30, 0, 0, newYork)
+
+  if next := s.nextRun(job, from); !next.Equal(want) {
+    t.Errorf("next run is %s, want %s", next.In(newYork), want)
+  }
+}
@@ -0,0 +1,13 @@
+package shard
+
+import "time"
+
+// Config sharding of tickers across fetcher instances. tickers are split into
+// fixed count of shards, shards are assigned to alive instances by rendezvous hashing
+type Config struct {
+  Enabled           bool          `yaml:"enabled" env:"SHARDING_ENABLED"`
+  ShardCount        int           `yaml:"shard_count" env:"SHARDING_SHARD_COUNT" validate:"min=1"`
+  MemberId          string        `yaml:"member_id" env:"SHARDING_MEMBER_ID"`
+  HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env:"SHARDING_HEARTBEAT_INTERVAL"`
+  MemberTTL         time.Duration `yaml:"member_ttl" env:"SHARDING_MEMBER_TTL"`
+}
@@ -0,0 +1,262 @@
+package shard
+
+import (
+  "context"
+  "fmt"
+  "hash/fnv"
+  "os"
+  "scientific-research/pkg/utils/logging"
+  "sort"
+  "sync"
+  "time"
+)
+
+var log = logging.Logger("shard")
+
+const (
+  defaultShardCount        = 16
+  defaultHeartbeatInterval = 10 * time.Second
+  defaultMemberTTL         = 30 * time.Second
+)
+
+// MembershipStorage storage of alive fetcher instances
+type MembershipStorage interface {
+  HeartbeatMember(fetcherId, memberId string) error
+  GetAliveMembers(fetcherId string, ttl time.Duration) ([]string, error)
+  RemoveMember(fetcherId, memberId string) error
+}
+
+// Coordinator keep heartbeat of instance and list of alive instances. shard of ticker is
+// owned by instance with the highest rendezvous hash of (instance, shard), so only shards
+// of joined or left instance move on rebalance
+type Coordinator struct {
+  storage           MembershipStorage
+  fetcherId         string
+  memberId          string
+  shardCount        int
+  heartbeatInterval time.Duration
+  memberTTL         time.Duration
+
+  mu        sync.RWMutex
+  members   []string
+  owned     map[int]bool
+  onAcquire func(shards []int)
+}
+
+// NewCoordinator create coordinator of fetcher instances. member id defaults to hostname,
+// it must be stable across restarts: checkpoint, job states and shards progress are kept by it
+//   storage membership storage
+//   config sharding config
+//   fetcherId fetcher id, instances of the same fetcher share shards
+func NewCoordinator(storage MembershipStorage, config *Config, fetcherId string) (*Coordinator, error) {
+  if storage == nil {
+    return nil, fmt.Errorf("membership storage is a nil")
+  }
+  if config == nil {
+    config = &Config{}
+  }
+  c := &Coordinator{
+    storage:           storage,
+    fetcherId:         fetcherId,
+    memberId:          config.MemberId,
+    shardCount:        config.ShardCount,
+    heartbeatInterval: config.HeartbeatInterval,
+    memberTTL:         config.MemberTTL,
+  }
+  if c.memberId == "" {
+    hostname, err := os.Hostname()
+    if err != nil {
+      return nil, fmt.Errorf("cannot get hostname for member id: %v", err)
+    }
+    c.memberId = hostname
+  }
+  if c.shardCount <= 0 {
+    c.shardCount = defaultShardCount
+  }
+  if c.heartbeatInterval <= 0 {
+    c.heartbeatInterval = defaultHeartbeatInterval
+  }
+  if c.memberTTL <= 0 {
+    c.memberTTL = defaultMemberTTL
+  }
+  if c.heartbeatInterval >= c.memberTTL {
+    return nil, fmt.Errorf("heartbeat interval %v must be less than member ttl %v",
+      c.heartbeatInterval, c.memberTTL)
+  }
+  // until the first heartbeat instance owns nothing
+  c.owned = map[int]bool{}
+
+  return c, nil
+}
+
+// MemberId return id of this instance
+func (c *Coordinator) MemberId() string {
+  return c.memberId
+}
+
+// OnAcquire set handler of shards acquired on rebalance. handler is called from refresh
+// after ownership is updated, so it delays the next heartbeat
+func (c *Coordinator) OnAcquire(handler func(shards []int)) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.onAcquire = handler
+}
+
+// Join send the first heartbeat and load alive instances after one heartbeat interval.
+// instances started together see heartbeats of each other only after a heartbeat round,
+// without waiting a fresh instance would own all shards until then. must be called before
+// fetching, returns context error if context is done while waiting
+func (c *Coordinator) Join(ctx context.Context) error {
+  if err := c.storage.HeartbeatMember(c.fetcherId, c.memberId); err != nil {
+    return fmt.Errorf("cannot send heartbeat: %v", err)
+  }
+  log.Infof("member '%s' joins fetcher '%s', wait %v for heartbeats of other members",
+    c.memberId, c.fetcherId, c.heartbeatInterval)
+
+  timer := time.NewTimer(c.heartbeatInterval)
+  defer timer.Stop()
+
+  select {
+  case <-ctx.Done():
+    return ctx.Err()
+  case <-timer.C:
+  }
+  return c.refresh()
+}
+
+// Run send heartbeats and refresh alive instances every heartbeat interval.
+// blocks until context is done, instance leaves then
+func (c *Coordinator) Run(ctx context.Context) {
+  ticker := time.NewTicker(c.heartbeatInterval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      c.leave()
+      return
+    case <-ticker.C:
+    }
+    if err := c.refresh(); err != nil {
+      log.Errorf("cannot refresh members of fetcher '%s': %v", c.fetcherId, err)
+    }
+  }
+}
+
+func (c *Coordinator) refresh() error {
+  if err := c.storage.HeartbeatMember(c.fetcherId, c.memberId); err != nil {
+    return fmt.Errorf("cannot send heartbeat: %v", err)
+  }
+  members, err := c.storage.GetAliveMembers(c.fetcherId, c.memberTTL)
+  if err != nil {
+    return fmt.Errorf("cannot get alive members: %v", err)
+  }
+  sort.Strings(members)
+
+  owned := map[int]bool{}
+  for shard := 0; shard < c.shardCount; shard++ {
+    if ownerOf(shard, members) == c.memberId {
+      owned[shard] = true
+    }
+  }
+
+  var acquired []int
+  c.mu.Lock()
+  for shard := range owned {
+    if !c.owned[shard] {
+      acquired = append(acquired, shard)
+    }
+  }
+  changed := !equalStrings(c.members, members)
+  c.members = members
+  c.owned = owned
+  onAcquire := c.onAcquire
+  c.mu.Unlock()
+
+  if changed {
+    log.Infof("members of fetcher '%s' changed: %v. '%s' owns %d of %d shards",
+      c.fetcherId, members, c.memberId, len(owned), c.shardCount)
+  }
+  if len(acquired) != 0 && onAcquire != nil {
+    sort.Ints(acquired)
+    onAcquire(acquired)
+  }
+  return nil
+}
+
+func (c *Coordinator) leave() {
+  if err := c.storage.RemoveMember(c.fetcherId, c.memberId); err != nil {
+    log.Errorf("cannot remove member '%s' of fetcher '%s': %v", c.memberId, c.fetcherId, err)
+    return
+  }
+  log.Infof("member '%s' left fetcher '%s'", c.memberId, c.fetcherId)
+}
+
+// ShardOf return shard of ticker
+func (c *Coordinator) ShardOf(tickerId string) int {
+  h := fnv.New32a()
+  _, _ = h.Write([]byte(tickerId))
+  return int(h.Sum32() % uint32(c.shardCount))
+}
+
+// Owns return true if shard of ticker is owned by this instance
+func (c *Coordinator) Owns(tickerId string) bool {
+  shard := c.ShardOf(tickerId)
+
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return c.owned[shard]
+}
+
+// OwnedShards return sorted shards owned by this instance
+func (c *Coordinator) OwnedShards() []int {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+
+  shards := make([]int, 0, len(c.owned))
+  for shard := range c.owned {
+    shards = append(shards, shard)
+  }
+  sort.Ints(shards)
+  return shards
+}
+
+// ownerOf return member with the highest rendezvous hash of shard
+func ownerOf(shard int, members []string) string {
+  var (
+    owner     string
+    bestScore uint64
+  )
+  for _, member := range members {
+    h := fnv.New64a()
+    _, _ = fmt.Fprint(h, member, "/", shard)
+    if score := mix64(h.Sum64()); owner == "" || score > bestScore {
+      owner = member
+      bestScore = score
+    }
+  }
+  return owner
+}
+
+// mix64 splitmix64 finalizer. fnv hashes of similar strings are close, scores must be uniform
+func mix64(x uint64) uint64 {
+  x ^= x >> 30
+  x *= 0xbf58476d1ce4e5b9
+  x ^= x >> 27
+  x *= 0x94d049bb133111eb
+  x ^= x >> 31
+  return x
+}
+
+func equalStrings(a, b []string) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for idx := range a {
+    if a[idx] != b[idx] {
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,103 @@
+package shard
+
+import (
+  "context"
+  "errors"
+  "sort"
+  "sync"
+  "testing"
+  "time"
+)
+
+// memMembership membership storage which treats every member with heartbeat as alive
+type memMembership struct {
+  mu         sync.Mutex
+  heartbeats map[string]int
+}
+
+func (s *memMembership) HeartbeatMember(fetcherId, memberId string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.heartbeats[memberId]++
+  return nil
+}
+
+func (s *memMembership) GetAliveMembers(fetcherId string, ttl time.Duration) ([]string, error) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  members := make([]string, 0, len(s.heartbeats))
+  for member := range s.heartbeats {
+    members = append(members, member)
+  }
+  sort.Strings(members)
+  return members, nil
+}
+
+func (s *memMembership) RemoveMember(fetcherId, memberId string) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  delete(s.heartbeats, memberId)
+  return nil
+}
+
+func newTestCoordinator(t *testing.T, storage MembershipStorage, memberId string) *Coordinator {
+  t.Helper()
+
+  c, err := NewCoordinator(storage, &Config{
+    MemberId:          memberId,
+    ShardCount:        16,
+    HeartbeatInterval: 100 * time.Millisecond,
+    MemberTTL:         300 * time.Millisecond,
+  }, "polygon")
+  if err != nil {
+    t.Fatalf("cannot create coordinator: %v", err)
+  }
+  return c
+}
+
+func TestJoinWaitsForHeartbeatsOfMembersStartedTogether(t *testing.T) {
+  storage := &memMembership{heartbeats: map[string]int{}}
+  first := newTestCoordinator(t, storage, "fetcher-0")
+  second := newTestCoordinator(t, storage, "fetcher-1")
+
+  var wg sync.WaitGroup
+  errs := make([]error, 2)
+  for idx, c := range []*Coordinator{first, second} {
+    wg.Add(1)
+    go func(idx int, c *Coordinator) {
+      defer wg.Done()
+      errs[idx] = c.Join(context.Background())
+    }(idx, c)
+  }
+  wg.Wait()
+
+  for idx, err := range errs {
+    if err != nil {
+      t.Fatalf("member %d cannot join: %v", idx, err)
+    }
+  }
+  firstShards, secondShards := first.OwnedShards(), second.OwnedShards()
+  if len(firstShards) == 0 || len(secondShards) == 0 || len(firstShards)+len(secondShards) != 16 {
+    t.Errorf("shards are not split between members: %v and %v", firstShards, secondShards)
+  }
+}
+
+func TestJoinOwnsNothingWhileWaiting(t *testing.T) {
+  storage := &memMembership{heartbeats: map[string]int{}}
+  c := newTestCoordinator(t, storage, "fetcher-0")
+
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  if err := c.Join(ctx); !errors.Is(err, context.Canceled) {
+    t.Errorf("got error %v, want context error", err)
+  }
+  if shards := c.OwnedShards(); len(shards) != 0 {
+    t.Errorf("member owns shards %v before heartbeat round", shards)
+  }
+  if storage.heartbeats["fetcher-0"] != 1 {
+    t.Errorf("heartbeat is not sent on join")
+  }
+}
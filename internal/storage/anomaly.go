@@ -0,0 +1,60 @@
+package storage
+
+import (
+  "fmt"
+  "scientific-research/internal/domain"
+
+  sq "github.com/Masterminds/squirrel"
+)
+
+// PutStockAnomaly put quarantined bar with reason of its rejection. bar detected again
+// replaces the previous detection
+func (s *storage) PutStockAnomaly(anomaly *domain.StockAnomaly) error {
+  if anomaly == nil || anomaly.Stock == nil {
+    return fmt.Errorf("stock anomaly is a nil")
+  }
+  stock := anomaly.Stock
+
+  builder := sq.Insert(`stock_anomalies`).
+    Columns(
+      `stock_id`,
+      `ticker_id`,
+      `open_price`,
+      `close_price`,
+      `highest_price`,
+      `lowest_price`,
+      `trading_volume`,
+      `stocked_at`,
+      `reason`,
+      `detected_at`,
+    ).
+    Values(
+      stock.StockId,
+      stock.TickerId,
+      stock.OpenPrice,
+      stock.ClosePrice,
+      stock.HighestPrice,
+      stock.LowestPrice,
+      stock.TradingVolume,
+      stock.StockedAt,
+      anomaly.Reason,
+      anomaly.DetectedAt,
+    ).
+    Suffix(`ON CONFLICT (stock_id) DO UPDATE SET ` +
+      `open_price = EXCLUDED.open_price, ` +
+      `close_price = EXCLUDED.close_price, ` +
+      `highest_price = EXCLUDED.highest_price, ` +
+      `lowest_price = EXCLUDED.lowest_price, ` +
+      `trading_volume = EXCLUDED.trading_volume, ` +
+      `reason = EXCLUDED.reason, ` +
+      `detected_at = EXCLUDED.detected_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  if err := s.doPutQuery(builder); err != nil {
+    return err
+  }
+  s.logger().Infof("put anomaly of stock '%s' for ticker '%s' in database. reason: %s",
+    stock.StockId, stock.TickerId, anomaly.Reason)
+
+  return nil
+}
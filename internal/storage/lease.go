@@ -0,0 +1,62 @@
+package storage
+
+import (
+  "scientific-research/internal/domain"
+  "time"
+
+  sq "github.com/Masterminds/squirrel"
+)
+
+// AcquireLease acquire or renew lease of fetcher. lease is taken if it is free,
+// expired or already held by holder. database time is used, so holders clocks may differ.
+// return the lease with its expiration and acquiring time by database clock, false if
+// other holder owns the lease
+//   fetcherId lease name
+//   holderId id of instance taking the lease
+//   duration lease duration from now
+func (s *storage) AcquireLease(fetcherId, holderId string, duration time.Duration) (*domain.FetcherLease, bool, error) {
+  expiresAt := sq.Expr(`NOW() + ?::float8 * INTERVAL '1 millisecond'`, float64(duration.Milliseconds()))
+
+  builder := sq.Insert(`fetcher_lease`).
+    Columns(
+      `fetcher_id`,
+      `holder_id`,
+      `expires_at`,
+      `updated_at`,
+    ).
+    Values(
+      fetcherId,
+      holderId,
+      expiresAt,
+      sq.Expr(`NOW()`),
+    ).
+    Suffix(`ON CONFLICT (fetcher_id) DO UPDATE SET ` +
+      `holder_id = EXCLUDED.holder_id, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at ` +
+      `WHERE fetcher_lease.holder_id = EXCLUDED.holder_id OR fetcher_lease.expires_at < NOW() ` +
+      `RETURNING expires_at, updated_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  lease := &domain.FetcherLease{
+    FetcherId: fetcherId,
+    HolderId:  holderId,
+  }
+  acquired, err := s.doGetQuery(builder, &lease.ExpiresAt, &lease.UpdatedAt)
+  if err != nil || !acquired {
+    return nil, false, err
+  }
+  return lease, true, nil
+}
+
+// ReleaseLease expire lease if it is held by holder, so other instances take it without waiting
+func (s *storage) ReleaseLease(fetcherId, holderId string) error {
+  builder := sq.Update(`fetcher_lease`).
+    Set(`expires_at`, sq.Expr(`NOW()`)).
+    Set(`updated_at`, sq.Expr(`NOW()`)).
+    Where(sq.Eq{
+      `fetcher_id`: fetcherId,
+      `holder_id`:  holderId,
+    }).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+  "embed"
+  "fmt"
+  "sort"
+  "strings"
+
+  sq "github.com/Masterminds/squirrel"
+  "github.com/jackc/pgx/v4"
+)
+
+// migrationsFS sql migrations applied in order of file names
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+const migrationsDir = "migrations"
+
+// Migrate apply embedded migrations which are not applied yet. every migration runs in
+// its own transaction with record in `schema_migrations`. migrations are idempotent,
+// so databases migrated by hand before are migrated again safely.
+// return versions of applied migrations
+func (s *storage) Migrate() ([]string, error) {
+  if _, err := s.client.Exec(s.ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version    TEXT        PRIMARY KEY,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`); err != nil {
+    return nil, fmt.Errorf("cannot create migrations table: %v", err)
+  }
+  applied, err := s.getAppliedMigrations()
+  if err != nil {
+    return nil, err
+  }
+  entries, err := migrationsFS.ReadDir(migrationsDir)
+  if err != nil {
+    return nil, fmt.Errorf("cannot read migrations: %v", err)
+  }
+  var versions []string
+  for _, entry := range entries {
+    versions = append(versions, strings.TrimSuffix(entry.Name(), ".sql"))
+  }
+  sort.Strings(versions)
+
+  var newlyApplied []string
+  for _, version := range versions {
+    if applied[version] {
+      continue
+    }
+    migration, err := migrationsFS.ReadFile(fmt.Sprint(migrationsDir, "/", version, ".sql"))
+    if err != nil {
+      return newlyApplied, fmt.Errorf("cannot read migration '%s': %v", version, err)
+    }
+    err = s.doInTx(func(tx pgx.Tx) error {
+      if _, err := tx.Exec(s.ctx, string(migration)); err != nil {
+        return fmt.Errorf("cannot exec migration: %v", err)
+      }
+      builder := sq.Insert(`schema_migrations`).
+        Columns(`version`).
+        Values(version).
+        PlaceholderFormat(sq.Dollar)
+
+      return s.doPutQueryWith(tx, builder)
+    })
+    if err != nil {
+      return newlyApplied, fmt.Errorf("cannot apply migration '%s': %v", version, err)
+    }
+    s.logger().Infof("applied migration '%s'", version)
+    newlyApplied = append(newlyApplied, version)
+  }
+  return newlyApplied, nil
+}
+
+func (s *storage) getAppliedMigrations() (map[string]bool, error) {
+  rows, err := s.client.Query(s.ctx, `SELECT version FROM schema_migrations`)
+  if err != nil {
+    return nil, fmt.Errorf("cannot do query: %v", err)
+  }
+  defer rows.Close()
+
+  applied := map[string]bool{}
+  for rows.Next() {
+    var version string
+    if err = rows.Scan(&version); err != nil {
+      return nil, fmt.Errorf("cannot scan queried row: %v", err)
+    }
+    applied[version] = true
+  }
+  if err = rows.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  return applied, nil
+}
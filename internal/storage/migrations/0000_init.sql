@@ -0,0 +1,47 @@
+-- base tables of fetcher
+CREATE TABLE IF NOT EXISTS ticker (
+  ticker_id           TEXT      PRIMARY KEY,
+  company_name        TEXT      NOT NULL,
+  company_locale      TEXT      NOT NULL,
+  currency_name       TEXT      NOT NULL,
+  ticker_cik          TEXT      NOT NULL,
+  active              BOOLEAN   NOT NULL,
+  created_at          TIMESTAMP NOT NULL,
+  external_updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ticker_details (
+  ticker_id           TEXT      PRIMARY KEY,
+  company_description TEXT      NOT NULL,
+  homepage_url        TEXT      NOT NULL,
+  phone_number        TEXT      NOT NULL,
+  total_employees     INT       NOT NULL,
+  company_state       TEXT      NOT NULL,
+  company_city        TEXT      NOT NULL,
+  company_address     TEXT      NOT NULL,
+  company_postal_code TEXT      NOT NULL,
+  created_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS stock (
+  stock_id       TEXT             PRIMARY KEY,
+  ticker_id      TEXT             NOT NULL,
+  open_price     DOUBLE PRECISION NOT NULL,
+  close_price    DOUBLE PRECISION NOT NULL,
+  highest_price  DOUBLE PRECISION NOT NULL,
+  lowest_price   DOUBLE PRECISION NOT NULL,
+  trading_volume DOUBLE PRECISION NOT NULL,
+  stocked_at     TIMESTAMP        NOT NULL,
+  created_at     TIMESTAMP        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_ticker_stocked_at_idx ON stock (ticker_id, stocked_at);
+
+CREATE TABLE IF NOT EXISTS fetcher_state (
+  state_id               SERIAL    PRIMARY KEY,
+  ticker_req_url         TEXT      NOT NULL,
+  ticker_details_req_url TEXT      NOT NULL,
+  stock_req_url          TEXT      NOT NULL,
+  created_at             TIMESTAMP NOT NULL,
+  finished               BOOLEAN   NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+  message_id BIGSERIAL PRIMARY KEY,
+  meta_info  JSONB     NOT NULL,
+  content    BYTEA,
+  created_at TIMESTAMP NOT NULL,
+  sent_at    TIMESTAMP,
+  attempts   INT       NOT NULL DEFAULT 0,
+  last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (message_id) WHERE sent_at IS NULL;
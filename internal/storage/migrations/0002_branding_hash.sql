@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS branding_hash (
+  ticker_id     TEXT      NOT NULL,
+  branding_type TEXT      NOT NULL,
+  content_hash  TEXT      NOT NULL,
+  updated_at    TIMESTAMP NOT NULL,
+  PRIMARY KEY (ticker_id, branding_type)
+);
@@ -0,0 +1,9 @@
+-- content_length of put message meta info changed from string to integer
+UPDATE outbox
+SET meta_info = jsonb_set(meta_info, '{content_length}', to_jsonb((meta_info->>'content_length')::bigint))
+WHERE jsonb_typeof(meta_info->'content_length') = 'string'
+  AND meta_info->>'content_length' <> '';
+
+UPDATE outbox
+SET meta_info = meta_info - 'content_length'
+WHERE meta_info->>'content_length' = '';
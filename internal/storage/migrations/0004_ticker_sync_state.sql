@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS ticker_sync_state (
+  ticker_id   TEXT      NOT NULL,
+  interval    TEXT      NOT NULL,
+  last_bar_at TIMESTAMP NOT NULL,
+  updated_at  TIMESTAMP NOT NULL,
+  PRIMARY KEY (ticker_id, interval)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE fetcher_state ADD COLUMN IF NOT EXISTS cursor         TEXT NOT NULL DEFAULT '';
+ALTER TABLE fetcher_state ADD COLUMN IF NOT EXISTS last_ticker_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS fetcher_lease (
+  fetcher_id TEXT        PRIMARY KEY,
+  holder_id  TEXT        NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
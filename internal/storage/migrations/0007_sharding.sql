@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS fetcher_member (
+  fetcher_id   TEXT        NOT NULL,
+  member_id    TEXT        NOT NULL,
+  heartbeat_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (fetcher_id, member_id)
+);
+
+CREATE TABLE IF NOT EXISTS fetcher_shard_state (
+  fetcher_id       TEXT        NOT NULL,
+  shard            INT         NOT NULL,
+  owner_id         TEXT        NOT NULL,
+  last_ticker_id   TEXT        NOT NULL,
+  processed_count  INT         NOT NULL,
+  cycle_started_at TIMESTAMPTZ NOT NULL,
+  updated_at       TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (fetcher_id, shard)
+);
+
+ALTER TABLE fetcher_state ADD COLUMN IF NOT EXISTS member_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS scheduler_job (
+  job_name        TEXT        NOT NULL,
+  owner_id        TEXT        NOT NULL DEFAULT '',
+  last_run_at     TIMESTAMPTZ,
+  last_success_at TIMESTAMPTZ,
+  next_run_at     TIMESTAMPTZ NOT NULL,
+  last_status     TEXT        NOT NULL DEFAULT '',
+  last_error      TEXT        NOT NULL DEFAULT '',
+  updated_at      TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (job_name, owner_id)
+);
@@ -0,0 +1,16 @@
+-- bars which failed data quality validation. bar is quarantined here instead of `stock`,
+-- the latest detection of bar is kept
+CREATE TABLE IF NOT EXISTS stock_anomalies (
+  stock_id       TEXT             PRIMARY KEY,
+  ticker_id      TEXT             NOT NULL,
+  open_price     DOUBLE PRECISION NOT NULL,
+  close_price    DOUBLE PRECISION NOT NULL,
+  highest_price  DOUBLE PRECISION NOT NULL,
+  lowest_price   DOUBLE PRECISION NOT NULL,
+  trading_volume DOUBLE PRECISION NOT NULL,
+  stocked_at     TIMESTAMP        NOT NULL,
+  reason         TEXT             NOT NULL,
+  detected_at    TIMESTAMP        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_anomalies_ticker_stocked_at_idx ON stock_anomalies (ticker_id, stocked_at);
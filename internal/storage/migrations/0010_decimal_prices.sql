@@ -0,0 +1,16 @@
+-- exact decimal prices and volume. stored doubles are converted with 15 significant
+-- digits, so float artifacts of existing rows (e.g. 0.30000000000000004) are dropped.
+-- conversion of numeric columns is no-op
+ALTER TABLE stock
+  ALTER COLUMN open_price     TYPE NUMERIC USING open_price::NUMERIC,
+  ALTER COLUMN close_price    TYPE NUMERIC USING close_price::NUMERIC,
+  ALTER COLUMN highest_price  TYPE NUMERIC USING highest_price::NUMERIC,
+  ALTER COLUMN lowest_price   TYPE NUMERIC USING lowest_price::NUMERIC,
+  ALTER COLUMN trading_volume TYPE NUMERIC USING trading_volume::NUMERIC;
+
+ALTER TABLE stock_anomalies
+  ALTER COLUMN open_price     TYPE NUMERIC USING open_price::NUMERIC,
+  ALTER COLUMN close_price    TYPE NUMERIC USING close_price::NUMERIC,
+  ALTER COLUMN highest_price  TYPE NUMERIC USING highest_price::NUMERIC,
+  ALTER COLUMN lowest_price   TYPE NUMERIC USING lowest_price::NUMERIC,
+  ALTER COLUMN trading_volume TYPE NUMERIC USING trading_volume::NUMERIC;
@@ -0,0 +1,6 @@
+-- relay claims pending messages for lease, messages failed max attempts are dead letters
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (message_id) WHERE sent_at IS NULL AND dead_at IS NULL;
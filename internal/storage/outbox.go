@@ -0,0 +1,230 @@
+package storage
+
+import (
+  "encoding/json"
+  "fmt"
+  "scientific-research/internal/domain"
+  "scientific-research/pkg/utils/timeutils"
+  "sort"
+  "time"
+
+  sq "github.com/Masterminds/squirrel"
+  "github.com/jackc/pgx/v4"
+)
+
+// PutTickerDetailsWithMessages put ticker details, messages to outbox table and
+// branding hashes of these messages in single transaction
+func (s *storage) PutTickerDetailsWithMessages(
+  tickerDetails *domain.TickerDetails,
+  messages []*domain.PutMessage,
+  hashes []*domain.BrandingHash,
+) error {
+  if tickerDetails == nil {
+    return fmt.Errorf("ticker details is a nil")
+  }
+  outboxBuilders := make([]queryBuilder, 0, len(messages)+len(hashes))
+
+  for _, message := range messages {
+    builder, err := buildPutOutboxMessageQuery(message)
+    if err != nil {
+      return err
+    }
+    outboxBuilders = append(outboxBuilders, builder)
+  }
+  for _, hash := range hashes {
+    if hash == nil {
+      continue
+    }
+    outboxBuilders = append(outboxBuilders, buildPutBrandingHashQuery(hash))
+  }
+
+  err := s.doInTx(func(tx pgx.Tx) error {
+    if err := s.doPutQueryWith(tx, buildPutTickerDetailsQuery(tickerDetails)); err != nil {
+      return err
+    }
+    for _, builder := range outboxBuilders {
+      if err := s.doPutQueryWith(tx, builder); err != nil {
+        return fmt.Errorf("cannot put message to outbox or branding hash: %v", err)
+      }
+    }
+    return nil
+  })
+  if err != nil {
+    return err
+  }
+  s.counters.tickerDetails.Add(counterInc)
+
+  s.logger().Infof("put ticker details for ticker '%s' with %d outbox messages in database. total: %d",
+    tickerDetails.TickerId, len(messages), s.counters.tickerDetails.Load())
+
+  return nil
+}
+
+func buildPutOutboxMessageQuery(message *domain.PutMessage) (queryBuilder, error) {
+  if message == nil || message.MetaInfo == nil {
+    return nil, fmt.Errorf("message or message meta info is a nil")
+  }
+  metaInfo, err := json.Marshal(message.MetaInfo)
+  if err != nil {
+    return nil, fmt.Errorf("cannot marshal message meta info: %v", err)
+  }
+  return sq.Insert(`outbox`).
+    Columns(
+      // `message_id` is bigserial type, autoincrement
+      `meta_info`,
+      `content`,
+      `created_at`,
+    ).
+    Values(
+      string(metaInfo),
+      message.Content,
+      timeutils.NotTimeUTC(),
+    ).
+    PlaceholderFormat(sq.Dollar), nil
+}
+
+// ClaimOutboxMessages lock pending outbox messages for lease duration and return them in order
+// of insertion. rows claimed by other relays are skipped, so message is published by single
+// replica at a time. messages of failed relay are claimed again after lease. dead letters are
+// never claimed
+func (s *storage) ClaimOutboxMessages(limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+  builder := sq.Update(`outbox`).
+    Set(`locked_until`, sq.Expr(`NOW() + ?::float8 * INTERVAL '1 millisecond'`, float64(lease.Milliseconds()))).
+    Where(sq.Expr(`message_id IN (SELECT message_id FROM outbox `+
+      `WHERE sent_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until < NOW()) `+
+      `ORDER BY message_id ASC LIMIT ? FOR UPDATE SKIP LOCKED)`, limit)).
+    Suffix(`RETURNING message_id, meta_info, content, created_at, attempts`).
+    PlaceholderFormat(sq.Dollar)
+
+  query, args := mustBuildQuery(builder)
+  rows, err := s.client.Query(s.ctx, query, args...)
+  if err != nil {
+    return nil, fmt.Errorf("cannot do query: %v", err)
+  }
+  defer rows.Close()
+
+  var messages []*domain.OutboxMessage
+
+  for rows.Next() {
+    var metaInfo []byte
+    message := &domain.OutboxMessage{
+      Message: &domain.PutMessage{
+        MetaInfo: &domain.PutMessageMetaInfo{},
+      },
+    }
+    if err = rows.Scan(
+      &message.MessageId,
+      &metaInfo,
+      &message.Message.Content,
+      &message.CreatedAt,
+      &message.Attempts,
+    ); err != nil {
+      return nil, fmt.Errorf("cannot scan queried row: %v", err)
+    }
+    if err = json.Unmarshal(metaInfo, message.Message.MetaInfo); err != nil {
+      return nil, fmt.Errorf("cannot unmarshal meta info of outbox message %d: %v", message.MessageId, err)
+    }
+    messages = append(messages, message)
+  }
+  if err = rows.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  // returned rows are not ordered
+  sort.Slice(messages, func(i, j int) bool {
+    return messages[i].MessageId < messages[j].MessageId
+  })
+  return messages, nil
+}
+
+func (s *storage) MarkOutboxMessageSent(messageId int64) error {
+  builder := sq.Update(`outbox`).
+    Set(`sent_at`, timeutils.NotTimeUTC()).
+    Set(`attempts`, sq.Expr(`attempts + 1`)).
+    Set(`last_error`, nil).
+    Set(`locked_until`, nil).
+    Where(sq.Eq{`message_id`: messageId}).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
+
+// MarkOutboxMessageFailed count failed attempt and release claim of message. message becomes
+// dead letter when attempts reach maxAttempts. return true if message is dead letter
+func (s *storage) MarkOutboxMessageFailed(messageId int64, reason string, maxAttempts int) (bool, error) {
+  builder := sq.Update(`outbox`).
+    Set(`attempts`, sq.Expr(`attempts + 1`)).
+    Set(`last_error`, reason).
+    Set(`locked_until`, nil).
+    Set(`dead_at`, sq.Expr(`CASE WHEN attempts + 1 >= ? THEN ?::timestamp END`, maxAttempts, timeutils.NotTimeUTC())).
+    Where(sq.Eq{`message_id`: messageId}).
+    Suffix(`RETURNING dead_at IS NOT NULL`).
+    PlaceholderFormat(sq.Dollar)
+
+  var dead bool
+  if _, err := s.doGetQuery(builder, &dead); err != nil {
+    return false, err
+  }
+  return dead, nil
+}
+
+// ReleaseOutboxMessages release claim of not published messages, so they are claimed
+// again on the next try in order of insertion
+func (s *storage) ReleaseOutboxMessages(messageIds []int64) error {
+  if len(messageIds) == 0 {
+    return nil
+  }
+  builder := sq.Update(`outbox`).
+    Set(`locked_until`, nil).
+    Where(sq.Eq{`message_id`: messageIds}).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
+
+func buildPutBrandingHashQuery(hash *domain.BrandingHash) queryBuilder {
+  return sq.Insert(`branding_hash`).
+    Columns(
+      `ticker_id`,
+      `branding_type`,
+      `content_hash`,
+      `updated_at`,
+    ).
+    Values(
+      hash.TickerId,
+      hash.BrandingType,
+      hash.ContentHash,
+      hash.UpdatedAt,
+    ).
+    Suffix(`ON CONFLICT (ticker_id, branding_type) DO UPDATE SET content_hash = EXCLUDED.content_hash, updated_at = EXCLUDED.updated_at`).
+    PlaceholderFormat(sq.Dollar)
+}
+
+func (s *storage) GetBrandingHash(tickerId, brandingType string) (*domain.BrandingHash, bool, error) {
+  builder := sq.Select(
+    `ticker_id`,
+    `branding_type`,
+    `content_hash`,
+    `updated_at`,
+  ).
+    From(`branding_hash`).
+    Where(sq.Eq{
+      `ticker_id`:     tickerId,
+      `branding_type`: brandingType,
+    }).
+    PlaceholderFormat(sq.Dollar)
+
+  hash := &domain.BrandingHash{}
+  found, err := s.doGetQuery(builder,
+    &hash.TickerId,
+    &hash.BrandingType,
+    &hash.ContentHash,
+    &hash.UpdatedAt,
+  )
+  if err != nil {
+    return nil, false, err
+  }
+  if !found {
+    return nil, false, nil
+  }
+  return hash, true, nil
+}
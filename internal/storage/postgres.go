@@ -5,25 +5,61 @@ import (
   "fmt"
   "scientific-research/internal/domain"
   "scientific-research/internal/storage/postgres"
+  "scientific-research/pkg/utils/logging"
   "sync/atomic"
+  "time"
 
   sq "github.com/Masterminds/squirrel"
+  "github.com/jackc/pgconn"
   "github.com/jackc/pgx/v4"
-  log "github.com/sirupsen/logrus"
+  "github.com/sirupsen/logrus"
 )
 
+var log = logging.Logger("storage")
+
 const counterInc = 1
 
 type queryBuilder interface {
   ToSql() (string, []any, error)
 }
 
+type queryExecutor interface {
+  Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
 type Storage interface {
+  // WithContext return storage which runs queries with context. log entries of storage
+  // have log fields of context (e.g. correlation id of fetching cycle)
+  WithContext(ctx context.Context) Storage
   PutTicker(ticker *domain.Ticker) error
   PutTickerDetails(ticker *domain.TickerDetails) error
-  PutStock(stock *domain.Stock) error
+  PutTickerDetailsWithMessages(ticker *domain.TickerDetails, messages []*domain.PutMessage, hashes []*domain.BrandingHash) error
+  GetBrandingHash(tickerId, brandingType string) (*domain.BrandingHash, bool, error)
+  ClaimOutboxMessages(limit int, lease time.Duration) ([]*domain.OutboxMessage, error)
+  MarkOutboxMessageSent(messageId int64) error
+  MarkOutboxMessageFailed(messageId int64, reason string, maxAttempts int) (bool, error)
+  ReleaseOutboxMessages(messageIds []int64) error
+  PutStock(stock *domain.Stock) (bool, error)
+  PutStockAnomaly(anomaly *domain.StockAnomaly) error
+  GetTickerSyncState(tickerId, interval string) (*domain.TickerSyncState, bool, error)
+  PutTickerSyncState(state *domain.TickerSyncState) error
   PutFetcherState(state *domain.FetcherState) error
-  GetFetcherState() (*domain.FetcherState, bool, error)
+  AcquireLease(fetcherId, holderId string, duration time.Duration) (*domain.FetcherLease, bool, error)
+  ReleaseLease(fetcherId, holderId string) error
+  GetFetcherState(memberId string) (*domain.FetcherState, bool, error)
+  HeartbeatMember(fetcherId, memberId string) error
+  GetAliveMembers(fetcherId string, ttl time.Duration) ([]string, error)
+  RemoveMember(fetcherId, memberId string) error
+  PutShardStates(states []*domain.ShardState) error
+  GetShardStates(fetcherId string) ([]*domain.ShardState, error)
+  GetJobState(jobName, ownerId string) (*domain.JobState, bool, error)
+  PutJobState(state *domain.JobState) error
+  GetTickerIds(activeOnly bool) ([]string, error)
+  GetTickers(afterTickerId string, limit int) ([]*domain.Ticker, error)
+  GetStocks(tickerId string, from, to time.Time, handler func(stock *domain.Stock) error) error
+  GetLastStock(tickerId string, before time.Time) (*domain.Stock, bool, error)
+  GetJobStates() ([]*domain.JobState, error)
+  Migrate() ([]string, error)
 }
 
 type storage struct {
@@ -50,6 +86,19 @@ func NewStorage(ctx context.Context, config *postgres.Config) (Storage, error) {
   }, nil
 }
 
+func (s *storage) WithContext(ctx context.Context) Storage {
+  if ctx == nil {
+    return s
+  }
+  withContext := *s
+  withContext.ctx = ctx
+  return &withContext
+}
+
+func (s *storage) logger() *logrus.Entry {
+  return logging.FromContext(s.ctx, log)
+}
+
 func newStorageCounters() *storageCounters {
   return &storageCounters{
     stock:         atomic.Uint64{},
@@ -91,7 +140,7 @@ func (s *storage) PutTicker(ticker *domain.Ticker) error {
   }
   s.counters.ticker.Add(counterInc)
 
-  log.Infof("put ticker '%s' for company '%s' in database. total: %d",
+  s.logger().Infof("put ticker '%s' for company '%s' in database. total: %d",
     ticker.TickerId, ticker.CompanyName, s.counters.ticker.Load())
 
   return nil
@@ -101,7 +150,19 @@ func (s *storage) PutTickerDetails(tickerDetails *domain.TickerDetails) error {
   if tickerDetails == nil {
     return fmt.Errorf("ticker details is a nil")
   }
-  builder := sq.Insert(`ticker_details`).
+  if err := s.doPutQuery(buildPutTickerDetailsQuery(tickerDetails)); err != nil {
+    return err
+  }
+  s.counters.tickerDetails.Add(counterInc)
+
+  s.logger().Infof("put ticker details for ticker '%s' in database. total: %d",
+    tickerDetails.TickerId, s.counters.tickerDetails.Load())
+
+  return nil
+}
+
+func buildPutTickerDetailsQuery(tickerDetails *domain.TickerDetails) queryBuilder {
+  return sq.Insert(`ticker_details`).
     Columns(
       `ticker_id`,
       `company_description`,
@@ -126,21 +187,14 @@ func (s *storage) PutTickerDetails(tickerDetails *domain.TickerDetails) error {
     ).
     Suffix(`ON CONFLICT (ticker_id) DO NOTHING`).
     PlaceholderFormat(sq.Dollar)
-
-  if err := s.doPutQuery(builder); err != nil {
-    return err
-  }
-  s.counters.tickerDetails.Add(counterInc)
-
-  log.Infof("put ticker details for ticker '%s' in database. total: %d",
-    tickerDetails.TickerId, s.counters.tickerDetails.Load())
-
-  return nil
 }
 
-func (s *storage) PutStock(stock *domain.Stock) error {
+// PutStock put stock to database. stored stock is updated if its values changed, e.g.
+// bar of watermark day corrected by data provider. return false if stock already stored
+// with the same values
+func (s *storage) PutStock(stock *domain.Stock) (bool, error) {
   if stock == nil {
-    return fmt.Errorf("stock is a nil")
+    return false, fmt.Errorf("stock is a nil")
   }
   builder := sq.Insert(`stock`).
     Columns(
@@ -165,24 +219,65 @@ func (s *storage) PutStock(stock *domain.Stock) error {
       stock.StockedAt,
       stock.CreatedAt,
     ).
-    Suffix(`ON CONFLICT (stock_id) DO NOTHING`).
+    Suffix(`ON CONFLICT (stock_id) DO UPDATE SET ` +
+      `open_price = EXCLUDED.open_price, ` +
+      `close_price = EXCLUDED.close_price, ` +
+      `highest_price = EXCLUDED.highest_price, ` +
+      `lowest_price = EXCLUDED.lowest_price, ` +
+      `trading_volume = EXCLUDED.trading_volume ` +
+      `WHERE (stock.open_price, stock.close_price, stock.highest_price, stock.lowest_price, stock.trading_volume) ` +
+      `IS DISTINCT FROM ` +
+      `(EXCLUDED.open_price, EXCLUDED.close_price, EXCLUDED.highest_price, EXCLUDED.lowest_price, EXCLUDED.trading_volume)`).
     PlaceholderFormat(sq.Dollar)
 
-  if err := s.doPutQuery(builder); err != nil {
-    return err
+  affected, err := s.doPutQueryAffected(s.client, builder)
+  if err != nil {
+    return false, err
+  }
+  if affected == 0 {
+    return false, nil
   }
   s.counters.stock.Add(counterInc)
 
-  log.Infof("put stock '%s' for ticker '%s' in database. total: %d",
+  s.logger().Infof("put stock '%s' for ticker '%s' in database. total: %d",
     stock.StockId, stock.TickerId, s.counters.stock.Load())
 
-  return nil
+  return true, nil
 }
 
 func (s *storage) doPutQuery(builder queryBuilder) error {
+  return s.doPutQueryWith(s.client, builder)
+}
+
+func (s *storage) doPutQueryWith(executor queryExecutor, builder queryBuilder) error {
+  _, err := s.doPutQueryAffected(executor, builder)
+  return err
+}
+
+// doPutQueryAffected exec query and return count of affected rows
+func (s *storage) doPutQueryAffected(executor queryExecutor, builder queryBuilder) (int64, error) {
   query, args := mustBuildQuery(builder)
-  if _, err := s.client.Exec(s.ctx, query, args...); err != nil {
-    return fmt.Errorf("cannot do exec: %v", err)
+  tag, err := executor.Exec(s.ctx, query, args...)
+  if err != nil {
+    return 0, fmt.Errorf("cannot do exec: %v", err)
+  }
+  return tag.RowsAffected(), nil
+}
+
+// doInTx run handler in transaction. transaction committed if handler succeeded
+func (s *storage) doInTx(handler func(tx pgx.Tx) error) error {
+  tx, err := s.client.Begin(s.ctx)
+  if err != nil {
+    return fmt.Errorf("cannot begin transaction: %v", err)
+  }
+  if err = handler(tx); err != nil {
+    if rbErr := tx.Rollback(s.ctx); rbErr != nil {
+      s.logger().Errorf("cannot rollback transaction: %v", rbErr)
+    }
+    return err
+  }
+  if err = tx.Commit(s.ctx); err != nil {
+    return fmt.Errorf("cannot commit transaction: %v", err)
   }
   return nil
 }
@@ -195,13 +290,24 @@ func mustBuildQuery(builder queryBuilder) (string, []any) {
   return query, args
 }
 
+// PutFetcherState put fetcher state checkpoint. new checkpoint is inserted and old ones
+// are pruned in single transaction, so the latest checkpoint is always complete
 func (s *storage) PutFetcherState(state *domain.FetcherState) error {
+  const (
+    keptStatesCount = 5
+  )
+  if state == nil {
+    return fmt.Errorf("fetcher state is a nil")
+  }
   builder := sq.Insert(`fetcher_state`).
     Columns(
       // `fetcher_state_id` is serial type, autoincrement
       `ticker_req_url`,
       `ticker_details_req_url`,
       `stock_req_url`,
+      `cursor`,
+      `last_ticker_id`,
+      `member_id`,
       `created_at`,
       `finished`,
     ).
@@ -209,19 +315,37 @@ func (s *storage) PutFetcherState(state *domain.FetcherState) error {
       state.TickerReqUrl,
       state.TickerDetailsReqUrl,
       state.StockReqUrl,
+      state.Cursor,
+      state.LastTickerId,
+      state.MemberId,
       state.CreatedAt,
       state.Finished,
     ).
     PlaceholderFormat(sq.Dollar)
 
-  if err := s.doPutQuery(builder); err != nil {
+  pruneBuilder := sq.Delete(`fetcher_state`).
+    Where(sq.Eq{`member_id`: state.MemberId}).
+    Where(sq.Expr(fmt.Sprintf(`state_id NOT IN (SELECT state_id FROM fetcher_state WHERE member_id = ? ORDER BY created_at DESC LIMIT %d)`,
+      keptStatesCount), state.MemberId)).
+    PlaceholderFormat(sq.Dollar)
+
+  err := s.doInTx(func(tx pgx.Tx) error {
+    if err := s.doPutQueryWith(tx, builder); err != nil {
+      return err
+    }
+    return s.doPutQueryWith(tx, pruneBuilder)
+  })
+  if err != nil {
     return err
   }
-  log.Infof("sucessfully put fetcher state in database")
+  s.logger().Infof("sucessfully put fetcher state in database. finished: %t, cursor: '%s', last ticker: '%s'",
+    state.Finished, state.Cursor, state.LastTickerId)
   return nil
 }
 
-func (s *storage) GetFetcherState() (*domain.FetcherState, bool, error) {
+// GetFetcherState return the latest fetcher state checkpoint of member.
+// member id is empty if tickers are not sharded
+func (s *storage) GetFetcherState(memberId string) (*domain.FetcherState, bool, error) {
   const (
     queryLimit = 5
   )
@@ -230,10 +354,14 @@ func (s *storage) GetFetcherState() (*domain.FetcherState, bool, error) {
     `ticker_req_url`,
     `ticker_details_req_url`,
     `stock_req_url`,
+    `cursor`,
+    `last_ticker_id`,
+    `member_id`,
     `created_at`,
     `finished`,
   ).
     From(`fetcher_state`).
+    Where(sq.Eq{`member_id`: memberId}).
     OrderBy(`created_at DESC`).
     Limit(queryLimit).
     PlaceholderFormat(sq.Dollar)
@@ -244,6 +372,9 @@ func (s *storage) GetFetcherState() (*domain.FetcherState, bool, error) {
     &state.TickerReqUrl,
     &state.TickerDetailsReqUrl,
     &state.StockReqUrl,
+    &state.Cursor,
+    &state.LastTickerId,
+    &state.MemberId,
     &state.CreatedAt,
     &state.Finished,
   )
@@ -253,7 +384,7 @@ func (s *storage) GetFetcherState() (*domain.FetcherState, bool, error) {
   if !found {
     return nil, false, nil
   }
-  log.Infof("sucessfully get fetcher state at '%s' from database",
+  s.logger().Infof("sucessfully get fetcher state at '%s' from database",
     state.CreatedAt)
 
   return state, true, nil
@@ -265,6 +396,9 @@ func (s *storage) doGetQuery(builder queryBuilder, fields ...any) (bool, error)
   if err != nil {
     return false, fmt.Errorf("cannot do query: %v", err)
   }
+  // release connection back to pool
+  defer rows.Close()
+
   return scanFirstQueriedRow(rows, fields)
 }
 
@@ -17,6 +17,7 @@ type Client interface {
   Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
   Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
   QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+  Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 func NewClient(ctx context.Context, config *Config) (Client, error) {
@@ -38,5 +39,5 @@ func NewClient(ctx context.Context, config *Config) (Client, error) {
     return nil, fmt.Errorf("connection to posgtres pgx driver failed: %v", err)
   }
 
-  return pgxConn, nil
+  return newTracedClient(pgxConn), nil
 }
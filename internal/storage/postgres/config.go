@@ -7,12 +7,12 @@ import (
 const conn = "host=%s port=%d user=%s password=%s dbname=%s sslmode=%s"
 
 type Config struct {
-  Host     string `yaml:"host" required:"true"`
-  Port     int    `yaml:"port" required:"true"`
-  User     string `yaml:"user" required:"true"`
-  Password string `yaml:"password" required:"true"`
-  DBName   string `yaml:"db_name" required:"true"`
-  SSLMode  string `yaml:"ssl_mode" required:"true"`
+  Host     string `yaml:"host" env:"POSTGRES_HOST" required:"true"`
+  Port     int    `yaml:"port" env:"POSTGRES_PORT" required:"true" validate:"min=1,max=65535"`
+  User     string `yaml:"user" env:"POSTGRES_USER" required:"true"`
+  Password string `yaml:"password" env:"POSTGRES_PASSWORD" required:"true"`
+  DBName   string `yaml:"db_name" env:"POSTGRES_DB_NAME" required:"true"`
+  SSLMode  string `yaml:"ssl_mode" env:"POSTGRES_SSL_MODE" required:"true" validate:"oneof=disable allow prefer require verify-ca verify-full"`
 }
 
 func (c *Config) ConnectString() string {
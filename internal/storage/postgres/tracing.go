@@ -0,0 +1,115 @@
+package postgres
+
+import (
+  "context"
+  "scientific-research/internal/tracing"
+  "strings"
+
+  "github.com/jackc/pgconn"
+  "github.com/jackc/pgx/v4"
+  semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+  "go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("storage")
+
+// tracedClient client which records span of every query. query args are not recorded
+type tracedClient struct {
+  client Client
+}
+
+// tracedTx transaction which records span of every query
+type tracedTx struct {
+  pgx.Tx
+}
+
+func newTracedClient(client Client) Client {
+  return &tracedClient{client: client}
+}
+
+func (c *tracedClient) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+  return tracedExec(ctx, c.client.Exec, sql, arguments)
+}
+
+func (c *tracedClient) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+  return tracedQuery(ctx, c.client.Query, sql, args)
+}
+
+func (c *tracedClient) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+  ctx, span := startQuerySpan(ctx, sql)
+  defer span.End()
+
+  return c.client.QueryRow(ctx, sql, args...)
+}
+
+func (c *tracedClient) Begin(ctx context.Context) (pgx.Tx, error) {
+  tx, err := c.client.Begin(ctx)
+  if err != nil {
+    return nil, err
+  }
+  return &tracedTx{Tx: tx}, nil
+}
+
+func (t *tracedTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+  return tracedExec(ctx, t.Tx.Exec, sql, arguments)
+}
+
+func (t *tracedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+  return tracedQuery(ctx, t.Tx.Query, sql, args)
+}
+
+func (t *tracedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+  ctx, span := startQuerySpan(ctx, sql)
+  defer span.End()
+
+  return t.Tx.QueryRow(ctx, sql, args...)
+}
+
+func tracedExec(
+  ctx context.Context,
+  exec func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error),
+  sql string,
+  arguments []any,
+) (pgconn.CommandTag, error) {
+  ctx, span := startQuerySpan(ctx, sql)
+  tag, err := exec(ctx, sql, arguments...)
+  tracing.End(span, err)
+
+  return tag, err
+}
+
+// tracedQuery record span of query execution. reading of returned rows is not included
+func tracedQuery(
+  ctx context.Context,
+  query func(ctx context.Context, sql string, args ...any) (pgx.Rows, error),
+  sql string,
+  args []any,
+) (pgx.Rows, error) {
+  ctx, span := startQuerySpan(ctx, sql)
+  rows, err := query(ctx, sql, args...)
+  tracing.End(span, err)
+
+  return rows, err
+}
+
+func startQuerySpan(ctx context.Context, sql string) (context.Context, trace.Span) {
+  operation := queryOperation(sql)
+
+  return tracer.Start(ctx, "postgres "+operation,
+    trace.WithSpanKind(trace.SpanKindClient),
+    trace.WithAttributes(
+      semconv.DBSystemPostgreSQL,
+      semconv.DBOperation(operation),
+      semconv.DBStatement(sql),
+    ),
+  )
+}
+
+// queryOperation return the first keyword of sql, e.g. INSERT
+func queryOperation(sql string) string {
+  fields := strings.Fields(sql)
+  if len(fields) == 0 {
+    return ""
+  }
+  return strings.ToUpper(fields[0])
+}
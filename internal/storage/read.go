@@ -0,0 +1,205 @@
+package storage
+
+import (
+  "fmt"
+  "scientific-research/internal/domain"
+  "time"
+
+  sq "github.com/Masterminds/squirrel"
+  "github.com/jackc/pgx/v4"
+)
+
+// GetTickers return page of stored tickers ordered by ticker id
+//   afterTickerId return tickers after this one, empty for the first page
+//   limit page size
+func (s *storage) GetTickers(afterTickerId string, limit int) ([]*domain.Ticker, error) {
+  builder := sq.Select(
+    `ticker_id`,
+    `company_name`,
+    `company_locale`,
+    `currency_name`,
+    `ticker_cik`,
+    `active`,
+    `created_at`,
+    `external_updated_at`,
+  ).
+    From(`ticker`).
+    Where(sq.Gt{`ticker_id`: afterTickerId}).
+    OrderBy(`ticker_id`).
+    Limit(uint64(limit)).
+    PlaceholderFormat(sq.Dollar)
+
+  var tickers []*domain.Ticker
+  err := s.doSelectQuery(builder, func(rows pgx.Rows) error {
+    ticker := &domain.Ticker{}
+    if err := rows.Scan(
+      &ticker.TickerId,
+      &ticker.CompanyName,
+      &ticker.CompanyLocale,
+      &ticker.CurrencyName,
+      &ticker.TickerCik,
+      &ticker.Active,
+      &ticker.CreatedAt,
+      &ticker.ExternalUpdatedAt,
+    ); err != nil {
+      return err
+    }
+    tickers = append(tickers, ticker)
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+  return tickers, nil
+}
+
+// GetStocks return stocks of ticker stocked in [from, to) ordered by ticker and time
+//   tickerId ticker id, stocks of all tickers are returned if empty
+//   from range start, not limited if zero
+//   to range end, not limited if zero
+//   handler called for every stock, rows are not kept in memory
+func (s *storage) GetStocks(tickerId string, from, to time.Time, handler func(stock *domain.Stock) error) error {
+  builder := sq.Select(
+    `stock_id`,
+    `ticker_id`,
+    `open_price`,
+    `close_price`,
+    `highest_price`,
+    `lowest_price`,
+    `trading_volume`,
+    `stocked_at`,
+    `created_at`,
+  ).
+    From(`stock`).
+    OrderBy(`ticker_id`, `stocked_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  if tickerId != "" {
+    builder = builder.Where(sq.Eq{`ticker_id`: tickerId})
+  }
+  if !from.IsZero() {
+    builder = builder.Where(sq.GtOrEq{`stocked_at`: from})
+  }
+  if !to.IsZero() {
+    builder = builder.Where(sq.Lt{`stocked_at`: to})
+  }
+  return s.doSelectQuery(builder, func(rows pgx.Rows) error {
+    stock := &domain.Stock{}
+    if err := rows.Scan(
+      &stock.StockId,
+      &stock.TickerId,
+      &stock.OpenPrice,
+      &stock.ClosePrice,
+      &stock.HighestPrice,
+      &stock.LowestPrice,
+      &stock.TradingVolume,
+      &stock.StockedAt,
+      &stock.CreatedAt,
+    ); err != nil {
+      return err
+    }
+    return handler(stock)
+  })
+}
+
+// GetLastStock return the latest stored stock of ticker before time
+func (s *storage) GetLastStock(tickerId string, before time.Time) (*domain.Stock, bool, error) {
+  builder := sq.Select(
+    `stock_id`,
+    `ticker_id`,
+    `open_price`,
+    `close_price`,
+    `highest_price`,
+    `lowest_price`,
+    `trading_volume`,
+    `stocked_at`,
+    `created_at`,
+  ).
+    From(`stock`).
+    Where(sq.Eq{`ticker_id`: tickerId}).
+    Where(sq.Lt{`stocked_at`: before}).
+    OrderBy(`stocked_at DESC`).
+    Limit(1).
+    PlaceholderFormat(sq.Dollar)
+
+  stock := &domain.Stock{}
+  found, err := s.doGetQuery(builder,
+    &stock.StockId,
+    &stock.TickerId,
+    &stock.OpenPrice,
+    &stock.ClosePrice,
+    &stock.HighestPrice,
+    &stock.LowestPrice,
+    &stock.TradingVolume,
+    &stock.StockedAt,
+    &stock.CreatedAt,
+  )
+  if err != nil {
+    return nil, false, err
+  }
+  if !found {
+    return nil, false, nil
+  }
+  return stock, true, nil
+}
+
+// GetJobStates return states of scheduled jobs of all owners
+func (s *storage) GetJobStates() ([]*domain.JobState, error) {
+  builder := sq.Select(
+    `job_name`,
+    `owner_id`,
+    `last_run_at`,
+    `last_success_at`,
+    `next_run_at`,
+    `last_status`,
+    `last_error`,
+    `updated_at`,
+  ).
+    From(`scheduler_job`).
+    OrderBy(`job_name`, `owner_id`).
+    PlaceholderFormat(sq.Dollar)
+
+  var states []*domain.JobState
+  err := s.doSelectQuery(builder, func(rows pgx.Rows) error {
+    state := &domain.JobState{}
+    if err := rows.Scan(
+      &state.JobName,
+      &state.OwnerId,
+      &state.LastRunAt,
+      &state.LastSuccessAt,
+      &state.NextRunAt,
+      &state.LastStatus,
+      &state.LastError,
+      &state.UpdatedAt,
+    ); err != nil {
+      return err
+    }
+    states = append(states, state)
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+  return states, nil
+}
+
+// doSelectQuery run query and call handler for every row
+func (s *storage) doSelectQuery(builder queryBuilder, handler func(rows pgx.Rows) error) error {
+  query, args := mustBuildQuery(builder)
+  rows, err := s.client.Query(s.ctx, query, args...)
+  if err != nil {
+    return fmt.Errorf("cannot do query: %v", err)
+  }
+  // release connection back to pool
+  defer rows.Close()
+
+  for rows.Next() {
+    if err = handler(rows); err != nil {
+      return fmt.Errorf("cannot handle queried row: %v", err)
+    }
+  }
+  if err = rows.Err(); err != nil {
+    return fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  return nil
+}
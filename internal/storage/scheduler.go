@@ -0,0 +1,111 @@
+package storage
+
+import (
+  "fmt"
+  "scientific-research/internal/domain"
+
+  sq "github.com/Masterminds/squirrel"
+)
+
+func (s *storage) GetJobState(jobName, ownerId string) (*domain.JobState, bool, error) {
+  builder := sq.Select(
+    `job_name`,
+    `owner_id`,
+    `last_run_at`,
+    `last_success_at`,
+    `next_run_at`,
+    `last_status`,
+    `last_error`,
+    `updated_at`,
+  ).
+    From(`scheduler_job`).
+    Where(sq.Eq{
+      `job_name`: jobName,
+      `owner_id`: ownerId,
+    }).
+    PlaceholderFormat(sq.Dollar)
+
+  state := &domain.JobState{}
+  found, err := s.doGetQuery(builder,
+    &state.JobName,
+    &state.OwnerId,
+    &state.LastRunAt,
+    &state.LastSuccessAt,
+    &state.NextRunAt,
+    &state.LastStatus,
+    &state.LastError,
+    &state.UpdatedAt,
+  )
+  if err != nil {
+    return nil, false, err
+  }
+  if !found {
+    return nil, false, nil
+  }
+  return state, true, nil
+}
+
+func (s *storage) PutJobState(state *domain.JobState) error {
+  if state == nil {
+    return fmt.Errorf("job state is a nil")
+  }
+  builder := sq.Insert(`scheduler_job`).
+    Columns(
+      `job_name`,
+      `owner_id`,
+      `last_run_at`,
+      `last_success_at`,
+      `next_run_at`,
+      `last_status`,
+      `last_error`,
+      `updated_at`,
+    ).
+    Values(
+      state.JobName,
+      state.OwnerId,
+      state.LastRunAt,
+      state.LastSuccessAt,
+      state.NextRunAt,
+      state.LastStatus,
+      state.LastError,
+      sq.Expr(`NOW()`),
+    ).
+    Suffix(`ON CONFLICT (job_name, owner_id) DO UPDATE SET ` +
+      `last_run_at = EXCLUDED.last_run_at, last_success_at = EXCLUDED.last_success_at, ` +
+      `next_run_at = EXCLUDED.next_run_at, last_status = EXCLUDED.last_status, ` +
+      `last_error = EXCLUDED.last_error, updated_at = EXCLUDED.updated_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
+
+// GetTickerIds return ids of stored tickers
+//   activeOnly return only active tickers
+func (s *storage) GetTickerIds(activeOnly bool) ([]string, error) {
+  builder := sq.Select(`ticker_id`).
+    From(`ticker`).
+    OrderBy(`ticker_id`).
+    PlaceholderFormat(sq.Dollar)
+  if activeOnly {
+    builder = builder.Where(sq.Eq{`active`: true})
+  }
+  query, args := mustBuildQuery(builder)
+  rows, err := s.client.Query(s.ctx, query, args...)
+  if err != nil {
+    return nil, fmt.Errorf("cannot do query: %v", err)
+  }
+  defer rows.Close()
+
+  var tickerIds []string
+  for rows.Next() {
+    var tickerId string
+    if err = rows.Scan(&tickerId); err != nil {
+      return nil, fmt.Errorf("cannot scan queried row: %v", err)
+    }
+    tickerIds = append(tickerIds, tickerId)
+  }
+  if err = rows.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  return tickerIds, nil
+}
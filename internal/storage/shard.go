@@ -0,0 +1,157 @@
+package storage
+
+import (
+  "fmt"
+  "scientific-research/internal/domain"
+  "time"
+
+  sq "github.com/Masterminds/squirrel"
+  "github.com/jackc/pgx/v4"
+)
+
+// HeartbeatMember mark fetcher instance alive. database time is used
+func (s *storage) HeartbeatMember(fetcherId, memberId string) error {
+  builder := sq.Insert(`fetcher_member`).
+    Columns(
+      `fetcher_id`,
+      `member_id`,
+      `heartbeat_at`,
+    ).
+    Values(
+      fetcherId,
+      memberId,
+      sq.Expr(`NOW()`),
+    ).
+    Suffix(`ON CONFLICT (fetcher_id, member_id) DO UPDATE SET heartbeat_at = EXCLUDED.heartbeat_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
+
+// GetAliveMembers return instances of fetcher with heartbeat within ttl
+func (s *storage) GetAliveMembers(fetcherId string, ttl time.Duration) ([]string, error) {
+  builder := sq.Select(`member_id`).
+    From(`fetcher_member`).
+    Where(sq.Eq{`fetcher_id`: fetcherId}).
+    Where(sq.Expr(`heartbeat_at > NOW() - ?::float8 * INTERVAL '1 millisecond'`, float64(ttl.Milliseconds()))).
+    OrderBy(`member_id ASC`).
+    PlaceholderFormat(sq.Dollar)
+
+  query, args := mustBuildQuery(builder)
+  rows, err := s.client.Query(s.ctx, query, args...)
+  if err != nil {
+    return nil, fmt.Errorf("cannot do query: %v", err)
+  }
+  defer rows.Close()
+
+  var members []string
+  for rows.Next() {
+    var member string
+    if err = rows.Scan(&member); err != nil {
+      return nil, fmt.Errorf("cannot scan queried row: %v", err)
+    }
+    members = append(members, member)
+  }
+  if err = rows.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  return members, nil
+}
+
+func (s *storage) RemoveMember(fetcherId, memberId string) error {
+  builder := sq.Delete(`fetcher_member`).
+    Where(sq.Eq{
+      `fetcher_id`: fetcherId,
+      `member_id`:  memberId,
+    }).
+    PlaceholderFormat(sq.Dollar)
+
+  return s.doPutQuery(builder)
+}
+
+// PutShardStates put progress of shards in single transaction
+func (s *storage) PutShardStates(states []*domain.ShardState) error {
+  if len(states) == 0 {
+    return nil
+  }
+  return s.doInTx(func(tx pgx.Tx) error {
+    for _, state := range states {
+      if state == nil {
+        continue
+      }
+      builder := sq.Insert(`fetcher_shard_state`).
+        Columns(
+          `fetcher_id`,
+          `shard`,
+          `owner_id`,
+          `last_ticker_id`,
+          `processed_count`,
+          `cycle_started_at`,
+          `updated_at`,
+        ).
+        Values(
+          state.FetcherId,
+          state.Shard,
+          state.OwnerId,
+          state.LastTickerId,
+          state.ProcessedCount,
+          state.CycleStartedAt,
+          state.UpdatedAt,
+        ).
+        Suffix(`ON CONFLICT (fetcher_id, shard) DO UPDATE SET ` +
+          `owner_id = EXCLUDED.owner_id, last_ticker_id = EXCLUDED.last_ticker_id, ` +
+          `processed_count = EXCLUDED.processed_count, cycle_started_at = EXCLUDED.cycle_started_at, ` +
+          `updated_at = EXCLUDED.updated_at`).
+        PlaceholderFormat(sq.Dollar)
+
+      if err := s.doPutQueryWith(tx, builder); err != nil {
+        return fmt.Errorf("cannot put state of shard %d: %v", state.Shard, err)
+      }
+    }
+    return nil
+  })
+}
+
+func (s *storage) GetShardStates(fetcherId string) ([]*domain.ShardState, error) {
+  builder := sq.Select(
+    `fetcher_id`,
+    `shard`,
+    `owner_id`,
+    `last_ticker_id`,
+    `processed_count`,
+    `cycle_started_at`,
+    `updated_at`,
+  ).
+    From(`fetcher_shard_state`).
+    Where(sq.Eq{`fetcher_id`: fetcherId}).
+    OrderBy(`shard ASC`).
+    PlaceholderFormat(sq.Dollar)
+
+  query, args := mustBuildQuery(builder)
+  rows, err := s.client.Query(s.ctx, query, args...)
+  if err != nil {
+    return nil, fmt.Errorf("cannot do query: %v", err)
+  }
+  defer rows.Close()
+
+  var states []*domain.ShardState
+  for rows.Next() {
+    state := &domain.ShardState{}
+    if err = rows.Scan(
+      &state.FetcherId,
+      &state.Shard,
+      &state.OwnerId,
+      &state.LastTickerId,
+      &state.ProcessedCount,
+      &state.CycleStartedAt,
+      &state.UpdatedAt,
+    ); err != nil {
+      return nil, fmt.Errorf("cannot scan queried row: %v", err)
+    }
+    states = append(states, state)
+  }
+  if err = rows.Err(); err != nil {
+    return nil, fmt.Errorf("cannot read queried rows: %v", err)
+  }
+  return states, nil
+}
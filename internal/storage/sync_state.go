@@ -0,0 +1,70 @@
+package storage
+
+import (
+  "fmt"
+  "scientific-research/internal/domain"
+
+  sq "github.com/Masterminds/squirrel"
+)
+
+func (s *storage) GetTickerSyncState(tickerId, interval string) (*domain.TickerSyncState, bool, error) {
+  builder := sq.Select(
+    `ticker_id`,
+    `interval`,
+    `last_bar_at`,
+    `updated_at`,
+  ).
+    From(`ticker_sync_state`).
+    Where(sq.Eq{
+      `ticker_id`: tickerId,
+      `interval`:  interval,
+    }).
+    PlaceholderFormat(sq.Dollar)
+
+  state := &domain.TickerSyncState{}
+  found, err := s.doGetQuery(builder,
+    &state.TickerId,
+    &state.Interval,
+    &state.LastBarAt,
+    &state.UpdatedAt,
+  )
+  if err != nil {
+    return nil, false, err
+  }
+  if !found {
+    return nil, false, nil
+  }
+  return state, true, nil
+}
+
+// PutTickerSyncState put ticker watermark. watermark never moves back
+func (s *storage) PutTickerSyncState(state *domain.TickerSyncState) error {
+  if state == nil {
+    return fmt.Errorf("ticker sync state is a nil")
+  }
+  builder := sq.Insert(`ticker_sync_state`).
+    Columns(
+      `ticker_id`,
+      `interval`,
+      `last_bar_at`,
+      `updated_at`,
+    ).
+    Values(
+      state.TickerId,
+      state.Interval,
+      state.LastBarAt,
+      state.UpdatedAt,
+    ).
+    Suffix(`ON CONFLICT (ticker_id, interval) DO UPDATE SET ` +
+      `last_bar_at = GREATEST(ticker_sync_state.last_bar_at, EXCLUDED.last_bar_at), ` +
+      `updated_at = EXCLUDED.updated_at`).
+    PlaceholderFormat(sq.Dollar)
+
+  if err := s.doPutQuery(builder); err != nil {
+    return err
+  }
+  s.logger().Infof("put sync state of ticker '%s' interval '%s' in database. last bar at: %s",
+    state.TickerId, state.Interval, state.LastBarAt)
+
+  return nil
+}
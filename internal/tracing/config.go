@@ -0,0 +1,22 @@
+package tracing
+
+const (
+  ExporterOTLP   = "otlp"
+  ExporterStdout = "stdout"
+)
+
+// Config traces export. tracing is disabled if exporter is not set. `otlp` exporter sends
+// spans to OTLP HTTP collector at endpoint (`localhost:4318` by default), `stdout` prints
+// spans as json. sample ratio is ratio of sampled cycles, all cycles are sampled by default
+type Config struct {
+  Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" validate:"oneof=otlp stdout"`
+  Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+  Insecure    bool    `yaml:"insecure" env:"TRACING_INSECURE"`
+  ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+  SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" validate:"max=1"`
+}
+
+// Enabled return true if spans must be exported
+func (c *Config) Enabled() bool {
+  return c != nil && c.Exporter != ""
+}
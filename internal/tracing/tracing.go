@@ -0,0 +1,99 @@
+package tracing
+
+import (
+  "context"
+  "fmt"
+  "os"
+
+  "go.opentelemetry.io/otel"
+  "go.opentelemetry.io/otel/codes"
+  "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+  "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+  "go.opentelemetry.io/otel/propagation"
+  "go.opentelemetry.io/otel/sdk/resource"
+  sdktrace "go.opentelemetry.io/otel/sdk/trace"
+  semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+  "go.opentelemetry.io/otel/trace"
+)
+
+const (
+  instrumentationPrefix = "scientific-research/"
+  defaultSampleRatio    = 1
+)
+
+// Shutdown flush exported spans and stop tracer provider
+type Shutdown func(ctx context.Context) error
+
+// Setup set global tracer provider and trace context propagator. spans are not recorded
+// if tracing is disabled, shutdown does nothing then
+//   defaultServiceName service name used if config has no service name
+func Setup(ctx context.Context, config *Config, defaultServiceName string) (Shutdown, error) {
+  otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+    propagation.TraceContext{},
+    propagation.Baggage{},
+  ))
+  if !config.Enabled() {
+    return func(context.Context) error { return nil }, nil
+  }
+  exporter, err := newExporter(ctx, config)
+  if err != nil {
+    return nil, fmt.Errorf("cannot create %s exporter: %v", config.Exporter, err)
+  }
+  serviceName := config.ServiceName
+  if serviceName == "" {
+    serviceName = defaultServiceName
+  }
+  sampleRatio := config.SampleRatio
+  if sampleRatio <= 0 {
+    sampleRatio = defaultSampleRatio
+  }
+  provider := sdktrace.NewTracerProvider(
+    sdktrace.WithBatcher(exporter),
+    sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+    sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+  )
+  otel.SetTracerProvider(provider)
+
+  return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config *Config) (sdktrace.SpanExporter, error) {
+  switch config.Exporter {
+  case ExporterStdout:
+    return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+  case ExporterOTLP:
+    var options []otlptracehttp.Option
+    if config.Endpoint != "" {
+      options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+    }
+    if config.Insecure {
+      options = append(options, otlptracehttp.WithInsecure())
+    }
+    return otlptracehttp.New(ctx, options...)
+  }
+  return nil, fmt.Errorf("unknown exporter '%s'. possible: %s, %s", config.Exporter, ExporterOTLP, ExporterStdout)
+}
+
+// Tracer return tracer of package
+//   name package name
+func Tracer(name string) trace.Tracer {
+  return otel.Tracer(instrumentationPrefix + name)
+}
+
+// TraceId return trace id of span of context, empty if context has no span
+func TraceId(ctx context.Context) string {
+  spanContext := trace.SpanContextFromContext(ctx)
+  if !spanContext.HasTraceID() {
+    return ""
+  }
+  return spanContext.TraceID().String()
+}
+
+// End record error of span operation and end span
+func End(span trace.Span, err error) {
+  if err != nil {
+    span.RecordError(err)
+    span.SetStatus(codes.Error, err.Error())
+  }
+  span.End()
+}
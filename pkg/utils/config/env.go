@@ -0,0 +1,193 @@
+package config
+
+import (
+  "fmt"
+  "os"
+  "reflect"
+  "regexp"
+  "strconv"
+  "strings"
+  "time"
+)
+
+const (
+  envTagKey     = "env"
+  envFileSuffix = "_FILE"
+  envListSep    = ","
+)
+
+// interpolationPattern matches `${VAR}` and `${VAR:-default}`
+var interpolationPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// LookupEnv return environment variable value. if variable is not set
+// but `<name>_FILE` is, value is read from file (docker and kubernetes secrets)
+//   name environment variable name
+func LookupEnv(name string) (string, bool, error) {
+  if value, ok := os.LookupEnv(name); ok {
+    return value, true, nil
+  }
+  path, ok := os.LookupEnv(fmt.Sprint(name, envFileSuffix))
+  if !ok {
+    return "", false, nil
+  }
+  content, err := os.ReadFile(path)
+  if err != nil {
+    return "", false, fmt.Errorf("cannot read secret file for '%s': %v", name, err)
+  }
+  return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// Interpolate replace `${VAR}` and `${VAR:-default}` references with environment variable values
+//   value raw config value
+func Interpolate(value string) (string, error) {
+  var errs []string
+
+  result := interpolationPattern.ReplaceAllStringFunc(value, func(ref string) string {
+    groups := interpolationPattern.FindStringSubmatch(ref)
+    name := groups[1]
+    hasDefault := groups[2] != ""
+
+    envValue, found, err := LookupEnv(name)
+    if err != nil {
+      errs = append(errs, err.Error())
+      return ""
+    }
+    if found {
+      return envValue
+    }
+    if hasDefault {
+      return groups[3]
+    }
+    errs = append(errs, fmt.Sprintf("environment variable '%s' is not set", name))
+    return ""
+  })
+  if len(errs) != 0 {
+    return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+  }
+  return result, nil
+}
+
+// ApplyEnvOverrides set struct fields with `env: "NAME"` tag from environment variables.
+// nested structs and pointers to structs are processed recursively
+//   anyStruct pointer to any struct
+func ApplyEnvOverrides(anyStruct any) error {
+  refVal := reflect.ValueOf(anyStruct)
+  if refVal.Kind() != reflect.Pointer || refVal.Elem().Kind() != reflect.Struct {
+    return fmt.Errorf("%T not a pointer to struct", anyStruct)
+  }
+  _, err := applyEnvOverrides(refVal.Elem())
+  return err
+}
+
+func applyEnvOverrides(refVal reflect.Value) (bool, error) {
+  refType := refVal.Type()
+  applied := false
+
+  for fieldIdx := 0; fieldIdx < refVal.NumField(); fieldIdx++ {
+    field := refVal.Field(fieldIdx)
+    fieldType := refType.Field(fieldIdx)
+
+    if !field.CanSet() {
+      continue
+    }
+    if envName, ok := fieldType.Tag.Lookup(envTagKey); ok && envName != "" {
+      value, found, err := LookupEnv(envName)
+      if err != nil {
+        return false, err
+      }
+      if found {
+        if err = setFieldFromString(field, value); err != nil {
+          return false, fmt.Errorf("cannot set field '%s' from '%s': %v", fieldType.Name, envName, err)
+        }
+        applied = true
+        continue
+      }
+    }
+    nestedApplied, err := applyNestedEnvOverrides(field)
+    if err != nil {
+      return false, fmt.Errorf("%s: %v", fieldType.Name, err)
+    }
+    applied = applied || nestedApplied
+  }
+  return applied, nil
+}
+
+func applyNestedEnvOverrides(field reflect.Value) (bool, error) {
+  switch {
+  case field.Kind() == reflect.Struct:
+    return applyEnvOverrides(field)
+
+  case field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.Struct:
+    if !field.IsNil() {
+      return applyEnvOverrides(field.Elem())
+    }
+    // allocate nested struct only if any of its fields set from environment
+    nested := reflect.New(field.Type().Elem())
+    applied, err := applyEnvOverrides(nested.Elem())
+    if err != nil || !applied {
+      return false, err
+    }
+    field.Set(nested)
+    return true, nil
+  }
+  return false, nil
+}
+
+func setFieldFromString(field reflect.Value, value string) error {
+  if field.Type() == reflect.TypeOf(time.Duration(0)) {
+    dur, err := time.ParseDuration(value)
+    if err != nil {
+      return err
+    }
+    field.SetInt(int64(dur))
+    return nil
+  }
+  switch field.Kind() {
+  case reflect.String:
+    field.SetString(value)
+
+  case reflect.Bool:
+    parsed, err := strconv.ParseBool(value)
+    if err != nil {
+      return err
+    }
+    field.SetBool(parsed)
+
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+    if err != nil {
+      return err
+    }
+    field.SetInt(parsed)
+
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+    if err != nil {
+      return err
+    }
+    field.SetUint(parsed)
+
+  case reflect.Float32, reflect.Float64:
+    parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+    if err != nil {
+      return err
+    }
+    field.SetFloat(parsed)
+
+  case reflect.Slice:
+    if field.Type().Elem().Kind() != reflect.String {
+      return fmt.Errorf("unsupported slice type: %s", field.Type())
+    }
+    var values []string
+    for _, item := range strings.Split(value, envListSep) {
+      if item = strings.TrimSpace(item); item != "" {
+        values = append(values, item)
+      }
+    }
+    field.Set(reflect.ValueOf(values).Convert(field.Type()))
+
+  default:
+    return fmt.Errorf("unsupported field type: %s", field.Type())
+  }
+  return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+  "context"
+  "os"
+  "scientific-research/pkg/utils/logging"
+  "time"
+)
+
+var log = logging.Logger("config")
+
+// Watch poll config file and call onChange when its modification time or size changed.
+// blocks until context is done
+//   path path to config file
+//   interval polling interval
+//   onChange callback called from watching goroutine
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+  lastInfo, err := os.Stat(path)
+  if err != nil {
+    log.Warnf("cannot stat config file '%s': %v", path, err)
+  }
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+
+    case <-ticker.C:
+      info, err := os.Stat(path)
+      if err != nil {
+        log.Warnf("cannot stat config file '%s': %v", path, err)
+        continue
+      }
+      if lastInfo != nil && info.ModTime().Equal(lastInfo.ModTime()) && info.Size() == lastInfo.Size() {
+        continue
+      }
+      lastInfo = info
+      onChange()
+    }
+  }
+}
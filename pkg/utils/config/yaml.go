@@ -11,13 +11,48 @@ type Config interface {
   Parse(configPath string) error
 }
 
+// ParseYamlConfig decode yaml config file, interpolate `${VAR}` references
+// in values and apply overrides from environment variables by `env` tags
 func ParseYamlConfig[T Config](path string, config T) error {
   file, err := os.Open(path)
   if err != nil {
     return fmt.Errorf("cannot open config file: %v", err)
   }
-  if err := yaml.NewDecoder(file).Decode(config); err != nil {
+  defer file.Close()
+
+  root := &yaml.Node{}
+  if err = yaml.NewDecoder(file).Decode(root); err != nil {
     return fmt.Errorf("cannot decode config: %v", err)
   }
+  if err = interpolateNode(root); err != nil {
+    return fmt.Errorf("cannot interpolate config: %v", err)
+  }
+  if err = root.Decode(config); err != nil {
+    return fmt.Errorf("cannot decode config: %v", err)
+  }
+  if err = ApplyEnvOverrides(config); err != nil {
+    return fmt.Errorf("cannot apply environment overrides: %v", err)
+  }
+  return nil
+}
+
+func interpolateNode(node *yaml.Node) error {
+  if node.Kind == yaml.ScalarNode {
+    value, err := Interpolate(node.Value)
+    if err != nil {
+      return fmt.Errorf("line %d: %v", node.Line, err)
+    }
+    if value != node.Value && node.Style == 0 {
+      // resolve tag of plain scalar again, e.g. `port: ${PORT}` must be decoded as int
+      node.Tag = ""
+    }
+    node.Value = value
+    return nil
+  }
+  for _, child := range node.Content {
+    if err := interpolateNode(child); err != nil {
+      return err
+    }
+  }
   return nil
 }
@@ -3,16 +3,17 @@ package httputils
 import (
   "fmt"
   "net/http"
-
-  log "github.com/sirupsen/logrus"
+  "scientific-research/pkg/utils/logging"
 )
 
+var log = logging.Logger("httputils")
+
 func HandleHealth() http.HandlerFunc {
   return func(w http.ResponseWriter, _ *http.Request) {
     w.WriteHeader(http.StatusOK)
     _, err := w.Write([]byte("/ok"))
     if err != nil {
-      log.Printf("cannot write to response writer: %v", err)
+      log.Errorf("cannot write to response writer: %v", err)
     }
   }
 }
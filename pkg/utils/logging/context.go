@@ -0,0 +1,68 @@
+package logging
+
+import (
+  "context"
+  "crypto/rand"
+  "encoding/hex"
+
+  "github.com/sirupsen/logrus"
+)
+
+const correlationIdSize = 8
+
+type fieldsKey struct{}
+
+// WithFields return context with log fields added to fields of parent context
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+  merged := logrus.Fields{}
+  for key, value := range Fields(ctx) {
+    merged[key] = value
+  }
+  for key, value := range fields {
+    merged[key] = value
+  }
+  return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithField return context with log field added to fields of parent context
+func WithField(ctx context.Context, key string, value any) context.Context {
+  return WithFields(ctx, logrus.Fields{key: value})
+}
+
+// WithCorrelationId return context with new correlation id of fetching cycle or request
+func WithCorrelationId(ctx context.Context) context.Context {
+  return WithField(ctx, FieldCorrelationId, newCorrelationId())
+}
+
+// CorrelationId return correlation id of context, empty if context has no correlation id
+func CorrelationId(ctx context.Context) string {
+  correlationId, _ := Fields(ctx)[FieldCorrelationId].(string)
+  return correlationId
+}
+
+// Fields return log fields of context. returned fields must not be modified
+func Fields(ctx context.Context) logrus.Fields {
+  if ctx == nil {
+    return nil
+  }
+  fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+  return fields
+}
+
+// FromContext return entry of logger with log fields of context
+func FromContext(ctx context.Context, logger *logrus.Entry) *logrus.Entry {
+  fields := Fields(ctx)
+  if len(fields) == 0 {
+    return logger
+  }
+  return logger.WithFields(fields)
+}
+
+func newCorrelationId() string {
+  id := make([]byte, correlationIdSize)
+  if _, err := rand.Read(id); err != nil {
+    // crypto rand never fails on supported platforms
+    panic(err)
+  }
+  return hex.EncodeToString(id)
+}
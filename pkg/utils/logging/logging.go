@@ -0,0 +1,146 @@
+package logging
+
+import (
+  "fmt"
+  "os"
+  "strings"
+  "sync"
+  "time"
+
+  "github.com/sirupsen/logrus"
+)
+
+const (
+  FormatJSON = "json"
+  FormatText = "text"
+
+  defaultFormat = FormatJSON
+  defaultLevel  = logrus.InfoLevel
+)
+
+// fields of structured log entries
+const (
+  FieldPackage       = "package"
+  FieldCorrelationId = "correlation_id"
+  FieldFetcher       = "fetcher"
+  FieldJob           = "job"
+  FieldMode          = "mode"
+  FieldTicker        = "ticker"
+  FieldURLPath       = "url_path"
+  FieldAttempt       = "attempt"
+  FieldDuration      = "duration"
+)
+
+// Config log output. levels of packages override default level, e.g. `httpclient: debug`.
+// package names are names of go packages: polygon, httpclient, storage, queue, etc.
+type Config struct {
+  Format string            `yaml:"format" env:"LOG_FORMAT" validate:"oneof=json text"`
+  Level  string            `yaml:"level" env:"LOG_LEVEL"`
+  Levels map[string]string `yaml:"levels"`
+}
+
+// registry loggers of packages. loggers are created on package init before
+// config is parsed, Setup updates all of them
+type registry struct {
+  mu        sync.Mutex
+  formatter logrus.Formatter
+  level     logrus.Level
+  levels    map[string]logrus.Level
+  loggers   map[string]*logrus.Logger
+}
+
+var loggers = &registry{
+  formatter: newFormatter(defaultFormat),
+  level:     defaultLevel,
+  levels:    map[string]logrus.Level{},
+  loggers:   map[string]*logrus.Logger{},
+}
+
+func init() {
+  logrus.SetFormatter(loggers.formatter)
+}
+
+// Logger return logger of package. entries have `package` field
+//   name package name used by levels config
+func Logger(name string) *logrus.Entry {
+  loggers.mu.Lock()
+  defer loggers.mu.Unlock()
+
+  logger, ok := loggers.loggers[name]
+  if !ok {
+    logger = logrus.New()
+    logger.SetOutput(os.Stderr)
+    logger.SetFormatter(loggers.formatter)
+    logger.SetLevel(loggers.levelOf(name))
+    loggers.loggers[name] = logger
+  }
+  return logger.WithField(FieldPackage, name)
+}
+
+// Setup apply config to loggers of all packages and to standard logger.
+// nil config set default json output with info level
+func Setup(config *Config) error {
+  if config == nil {
+    config = &Config{}
+  }
+  format := strings.ToLower(strings.TrimSpace(config.Format))
+  if format == "" {
+    format = defaultFormat
+  }
+  if format != FormatJSON && format != FormatText {
+    return fmt.Errorf("unknown log format '%s'. possible: %s, %s", config.Format, FormatJSON, FormatText)
+  }
+  level, err := parseLevel(config.Level, defaultLevel)
+  if err != nil {
+    return err
+  }
+  levels := map[string]logrus.Level{}
+  for name, value := range config.Levels {
+    if levels[name], err = parseLevel(value, level); err != nil {
+      return fmt.Errorf("invalid level of package '%s': %v", name, err)
+    }
+  }
+  formatter := newFormatter(format)
+
+  loggers.mu.Lock()
+  defer loggers.mu.Unlock()
+
+  loggers.formatter = formatter
+  loggers.level = level
+  loggers.levels = levels
+
+  for name, logger := range loggers.loggers {
+    logger.SetFormatter(formatter)
+    logger.SetLevel(loggers.levelOf(name))
+  }
+  logrus.SetFormatter(formatter)
+  logrus.SetLevel(level)
+
+  return nil
+}
+
+func (r *registry) levelOf(name string) logrus.Level {
+  if level, ok := r.levels[name]; ok {
+    return level
+  }
+  return r.level
+}
+
+func parseLevel(value string, defaultLevel logrus.Level) (logrus.Level, error) {
+  value = strings.TrimSpace(value)
+  if value == "" {
+    return defaultLevel, nil
+  }
+  level, err := logrus.ParseLevel(value)
+  if err != nil {
+    return 0, fmt.Errorf("unknown log level '%s'", value)
+  }
+  return level, nil
+}
+
+func newFormatter(format string) logrus.Formatter {
+  if format == FormatText {
+    return &logrus.TextFormatter{FullTimestamp: true, TimestampFormat: time.RFC3339Nano}
+  }
+  return &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+}
@@ -2,11 +2,12 @@ package retries
 
 import (
   "scientific-research/pkg/utils/common"
+  "scientific-research/pkg/utils/logging"
   "time"
-
-  log "github.com/sirupsen/logrus"
 )
 
+var log = logging.Logger("retries")
+
 const (
   retryCount   = 5
   waitInterval = 1 * time.Minute
@@ -2,34 +2,216 @@ package validation
 
 import (
   "fmt"
+  "net/url"
   "reflect"
   "strconv"
+  "strings"
 )
 
-// CheckRequiredFields check fields with `required: true` tag
+const (
+  requiredTagKey = "required"
+  validateTagKey = "validate"
+  nameTagKey     = "yaml"
+
+  rulesSep     = ","
+  ruleParamSep = "="
+  pathSep      = "."
+)
+
+const (
+  ruleMin   = "min"
+  ruleMax   = "max"
+  ruleOneOf = "oneof"
+  ruleURL   = "url"
+)
+
+// Violation single failed check of struct field
+type Violation struct {
+  Path    string
+  Message string
+}
+
+// Errors all violations found in struct
+type Errors []*Violation
+
+func (e Errors) Error() string {
+  messages := make([]string, 0, len(e))
+  for _, violation := range e {
+    messages = append(messages, fmt.Sprintf("%s: %s", violation.Path, violation.Message))
+  }
+  return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+// ValidateStruct check fields with `required: "true"` and `validate: "<rules>"` tags.
+// zero values of required fields treated as missing. nested structs and pointers
+// to structs checked recursively. all violations collected in single Errors value
 //   anyStruct pointer to any struct
-func CheckRequiredFields[T any](anyStruct T) error {
-  const tagKey = "required"
-  refVal, refType, err := getStructReflection(anyStruct)
+//
+// supported rules (comma separated, skipped for zero values of not required fields):
+//   min=N, max=N  bounds for numbers, length bounds for strings and slices
+//   oneof=a b c   value must be one of space separated options
+//   url           value must be absolute url with scheme and host
+func ValidateStruct[T any](anyStruct T) error {
+  refVal, _, err := getStructReflection(anyStruct)
   if err != nil {
     return fmt.Errorf("cannot get struct reflection: %v", err)
   }
+  var violations Errors
+  if err = validateStruct(refVal, "", &violations); err != nil {
+    return err
+  }
+  if len(violations) != 0 {
+    return violations
+  }
+  return nil
+}
+
+func validateStruct(refVal reflect.Value, parentPath string, violations *Errors) error {
+  refType := refVal.Type()
+
   for fieldIdx := 0; fieldIdx < refVal.NumField(); fieldIdx++ {
-    tagVal, tagExist := refType.Field(fieldIdx).Tag.Lookup(tagKey)
-    if !tagExist {
+    fieldType := refType.Field(fieldIdx)
+    if !fieldType.IsExported() {
       continue
     }
-    if trueTagVal, _ := strconv.ParseBool(tagVal); !trueTagVal {
+    field := refVal.Field(fieldIdx)
+    path := joinPath(parentPath, fieldName(fieldType))
+
+    required, _ := strconv.ParseBool(fieldType.Tag.Get(requiredTagKey))
+    if field.IsZero() {
+      if required {
+        *violations = append(*violations, &Violation{Path: path, Message: "is required"})
+      }
       continue
     }
-    fieldName := refType.Field(fieldIdx).Name
-    if fieldI := refVal.Field(fieldIdx).Interface(); fieldI == nil {
-      return fmt.Errorf("field '%s' with tag '%s' is empty", fieldName, tagKey)
+    if rules, ok := fieldType.Tag.Lookup(validateTagKey); ok {
+      if err := checkRules(field, path, rules, violations); err != nil {
+        return err
+      }
+    }
+    if nested, ok := nestedStruct(field); ok {
+      if err := validateStruct(nested, path, violations); err != nil {
+        return err
+      }
     }
   }
   return nil
 }
 
+func nestedStruct(field reflect.Value) (reflect.Value, bool) {
+  if field.Kind() == reflect.Pointer {
+    if field.IsNil() {
+      return reflect.Value{}, false
+    }
+    field = field.Elem()
+  }
+  // time.Time and similar values have no exported fields to check
+  if field.Kind() != reflect.Struct || field.Type().PkgPath() == "time" {
+    return reflect.Value{}, false
+  }
+  return field, true
+}
+
+func checkRules(field reflect.Value, path, rules string, violations *Errors) error {
+  if field.Kind() == reflect.Pointer {
+    field = field.Elem()
+  }
+  for _, rule := range strings.Split(rules, rulesSep) {
+    rule = strings.TrimSpace(rule)
+    if rule == "" {
+      continue
+    }
+    name, param, _ := strings.Cut(rule, ruleParamSep)
+
+    message, err := checkRule(field, name, param)
+    if err != nil {
+      return fmt.Errorf("field '%s' has malformed rule '%s': %v", path, rule, err)
+    }
+    if message != "" {
+      *violations = append(*violations, &Violation{Path: path, Message: message})
+    }
+  }
+  return nil
+}
+
+// checkRule return violation message or empty string if field satisfies rule
+func checkRule(field reflect.Value, name, param string) (string, error) {
+  switch name {
+  case ruleMin, ruleMax:
+    bound, err := strconv.ParseFloat(param, 64)
+    if err != nil {
+      return "", fmt.Errorf("bound is not a number")
+    }
+    value, isLength, err := measure(field)
+    if err != nil {
+      return "", err
+    }
+    what := "must be"
+    if isLength {
+      what = "length must be"
+    }
+    if name == ruleMin && value < bound {
+      return fmt.Sprintf("%s at least %s", what, param), nil
+    }
+    if name == ruleMax && value > bound {
+      return fmt.Sprintf("%s at most %s", what, param), nil
+    }
+
+  case ruleOneOf:
+    options := strings.Fields(param)
+    value := fmt.Sprint(field.Interface())
+    for _, option := range options {
+      if value == option {
+        return "", nil
+      }
+    }
+    return fmt.Sprintf("must be one of [%s]", strings.Join(options, ", ")), nil
+
+  case ruleURL:
+    if field.Kind() != reflect.String {
+      return "", fmt.Errorf("not a string field")
+    }
+    parsedURL, err := url.Parse(field.String())
+    if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+      return "must be a valid absolute url", nil
+    }
+
+  default:
+    return "", fmt.Errorf("unknown rule")
+  }
+  return "", nil
+}
+
+// measure return numeric value of number fields or length of strings, slices and maps
+func measure(field reflect.Value) (float64, bool, error) {
+  switch field.Kind() {
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return float64(field.Int()), false, nil
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    return float64(field.Uint()), false, nil
+  case reflect.Float32, reflect.Float64:
+    return field.Float(), false, nil
+  case reflect.String, reflect.Slice, reflect.Map:
+    return float64(field.Len()), true, nil
+  }
+  return 0, false, fmt.Errorf("unsupported field type: %s", field.Type())
+}
+
+func fieldName(field reflect.StructField) string {
+  name, _, _ := strings.Cut(field.Tag.Get(nameTagKey), ",")
+  if name == "" || name == "-" {
+    return field.Name
+  }
+  return name
+}
+
+func joinPath(parentPath, name string) string {
+  if parentPath == "" {
+    return name
+  }
+  return fmt.Sprint(parentPath, pathSep, name)
+}
+
 // SetDefaultStringValues set default value for string fields in any struct
 //   anyStruct pointer to any struct
 //   defaultValue string value to set
@@ -0,0 +1,194 @@
+package validation
+
+import (
+  "errors"
+  "testing"
+  "time"
+)
+
+type testQueueConfig struct {
+  Host string `yaml:"host" required:"true"`
+  Port int    `yaml:"port" required:"true" validate:"min=1,max=65535"`
+}
+
+type testLimitsConfig struct {
+  RequestsCount int           `yaml:"requests_count" validate:"min=1"`
+  RequestsPer   time.Duration `yaml:"requests_per,omitempty" validate:"max=60000000000"`
+}
+
+type testConfig struct {
+  ApiToken    string           `yaml:"api_token" required:"true"`
+  ApiBaseUrl  string           `yaml:"api_base_url" validate:"url"`
+  Mode        string           `yaml:"mode" validate:"oneof=total current"`
+  Name        string           `yaml:"name" validate:"min=2,max=8"`
+  Tickers     []string         `yaml:"tickers" validate:"max=2"`
+  Workers     *int             `yaml:"workers" validate:"min=1"`
+  QueueConfig *testQueueConfig `yaml:"queue_config"`
+  Limits      testLimitsConfig `yaml:"limits"`
+  Retries     int              `validate:"max=5"`
+  CreatedAt   time.Time        `yaml:"created_at"`
+  // unexported fields are not checked
+  internal string `required:"true"`
+}
+
+func validTestConfig() *testConfig {
+  workers := 4
+  return &testConfig{
+    ApiToken:    "token",
+    ApiBaseUrl:  "https://api.polygon.io",
+    Mode:        "total",
+    Name:        "polygon",
+    Tickers:     []string{"AAPL"},
+    Workers:     &workers,
+    QueueConfig: &testQueueConfig{Host: "localhost", Port: 5672},
+    Limits:      testLimitsConfig{RequestsCount: 5, RequestsPer: time.Minute},
+    Retries:     3,
+  }
+}
+
+func TestValidateStruct(t *testing.T) {
+  zero := 0
+  tests := []struct {
+    name   string
+    modify func(c *testConfig)
+    // wantPaths paths of violations in order, nil if config is valid
+    wantPaths    []string
+    wantMessages []string
+  }{
+    {"valid", func(c *testConfig) {}, nil, nil},
+    {"zero values of not required fields skip rules", func(c *testConfig) {
+      c.ApiBaseUrl, c.Mode, c.Name, c.Tickers, c.Workers, c.Retries = "", "", "", nil, nil, 0
+      c.Limits = testLimitsConfig{}
+    }, nil, nil},
+    {"nil nested pointer is not checked", func(c *testConfig) {
+      c.QueueConfig = nil
+    }, nil, nil},
+    {"missing required field", func(c *testConfig) {
+      c.ApiToken = ""
+    }, []string{"api_token"}, []string{"is required"}},
+    {"zero values of required fields of nested pointer", func(c *testConfig) {
+      c.QueueConfig = &testQueueConfig{}
+    }, []string{"queue_config.host", "queue_config.port"}, []string{"is required", "is required"}},
+    {"max of nested pointer field", func(c *testConfig) {
+      c.QueueConfig.Port = 70000
+    }, []string{"queue_config.port"}, []string{"must be at most 65535"}},
+    {"min of nested struct field", func(c *testConfig) {
+      c.Limits.RequestsCount = -1
+    }, []string{"limits.requests_count"}, []string{"must be at least 1"}},
+    {"yaml name options are dropped", func(c *testConfig) {
+      c.Limits.RequestsPer = time.Hour
+    }, []string{"limits.requests_per"}, []string{"must be at most 60000000000"}},
+    {"min of pointer value", func(c *testConfig) {
+      c.Workers = new(int)
+      *c.Workers = -2
+    }, []string{"workers"}, []string{"must be at least 1"}},
+    {"pointer to zero value is checked", func(c *testConfig) {
+      c.Workers = &zero
+    }, []string{"workers"}, []string{"must be at least 1"}},
+    {"min length of string", func(c *testConfig) {
+      c.Name = "p"
+    }, []string{"name"}, []string{"length must be at least 2"}},
+    {"max length of string", func(c *testConfig) {
+      c.Name = "polygon.io"
+    }, []string{"name"}, []string{"length must be at most 8"}},
+    {"max length of slice", func(c *testConfig) {
+      c.Tickers = []string{"AAPL", "MSFT", "GOOG"}
+    }, []string{"tickers"}, []string{"length must be at most 2"}},
+    {"oneof", func(c *testConfig) {
+      c.Mode = "daily"
+    }, []string{"mode"}, []string{"must be one of [total, current]"}},
+    {"url without scheme", func(c *testConfig) {
+      c.ApiBaseUrl = "api.polygon.io"
+    }, []string{"api_base_url"}, []string{"must be a valid absolute url"}},
+    {"url without host", func(c *testConfig) {
+      c.ApiBaseUrl = "https://"
+    }, []string{"api_base_url"}, []string{"must be a valid absolute url"}},
+    {"field without yaml tag", func(c *testConfig) {
+      c.Retries = 10
+    }, []string{"Retries"}, []string{"must be at most 5"}},
+  }
+  for _, test := range tests {
+    config := validTestConfig()
+    test.modify(config)
+
+    err := ValidateStruct(config)
+    if test.wantPaths == nil {
+      if err != nil {
+        t.Errorf("%s: got error %v, want valid config", test.name, err)
+      }
+      continue
+    }
+    var violations Errors
+    if !errors.As(err, &violations) {
+      t.Errorf("%s: got error %v, want violations", test.name, err)
+      continue
+    }
+    if len(violations) != len(test.wantPaths) {
+      t.Errorf("%s: got violations %v, want paths %v", test.name, err, test.wantPaths)
+      continue
+    }
+    for idx, violation := range violations {
+      if violation.Path != test.wantPaths[idx] || violation.Message != test.wantMessages[idx] {
+        t.Errorf("%s: got violation '%s: %s', want '%s: %s'", test.name,
+          violation.Path, violation.Message, test.wantPaths[idx], test.wantMessages[idx])
+      }
+    }
+  }
+}
+
+func TestValidateStructCollectsAllViolations(t *testing.T) {
+  config := validTestConfig()
+  config.ApiToken = ""
+  config.Mode = "daily"
+  config.QueueConfig.Port = 0
+  config.Limits.RequestsCount = -1
+
+  err := ValidateStruct(config)
+  want := "validation failed: api_token: is required; mode: must be one of [total, current]; " +
+    "queue_config.port: is required; limits.requests_count: must be at least 1"
+  if err == nil || err.Error() != want {
+    t.Errorf("got error '%v', want '%s'", err, want)
+  }
+}
+
+func TestValidateStructMalformedRules(t *testing.T) {
+  type unknownRule struct {
+    Name string `yaml:"name" validate:"email"`
+  }
+  type malformedBound struct {
+    Count int `yaml:"count" validate:"min=one"`
+  }
+  type unsupportedType struct {
+    Enabled bool `yaml:"enabled" validate:"min=1"`
+  }
+  type urlOfNumber struct {
+    Port int `yaml:"port" validate:"url"`
+  }
+
+  tests := []struct {
+    name string
+    err  error
+  }{
+    {"unknown rule", ValidateStruct(&unknownRule{Name: "name"})},
+    {"malformed bound", ValidateStruct(&malformedBound{Count: 1})},
+    {"unsupported type", ValidateStruct(&unsupportedType{Enabled: true})},
+    {"url of number", ValidateStruct(&urlOfNumber{Port: 80})},
+  }
+  for _, test := range tests {
+    var violations Errors
+    if test.err == nil || errors.As(test.err, &violations) {
+      t.Errorf("%s: got error %v, want malformed rule error", test.name, test.err)
+    }
+  }
+  // rules of zero values are not parsed
+  if err := ValidateStruct(&malformedBound{}); err != nil {
+    t.Errorf("zero value: got error %v", err)
+  }
+}
+
+func TestValidateStructRequiresStruct(t *testing.T) {
+  name := "polygon"
+  if err := ValidateStruct(&name); err == nil {
+    t.Errorf("not a struct is validated")
+  }
+}